@@ -24,6 +24,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
 	"time"
 
 	"github.com/bygui86/multi-profile/v2"
@@ -40,17 +44,125 @@ var Version = "dev"
 // O_DIRECT align size.
 const alignSize = 4096
 
+// Defaults of --sync-test, small writes as issued by databases and for
+// MinIO metadata.
+const (
+	syncTestBlockSize = "4KiB"
+	syncTestFileSize  = "16MiB"
+)
+
+// Supported --stream formats
+const streamNDJSON = "ndjson"
+
 // flags
 var (
 	serial     = false
 	writeOnly  = false
+	readOnly   = false
+	syncTest   = false
+	syncBatch  = 1
+	mdTest     = false
+	mdFiles    = 10000
 	verbose    = false
+	quiet      = false
 	blockSize  = "4MiB"
 	fileSize   = "1GiB"
 	cpuNode    = 0
 	ioPerDrive = 4
+	access     = dperf.AccessSequential
+	engine     = dperf.EngineSync
+	aioDepth   = 1
+	rwFlags    []string
+	poll       = false
+	copyFiles  = false
+	copyTo     = ""
+	rwMix      = 0
+	duration   time.Duration
+	runs       = 1
+	warmup     = ""
+	maxWrite   = ""
+	dryRun     = false
+	seed       int64
+	tags       []string
+	output     = dperf.OutputTable
+	units      = dperf.UnitsIEC
+	noColor    = false
+	colorTheme = dperf.ThemeDefault
+	format     = ""
 	profileDir = "./"
 
+	outputFile    = ""
+	outputAppend  = false
+	outputMaxSize = ""
+
+	progressURL      = ""
+	progressInterval = 10 * time.Second
+	statusInterval   time.Duration
+	metricsAddr      = ""
+	stream           = ""
+	statsdAddr       = ""
+	graphiteAddr     = ""
+	graphitePrefix   = "dperf"
+	kafkaBrokers     = ""
+	kafkaTopic       = "dperf"
+	kafkaKey         = ""
+	kafkaProgress    = false
+	kafkaTLS         = false
+	kafkaUser        = ""
+	kafkaPassword    = ""
+
+	preCmd  = ""
+	postCmd = ""
+
+	promTextfile     = ""
+	historyFile      = ""
+	chartFile        = ""
+	histogramDir     = ""
+	maxFileSize      = ""
+	objects          = 0
+	objectSize       = "4KiB..1MiB"
+	erasure          = ""
+	partSize         = ""
+	existing         = ""
+	destructive      = false
+	offset           = ""
+	regionSize       = ""
+	rate             = ""
+	filesPerIO       = 1
+	fsyncFreq        = 0
+	syncMode         = dperf.SyncModeDirect
+	writeMode        = dperf.WriteModeFresh
+	dataPattern      = dperf.PatternRandom
+	verify           = false
+	warmRead         = false
+	keepFiles        = false
+	reuse            = false
+	soak             time.Duration
+	fill             = ""
+	ramp             time.Duration
+	series           = false
+	latencyThreshold time.Duration
+	maxDegradation   = float64(dperf.DefaultMaxDegradation)
+	slowestOps       = 5
+	score            = false
+	scoreWeights     = ""
+	heatmap          = false
+	seriesCSV        = ""
+	logResultsTo     = ""
+
+	webhookURL     = ""
+	webhookSecret  = ""
+	webhookRetries = 3
+
+	minWrite      = ""
+	minRead       = ""
+	minTotalWrite = ""
+	minTotalRead  = ""
+
+	expectWrite = ""
+	expectRead  = ""
+	specFile    = ""
+
 	pCPU, pCPUio, pBlock, pMem, pMutex, pThread, pTrace bool
 )
 
@@ -66,6 +178,15 @@ MinIO drive performance utility
 	SilenceErrors: true,
 	Args:          cobra.MinimumNArgs(1),
 	Version:       Version,
+	PersistentPreRunE: func(c *cobra.Command, args []string) error {
+		if err := dperf.SetUnits(units); err != nil {
+			return fmt.Errorf("Invalid units: %v", err)
+		}
+		if err := dperf.SetColors(colorTheme, !noColor); err != nil {
+			return fmt.Errorf("Invalid color-theme: %v", err)
+		}
+		return nil
+	},
 	Example: `
 # run dpref on drive mounted at /mnt/drive1
 $ dperf /mnt/drive1
@@ -75,72 +196,1049 @@ $ dperf /mnt/drive{1..6}
 
 # run dperf on drives one-by-one
 $ dperf --serial /mnt/drive{1..6}
+
+# run read-only tests against files already present on the drives
+$ dperf --read-only /mnt/drive{1..6}
+
+# read the objects of a bucket at random offsets without writing anything
+$ dperf --read-only --existing mybucket --access random /mnt/drive{1..6}
+
+# write like a journal, syncing after every 16 blocks of 64KiB
+$ dperf -v --fsync-freq 16 --blocksize 64KiB /mnt/drive{1..6}
+
+# write data that compresses by half, as on ZFS with compression
+$ dperf --data-pattern compressible:50 /mnt/drive{1..6}
+
+# measure overwrites in place rather than writes to new files, as on XFS or ZFS
+$ dperf --write-mode overwrite --duration 1m /mnt/drive{1..6}
+
+# interleave the writes of every concurrent I/O over 8 files, fragmenting them
+$ dperf --files-per-io 8 /mnt/drive{1..6}
+
+# measure the latency at a steady load of 200MiB/s per drive
+$ dperf -v --rate 200MiB/s --duration 1m /mnt/drive{1..6}
+
+# find where the write throughput drops as the drives fill up
+$ dperf --fill 90% /mnt/drive{1..6}
+
+# find the number of concurrent I/Os the drives saturate at
+$ dperf --ramp 2m --ioperdrive 32 /mnt/drive{1..6}
+
+# stress the drives overnight, printing the results of every cycle
+$ dperf --soak 12h --duration 5m /mnt/drive{1..6}
+
+# burn in new drives, checking that every block reads back as written
+$ dperf --verify --duration 1h /mnt/drive{1..6}
+
+# check after a reboot that the data written before it reads back intact
+$ dperf --verify --keep-files /mnt/drive{1..6}
+$ dperf --verify --reuse /mnt/drive{1..6}
+
+# tell how much faster reads served from the page cache are than from the drives
+$ dperf --warm-read --filesize 256MiB /mnt/drive{1..6}
+
+# measure synchronous writes, every write returns once its data is durable
+$ dperf --sync-mode dsync /mnt/drive{1..6}
+
+# benchmark unformatted drives, overwriting all data on them
+$ dperf --destructive /dev/nvme{0..5}n1
+
+# read the last 10GiB of nearly full 12TB hard drives, their slowest tracks
+$ dperf --read-only --offset 11TB --size 10GiB /dev/sd{a..f}
+
+# review the files, writes, memory and duration of a run without running it
+$ dperf --dry-run /mnt/drive{1..6}
+
+# print the results as JSON for automation
+$ dperf --output json /mnt/drive{1..6} > results.json
+
+# print the results in a custom format
+$ dperf --format '{{.Path}} {{bytes .WriteThroughput}}/s {{bytes .ReadThroughput}}/s' /mnt/drive{1..6}
+
+# draw the results to an image for the wiki
+$ dperf --chart results.png /mnt/drive{1..6}
+
+# record the throughput of every second to spot SLC cache exhaustion
+$ dperf --filesize 100GiB --series-csv series.csv /mnt/drive{1..6}
+
+# find drives with occasional multi-second stalls
+$ dperf -v --latency-threshold 100ms /mnt/drive{1..6}
+
+# watch the latency of a drive live to spot periodic stalls
+$ dperf --heatmap --filesize 20GiB /mnt/drive1
+
+# export the latency histograms to overlay the drives in HdrHistogram plotters
+$ dperf --histogram-dir ./hgrm /mnt/drive{1..6}
+
+# print path, write and read bytes/sec and error of every drive for awk
+$ dperf --quiet --output tsv /mnt/drive{1..6} | awk -F'\t' '$3 < 1e9 {print $1}'
+
+# accumulate the results of scheduled runs in a log rotated at 10MiB
+$ dperf --output json --output-file /var/log/dperf.json --output-append --output-max-size 10MiB /mnt/drive{1..6}
+
+# label the results for aggregation across runs
+$ dperf --tag rack=12 --tag firmware=3B2QGXA7 /mnt/drive{1..6}
+
+# drop the page cache before and record the result after testing each drive
+$ dperf --pre-cmd 'sync; echo 3 > /proc/sys/vm/drop_caches' \
+    --post-cmd 'echo "$DPERF_PATH $DPERF_WRITE_THROUGHPUT $DPERF_READ_THROUGHPUT" >> results.txt' /mnt/drive{1..6}
+
+# export the results of scheduled runs to the node_exporter textfile collector
+$ dperf --prom-textfile /var/lib/node_exporter/dperf.prom /mnt/drive{1..6}
+
+# watch the progress of a long run from Prometheus/Grafana
+$ dperf --metrics-addr :9100 --filesize 100GiB /mnt/drive{1..6}
+
+# tail the progress of every I/O worker from a dashboard
+$ dperf --stream ndjson /mnt/drive{1..6} | tee progress.ndjson
+
+# send the throughput of every drive to the local Datadog agent
+$ dperf --statsd 127.0.0.1:8125 --tag rack=12 /mnt/drive{1..6}
+
+# feed a Graphite dashboard
+$ dperf --graphite carbon:2003 --graphite-prefix dperf.$(hostname -s) /mnt/drive{1..6}
+
+# print throughput in MB/s as quoted by drive vendors
+$ dperf --units si /mnt/drive{1..6}
+
+# fail the burn-in when any drive writes slower than 500MiB/s
+$ dperf --min-write 500MiB /mnt/drive{1..6}
+
+# show the throughput of every drive in percent of the vendor spec
+$ dperf -v --spec-file specs.csv /mnt/drive{1..6}
+
+# send the signed results to the inventory system
+$ DPERF_WEBHOOK_SECRET=s3cr3t dperf --webhook https://cmdb.example.com/dperf /mnt/drive{1..6}
+
+# publish the results to the fleet data platform
+$ dperf --kafka-brokers kafka1:9092,kafka2:9092 --kafka-topic drive-benchmarks /mnt/drive{1..6}
+
+# log the results to the journal for the fleet log pipeline
+$ dperf --log-results journald /mnt/drive{1..6}
+
+# show the throughput trend of a drive across previous runs
+$ dperf --history ~/.dperf/history.jsonl /mnt/drive{1..6}
+$ dperf history --history ~/.dperf/history.jsonl /mnt/drive1
+
+# compare the drives to the results from before a firmware upgrade
+$ dperf compare before.json /mnt/drive{1..6}
+
+# measure the fdatasync latency of 4KiB writes, as seen by databases
+$ dperf --sync-test /mnt/drive{1..6}
+
+# rank the drives by throughput, IOPS and tail latency
+$ dperf --score --score-weights throughput=4,iops=2,latency=4 /mnt/drive{1..6}
+
+# follow a long run in the CI log
+$ dperf --status-interval 5s --filesize 100GiB /mnt/drive{1..6}
+
+# measure how fast the drives create, stat, rename and unlink files
+$ dperf --metadata-test /mnt/drive{1..6}
+
+# measure the random IOPS and latency of small blocks, as seen on HDDs
+$ dperf --access random --blocksize 64KiB /mnt/drive{1..6}
+
+# transfer the blocks with Linux AIO rather than pread and pwrite
+$ dperf --engine libaio --access random --blocksize 64KiB /mnt/drive{1..6}
+
+# read NVMe namespaces with passthrough commands, bypassing the block layer
+$ dperf --engine nvme --read-only --access random --blocksize 4KiB /dev/nvme{0..5}n1
+
+# make every write durable with RWF_DSYNC rather than opening the files with O_DSYNC
+$ dperf --rwf dsync /mnt/drive{1..6}
+
+# copy the files of every drive to another drive in the kernel, as healing does
+$ dperf --copy-to /mnt/spare /mnt/drive{1..6}
+
+# compare the latency of small blocks completing on interrupts and polled
+$ dperf --poll --access random --blocksize 4KiB /dev/nvme0n1 --destructive
+
+# compare the sequential and random throughput of every drive in one run
+$ dperf --access both --blocksize 64KiB /mnt/drive{1..6}
+
+# interleave 70% reads with 30% writes, closer to object storage traffic
+$ dperf --rwmix 70 /mnt/drive{1..6}
+
+# run every phase for a minute, however fast the drives are
+$ dperf --duration 60s /mnt/drive{1..6}
+
+# average three runs, with their spread, on a shared system
+$ dperf -v --runs 3 /mnt/drive{1..6}
+
+# let caches and CPU frequencies settle for 10 seconds before measuring
+$ dperf --warmup 10s /mnt/drive{1..6}
+
+# write and read back 10000 objects of 4KiB to 1MiB per concurrent I/O
+$ dperf --objects 10000 /mnt/drive{1..6}
+
+# estimate the objects per second of 64MiB objects in an erasure set of 12+4 drives
+$ dperf --objects 100 --object-size 64MiB --erasure 12+4 /mnt/drive{1..6}
+
+# size the test files to 10% of the free space of every drive, 100GiB at most
+$ dperf --filesize 10% --max-filesize 100GiB /mnt/drive{1..6}
+
+# compare the throughput of the drives at block sizes from 4KiB to 4MiB
+$ dperf --blocksize 4KiB..4MiB /mnt/drive{1..6}
+
+# check the drives are ready for a run, writing only a 4KiB probe file it removes
+$ dperf doctor /mnt/drive{1..6}
+
+# run the stages of a qualification suite kept in a job file
+$ dperf run qualify.yaml
 `,
-	RunE: func(c *cobra.Command, args []string) error {
-		bs, err := humanize.ParseBytes(blockSize)
+	RunE: runDperf,
+}
+
+// runDperf - tests the drives at args with the options of the flags.
+func runDperf(c *cobra.Command, args []string) error {
+	blockSizes, err := parseBlockSizes(blockSize)
+	if err != nil {
+		return err
+	}
+	if len(blockSizes) > 1 {
+		// The other flags are validated along with the first size.
+		blockSize = strconv.FormatUint(blockSizes[0], 10)
+	}
+	perf, err := newDrivePerf(c)
+	if err != nil {
+		return err
+	}
+	paths, err := checkPaths(args)
+	if err != nil {
+		return err
+	}
+	if len(blockSizes) > 1 {
+		if perf.Format != nil || dryRun || soak > 0 {
+			return errors.New("a list of blocksizes cannot be combined with --format, --dry-run or --soak")
+		}
+		if err = fitWriteBudget(perf, sweepPlanned(perf, dperf.SweepBlockSize, blockSizes, len(paths))); err != nil {
+			return err
+		}
+		return runWithReporting(c, perf, func(ctx context.Context) error {
+			return perf.SweepAndRender(ctx, dperf.SweepBlockSize, blockSizes, paths...)
+		})
+	}
+	if err = fitWriteBudget(perf, runPlanned(perf, len(paths))); err != nil {
+		return err
+	}
+	if dryRun {
+		return perf.PlanAndRender(paths...)
+	}
+	return runWithReporting(c, perf, func(ctx context.Context) error {
+		return perf.RunAndRender(ctx, paths...)
+	})
+}
+
+// runWithReporting - sets up the output file, hooks, publishers, stream
+// and live reporting of perf as the flags ask, then calls run.
+func runWithReporting(c *cobra.Command, perf *dperf.DrivePerf, run func(ctx context.Context) error) error {
+	if progressInterval <= 0 {
+		return fmt.Errorf("Invalid progress-interval must be greater than 0: %s", progressInterval)
+	}
+	if statusInterval < 0 {
+		return fmt.Errorf("Invalid status-interval must not be negative: %s", statusInterval)
+	}
+	if statusInterval > 0 && heatmap {
+		return errors.New("--status-interval cannot be combined with --heatmap")
+	}
+	sinks, err := dialMetricsSinks(perf.Tags)
+	if err != nil {
+		return err
+	}
+	// The throughput over time is always sampled for the stability
+	// of the drives.
+	timeline := &dperf.Timeline{}
+	perf.Timeline = timeline
+	perf.Series = series || seriesCSV != ""
+	if outputFile != "" {
+		f, err := openOutputFile()
 		if err != nil {
-			return fmt.Errorf("Invalid blocksize format: %v", err)
+			return err
+		}
+		defer f.Close()
+		perf.Out = f
+	}
+	setupHooks(perf)
+	setupPublishers(perf, sinks, timeline)
+	setupStream(perf)
+	defer startTraces()()
+	stopProgress, err := startProgressReporting(c.Context(), perf, sinks)
+	if err != nil {
+		return err
+	}
+	defer stopProgress()
+	stopHeatmap, err := startHeatmap(c.Context(), perf)
+	if err != nil {
+		return err
+	}
+	defer stopHeatmap()
+	defer startStatusLines(c.Context(), perf)()
+	return run(c.Context())
+}
+
+// newDrivePerf - validates the flags and returns the configured DrivePerf.
+func newDrivePerf(c *cobra.Command) (*dperf.DrivePerf, error) {
+	var syncEvery int
+	if syncTest {
+		if readOnly {
+			return nil, errors.New("Invalid sync-test cannot be combined with read-only")
+		}
+		if syncBatch <= 0 {
+			return nil, fmt.Errorf("Invalid sync-batch must be greater than 0: %d", syncBatch)
+		}
+		// The sync test writes small blocks, unless asked otherwise.
+		if !c.Flags().Changed("blocksize") {
+			blockSize = syncTestBlockSize
+		}
+		if !c.Flags().Changed("filesize") {
+			fileSize = syncTestFileSize
+		}
+		writeOnly = true
+		syncEvery = syncBatch
+	}
+
+	if fsyncFreq != 0 {
+		if fsyncFreq < 0 {
+			return nil, fmt.Errorf("Invalid fsync-freq must be greater than 0: %d", fsyncFreq)
+		}
+		if readOnly || syncTest || mdTest || objects != 0 {
+			return nil, errors.New("Invalid fsync-freq cannot be combined with read-only, sync-test, metadata-test or objects")
 		}
+		syncEvery = fsyncFreq
+	}
 
-		if bs < alignSize {
-			return fmt.Errorf("Invalid blocksize must greater than 4k: %d", bs)
+	var metadataFiles int
+	if mdTest {
+		if readOnly || syncTest {
+			return nil, errors.New("Invalid metadata-test cannot be combined with read-only or sync-test")
+		}
+		if mdFiles <= 0 {
+			return nil, fmt.Errorf("Invalid metadata-files must be greater than 0: %d", mdFiles)
 		}
+		metadataFiles = mdFiles
+	}
 
-		if bs%alignSize != 0 {
-			return fmt.Errorf("Invalid blocksize must be multiples of 4k: %d", bs)
+	var objMin, objMax uint64
+	if objects != 0 {
+		if readOnly || syncTest || mdTest || rwMix != 0 || duration != 0 {
+			return nil, errors.New("Invalid objects cannot be combined with read-only, sync-test, metadata-test, rwmix or duration")
 		}
+		if objects < 0 {
+			return nil, fmt.Errorf("Invalid objects must be greater than 0: %d", objects)
+		}
+		var err error
+		if objMin, objMax, err = parseObjectSize(objectSize); err != nil {
+			return nil, err
+		}
+	}
 
-		fs, err := humanize.ParseBytes(fileSize)
+	var ecData, ecParity int
+	var ecPartSize uint64
+	var err error
+	if erasure != "" {
+		if objects == 0 {
+			return nil, errors.New("Invalid erasure requires objects")
+		}
+		if ecData, ecParity, err = parseErasure(erasure); err != nil {
+			return nil, err
+		}
+	}
+	if partSize != "" {
+		if erasure == "" {
+			return nil, errors.New("Invalid part-size requires erasure")
+		}
+		if ecPartSize, err = humanize.ParseBytes(partSize); err != nil || ecPartSize == 0 {
+			return nil, fmt.Errorf("Invalid part-size %q, e.g. 16MiB", partSize)
+		}
+	}
+
+	bs, err := parseBlockSize(blockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var fs, maxFS uint64
+	var filePercent float64
+	if pct, ok := strings.CutSuffix(fileSize, "%"); ok {
+		if readOnly {
+			return nil, errors.New("Invalid filesize a percent of the free space cannot be combined with read-only")
+		}
+		filePercent, err = strconv.ParseFloat(pct, 64)
+		if err != nil || filePercent <= 0 || filePercent > 100 {
+			return nil, fmt.Errorf("Invalid filesize %q, a percent of the free space must be greater than 0 and at most 100", fileSize)
+		}
+		if maxFileSize != "" {
+			if maxFS, err = humanize.ParseBytes(maxFileSize); err != nil {
+				return nil, fmt.Errorf("Invalid max-filesize format: %v", err)
+			}
+		}
+	} else {
+		fs, err = humanize.ParseBytes(fileSize)
 		if err != nil {
-			return fmt.Errorf("Invalid filesize format: %v", err)
+			return nil, fmt.Errorf("Invalid filesize format: %v", err)
 		}
 
 		if fs < alignSize {
-			return fmt.Errorf("Invalid filesize must greater than 4k: %d", fs)
+			return nil, fmt.Errorf("Invalid filesize must greater than 4k: %d", fs)
 		}
 
 		if fs%alignSize != 0 {
-			return fmt.Errorf("Invalid filesize must multiples of 4k: %d", fs)
+			return nil, fmt.Errorf("Invalid filesize must multiples of 4k: %d", fs)
 		}
+	}
 
-		if ioPerDrive <= 0 {
-			return fmt.Errorf("Invalid ioperdrive must greater than 0: %d", ioPerDrive)
+	if ioPerDrive <= 0 {
+		return nil, fmt.Errorf("Invalid ioperdrive must greater than 0: %d", ioPerDrive)
+	}
+
+	var off uint64
+	if offset != "" || regionSize != "" {
+		if syncTest || mdTest || objects != 0 || fill != "" {
+			return nil, errors.New("Invalid offset and size cannot be combined with sync-test, metadata-test, objects or fill")
+		}
+	}
+	if offset != "" {
+		if off, err = humanize.ParseBytes(offset); err != nil {
+			return nil, fmt.Errorf("Invalid offset format: %v", err)
+		}
+		if off%alignSize != 0 {
+			return nil, fmt.Errorf("Invalid offset must multiples of 4k: %d", off)
+		}
+	}
+	if regionSize != "" {
+		if c.Flags().Changed("filesize") {
+			return nil, errors.New("--size and --filesize are mutually exclusive")
 		}
+		size, err := humanize.ParseBytes(regionSize)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid size format: %v", err)
+		}
+		// Split between the I/O workers, aligned for O_DIRECT.
+		fs = size / uint64(ioPerDrive)
+		fs -= fs % alignSize
+		if fs < alignSize {
+			return nil, fmt.Errorf("Invalid size must be at least 4k per ioperdrive: %d", size)
+		}
+	}
 
-		perf := &dperf.DrivePerf{
-			Serial:     serial,
-			BlockSize:  bs,
-			FileSize:   fs,
-			Verbose:    verbose,
-			IOPerDrive: ioPerDrive,
-			WriteOnly:  writeOnly,
+	if c.Flags().Changed("rwmix") {
+		if readOnly || writeOnly || syncTest || mdTest {
+			return nil, errors.New("Invalid rwmix cannot be combined with read-only, write-only, sync-test or metadata-test")
 		}
-		paths := make([]string, 0, len(args))
-		for _, arg := range args {
-			if filepath.Clean(arg) == "" {
-				return errors.New("empty paths are not allowed as input")
-			}
-			if filepath.Clean(arg) == "/" {
-				return errors.New("not allowed to write at the root of the system, please choose a valid path")
+		if rwMix <= 0 || rwMix > 100 {
+			return nil, fmt.Errorf("Invalid rwmix must be a percent of reads from 1 to 100: %d", rwMix)
+		}
+	}
+
+	if duration < 0 {
+		return nil, fmt.Errorf("Invalid duration must not be negative: %s", duration)
+	}
+	if duration > 0 && mdTest {
+		return nil, errors.New("Invalid duration cannot be combined with metadata-test")
+	}
+
+	var warmupTime time.Duration
+	var warmupBytes uint64
+	if warmup != "" {
+		if mdTest {
+			return nil, errors.New("Invalid warmup cannot be combined with metadata-test")
+		}
+		if warmupTime, err = time.ParseDuration(warmup); err != nil {
+			if warmupBytes, err = humanize.ParseBytes(warmup); err != nil {
+				return nil, fmt.Errorf("Invalid warmup %q, must be a duration or a size, e.g. 10s or 4GiB", warmup)
 			}
-			path := filepath.Clean(arg)
+		}
+		if warmupTime < 0 {
+			return nil, fmt.Errorf("Invalid warmup must not be negative: %s", warmupTime)
+		}
+	}
 
-			stat, err := os.Stat(path)
-			if err != nil {
-				if os.IsNotExist(err) {
-					return errors.New("directory at path '" + path + "' does not exist")
-				}
-				return err
+	if runs <= 0 {
+		return nil, fmt.Errorf("Invalid runs must be greater than 0: %d", runs)
+	}
+
+	pattern, compressibility, err := parseDataPattern(dataPattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if verify && (readOnly || writeOnly || syncTest || mdTest || objects != 0) {
+		return nil, errors.New("Invalid verify cannot be combined with read-only, write-only, sync-test, metadata-test or objects")
+	}
+	if verify && pattern != dperf.PatternRandom {
+		// The trailers of the blocks would change what the pattern puts
+		// on the drives.
+		return nil, errors.New("Invalid verify cannot be combined with data-pattern zero or compressible")
+	}
+
+	if warmRead && (writeOnly || syncTest || mdTest || objects != 0 || rwMix != 0) {
+		return nil, errors.New("Invalid warm-read cannot be combined with write-only, sync-test, metadata-test, objects or rwmix")
+	}
+
+	var fillPercent float64
+	if fill != "" {
+		if fillPercent, err = strconv.ParseFloat(strings.TrimSuffix(fill, "%"), 64); err != nil || fillPercent <= 0 || fillPercent > 100 {
+			return nil, fmt.Errorf("Invalid fill %q, must be a percent of the capacity, e.g. 90%%", fill)
+		}
+		if readOnly || syncTest || mdTest || objects != 0 || rwMix != 0 || duration != 0 || verify || warmRead {
+			return nil, errors.New("Invalid fill cannot be combined with read-only, sync-test, metadata-test, objects, rwmix, duration, verify or warm-read")
+		}
+		writeOnly = true
+	}
+
+	if ramp < 0 {
+		return nil, fmt.Errorf("Invalid ramp must not be negative: %s", ramp)
+	}
+	if ramp > 0 {
+		if readOnly || syncTest || mdTest || objects != 0 || fill != "" || rwMix != 0 || duration != 0 || verify || warmRead || access == dperf.AccessBoth || maxWrite != "" {
+			return nil, errors.New("Invalid ramp cannot be combined with read-only, sync-test, metadata-test, objects, fill, rwmix, duration, verify, warm-read, access both or max-write")
+		}
+		writeOnly = true
+	}
+
+	if keepFiles && (readOnly || syncTest || mdTest || objects != 0 || fill != "" || ramp != 0 || offset != "") {
+		return nil, errors.New("Invalid keep-files cannot be combined with read-only, sync-test, metadata-test, objects, fill, ramp or offset")
+	}
+	if reuse {
+		if readOnly || writeOnly || syncTest || mdTest || objects != 0 || fill != "" || ramp != 0 || offset != "" || rwMix != 0 || warmup != "" {
+			return nil, errors.New("Invalid reuse cannot be combined with read-only, write-only, sync-test, metadata-test, objects, fill, ramp, offset, rwmix or warmup")
+		}
+		if !keepFiles && (runs > 1 || soak > 0 || access == dperf.AccessBoth) {
+			return nil, errors.New("Invalid reuse removes the files once read, add keep-files to read them more than once")
+		}
+	}
+
+	if soak < 0 {
+		return nil, fmt.Errorf("Invalid soak must not be negative: %s", soak)
+	}
+	if soak > 0 && runs > 1 {
+		return nil, errors.New("Invalid soak cannot be combined with runs")
+	}
+
+	switch syncMode {
+	case dperf.SyncModeDirect:
+	case dperf.SyncModeDSync, dperf.SyncModeSync:
+		if readOnly || mdTest || objects != 0 {
+			return nil, errors.New("Invalid sync-mode cannot be combined with read-only, metadata-test or objects")
+		}
+	default:
+		return nil, fmt.Errorf("Invalid sync-mode %q, must be one of direct, dsync, sync", syncMode)
+	}
+
+	switch writeMode {
+	case dperf.WriteModeFresh:
+	case dperf.WriteModeOverwrite:
+		if readOnly || syncTest || mdTest || objects != 0 || fill != "" {
+			return nil, errors.New("Invalid write-mode cannot be combined with read-only, sync-test, metadata-test, objects or fill")
+		}
+	default:
+		return nil, fmt.Errorf("Invalid write-mode %q, must be one of fresh, overwrite", writeMode)
+	}
+
+	switch access {
+	case dperf.AccessSequential, dperf.AccessRandom:
+	case dperf.AccessBoth:
+		if syncTest || mdTest || objects != 0 || fill != "" {
+			return nil, errors.New("Invalid access both cannot be combined with sync-test, metadata-test, objects or fill")
+		}
+	default:
+		return nil, fmt.Errorf("Invalid access %q, must be one of sequential, random, both", access)
+	}
+
+	switch engine {
+	case dperf.EngineSync:
+	case dperf.EngineLibaio, dperf.EngineMmap:
+		if mdTest || objects != 0 {
+			return nil, fmt.Errorf("Invalid engine %s cannot be combined with metadata-test or objects", engine)
+		}
+	case dperf.EngineNVMe:
+		if !readOnly || warmRead {
+			return nil, errors.New("Invalid engine nvme needs read-only and cannot be combined with warm-read")
+		}
+	default:
+		return nil, fmt.Errorf("Invalid engine %q, must be one of sync, libaio, mmap, nvme", engine)
+	}
+	if aioDepth < 1 || aioDepth > 256 {
+		return nil, fmt.Errorf("Invalid aio-depth %d, must be between 1 and 256", aioDepth)
+	}
+	if aioDepth > 1 && engine != dperf.EngineLibaio {
+		return nil, errors.New("Invalid aio-depth needs engine libaio")
+	}
+
+	for _, f := range rwFlags {
+		switch f {
+		case dperf.RWFlagDsync, dperf.RWFlagHipri:
+		default:
+			return nil, fmt.Errorf("Invalid rwf %q, must be one of dsync, hipri", f)
+		}
+	}
+	if len(rwFlags) > 0 && (engine != dperf.EngineSync || mdTest || objects != 0) {
+		return nil, errors.New("Invalid rwf cannot be combined with engine libaio, mmap or nvme, metadata-test or objects")
+	}
+	if copyTo != "" {
+		copyFiles = true
+	}
+	if copyFiles && (readOnly || syncTest || mdTest || objects != 0 || fill != "" || ramp != 0) {
+		return nil, errors.New("Invalid copy cannot be combined with read-only, sync-test, metadata-test, objects, fill or ramp")
+	}
+	if poll && (engine != dperf.EngineSync || slices.Contains(rwFlags, dperf.RWFlagHipri) || syncTest || mdTest || objects != 0 || fill != "" || ramp != 0 || access == dperf.AccessBoth) {
+		return nil, errors.New("Invalid poll cannot be combined with engine libaio, mmap or nvme, rwf hipri, sync-test, metadata-test, objects, fill, ramp or access both")
+	}
+
+	if readOnly && writeOnly {
+		return nil, errors.New("--read-only and --write-only are mutually exclusive")
+	}
+
+	if destructive && readOnly {
+		return nil, errors.New("--destructive and --read-only are mutually exclusive")
+	}
+
+	if existing != "" {
+		if !readOnly {
+			return nil, errors.New("Invalid existing can only be used with read-only")
+		}
+		if filepath.IsAbs(existing) {
+			return nil, fmt.Errorf("Invalid existing %q, must be relative to the drives", existing)
+		}
+	}
+
+	if filesPerIO < 1 {
+		return nil, fmt.Errorf("Invalid files-per-io must greater than 0: %d", filesPerIO)
+	}
+	if filesPerIO > 1 && (readOnly || syncTest || mdTest || objects != 0 || fill != "") {
+		return nil, errors.New("Invalid files-per-io cannot be combined with read-only, sync-test, metadata-test, objects or fill")
+	}
+
+	var rateLimit uint64
+	if rate != "" {
+		if syncTest || mdTest || objects != 0 || fill != "" {
+			return nil, errors.New("Invalid rate cannot be combined with sync-test, metadata-test, objects or fill")
+		}
+		if rateLimit, err = humanize.ParseBytes(strings.TrimSuffix(rate, "/s")); err != nil || rateLimit == 0 {
+			return nil, fmt.Errorf("Invalid rate %q, must be a throughput per drive, e.g. 200MiB/s", rate)
+		}
+	}
+
+	var mw uint64
+	if maxWrite != "" {
+		mw, err = humanize.ParseBytes(maxWrite)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid max-write format: %v", err)
+		}
+		if duration != 0 && !readOnly && !mdTest {
+			// Timed phases rewrite the files until the deadline.
+			return nil, errors.New("Invalid max-write cannot be combined with duration")
+		}
+		if warmupTime > 0 && warmupBytes == 0 && !readOnly {
+			return nil, errors.New("Invalid max-write cannot be combined with a warmup duration, use a warmup size such as 4GiB")
+		}
+	}
+
+	thresholds, err := parseThresholds()
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := parseSpecs()
+	if err != nil {
+		return nil, err
+	}
+
+	switch output {
+	case dperf.OutputTable, dperf.OutputJSON, dperf.OutputMarkdown, dperf.OutputTSV, dperf.OutputCBOR:
+	default:
+		return nil, fmt.Errorf("Invalid output format %q, must be one of table, json, markdown, tsv, cbor", output)
+	}
+
+	if stream != "" && stream != streamNDJSON {
+		return nil, fmt.Errorf("Invalid stream format %q, must be ndjson", stream)
+	}
+
+	var tmpl *template.Template
+	if format != "" {
+		tmpl, err = dperf.ParseFormat(format)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid format: %v", err)
+		}
+	}
+
+	switch logResultsTo {
+	case "", logResultsSyslog, logResultsJournald:
+	default:
+		return nil, fmt.Errorf("Invalid log-results %q, must be one of syslog, journald", logResultsTo)
+	}
+
+	if chartFile != "" {
+		switch strings.ToLower(filepath.Ext(chartFile)) {
+		case ".svg", ".png":
+		default:
+			return nil, fmt.Errorf("Invalid chart %q: %v", chartFile, dperf.ErrUnknownChartFormat)
+		}
+	}
+
+	if latencyThreshold < 0 {
+		return nil, fmt.Errorf("Invalid latency-threshold must not be negative: %s", latencyThreshold)
+	}
+
+	if slowestOps < 0 {
+		return nil, fmt.Errorf("Invalid slowest-ops must not be negative: %d", slowestOps)
+	}
+
+	if maxDegradation <= 0 {
+		return nil, fmt.Errorf("Invalid max-degradation must be greater than 0: %v", maxDegradation)
+	}
+
+	var weights *dperf.ScoreWeights
+	if score || scoreWeights != "" {
+		w := dperf.DefaultScoreWeights
+		if scoreWeights != "" {
+			if w, err = dperf.ParseScoreWeights(scoreWeights); err != nil {
+				return nil, fmt.Errorf("Invalid score-weights: %v", err)
 			}
+		}
+		weights = &w
+	}
+
+	tagMap, err := parseTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dperf.DrivePerf{
+		Version:    Version,
+		Output:     output,
+		Format:     tmpl,
+		Tags:       tagMap,
+		MaxWrite:   mw,
+		Serial:     serial,
+		BlockSize:  bs,
+		FileSize:   fs,
+		Verbose:    verbose,
+		IOPerDrive: ioPerDrive,
+		WriteOnly:  writeOnly,
+		ReadOnly:   readOnly,
+		Seed:       seed,
+		Thresholds: thresholds,
+		Specs:      specs,
+
+		SyncEvery:        syncEvery,
+		SyncMode:         syncMode,
+		WriteMode:        writeMode,
+		DataPattern:      pattern,
+		Compressibility:  compressibility,
+		Verify:           verify,
+		WarmRead:         warmRead,
+		KeepFiles:        keepFiles,
+		Reuse:            reuse,
+		Soak:             soak,
+		Fill:             fillPercent,
+		Ramp:             ramp,
+		Access:           access,
+		Engine:           engine,
+		AIODepth:         aioDepth,
+		RWFlags:          rwFlags,
+		Poll:             poll,
+		Copy:             copyFiles,
+		CopyTo:           copyTo,
+		ReadMix:          rwMix,
+		Duration:         duration,
+		Runs:             runs,
+		Warmup:           warmupTime,
+		FilePercent:      filePercent,
+		MaxFileSize:      maxFS,
+		Objects:          objects,
+		ObjectMinSize:    objMin,
+		ObjectMaxSize:    objMax,
+		ErasureData:      ecData,
+		ErasureParity:    ecParity,
+		PartSize:         ecPartSize,
+		Existing:         existing,
+		Destructive:      destructive,
+		Offset:           off,
+		Rate:             rateLimit,
+		FilesPerIO:       filesPerIO,
+		WarmupBytes:      warmupBytes,
+		MetadataFiles:    metadataFiles,
+		LatencyThreshold: latencyThreshold,
+		MaxDegradation:   maxDegradation,
+		SlowestOps:       slowestOps,
+		Score:            weights,
+	}, nil
+}
 
-			if !stat.Mode().IsDir() {
-				return errors.New("path '" + path + "' is not a directory ")
+// parseDataPattern - parses --data-pattern, random, zero or
+// compressible:N with N the percent of compressible data.
+func parseDataPattern(s string) (string, int, error) {
+	switch s {
+	case dperf.PatternRandom, dperf.PatternZero:
+		return s, 0, nil
+	}
+	name, percent, ok := strings.Cut(s, ":")
+	if !ok || name != dperf.PatternCompressible {
+		return "", 0, fmt.Errorf("Invalid data-pattern %q, must be one of random, zero, compressible:N", s)
+	}
+	n, err := strconv.Atoi(percent)
+	if err != nil || n < 0 || n > 100 {
+		return "", 0, fmt.Errorf("Invalid data-pattern %q, N must be a percent from 0 to 100", s)
+	}
+	return dperf.PatternCompressible, n, nil
+}
+
+// parseSpecs - reads --spec-file, --expect-write and --expect-read apply
+// to the drives without a spec of their own.
+func parseSpecs() (map[string]dperf.DriveSpec, error) {
+	specs := make(map[string]dperf.DriveSpec)
+	if specFile != "" {
+		var err error
+		if specs, err = dperf.ReadSpecFile(specFile); err != nil {
+			return nil, fmt.Errorf("Invalid spec-file: %v", err)
+		}
+	}
+	var def dperf.DriveSpec
+	for _, f := range []struct {
+		name  string
+		value string
+		dst   *uint64
+	}{
+		{"expect-write", expectWrite, &def.Write},
+		{"expect-read", expectRead, &def.Read},
+	} {
+		if f.value == "" {
+			continue
+		}
+		v, err := humanize.ParseBytes(strings.TrimSuffix(f.value, "/s"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s format: %v", f.name, err)
+		}
+		*f.dst = v
+	}
+	if def != (dperf.DriveSpec{}) {
+		specs[dperf.DefaultSpec] = def
+	}
+	return specs, nil
+}
+
+// parseThresholds - parses the --min-* throughput flags.
+func parseThresholds() (t dperf.Thresholds, err error) {
+	for _, f := range []struct {
+		name  string
+		value string
+		dst   *uint64
+	}{
+		{"min-write", minWrite, &t.MinWrite},
+		{"min-read", minRead, &t.MinRead},
+		{"min-total-write", minTotalWrite, &t.MinTotalWrite},
+		{"min-total-read", minTotalRead, &t.MinTotalRead},
+	} {
+		if f.value == "" {
+			continue
+		}
+		if *f.dst, err = humanize.ParseBytes(strings.TrimSuffix(f.value, "/s")); err != nil {
+			return t, fmt.Errorf("Invalid %s format: %v", f.name, err)
+		}
+	}
+	if readOnly && (t.MinWrite > 0 || t.MinTotalWrite > 0) {
+		return t, errors.New("--min-write and --min-total-write cannot be used with --read-only")
+	}
+	if writeOnly && (t.MinRead > 0 || t.MinTotalRead > 0) {
+		return t, errors.New("--min-read and --min-total-read cannot be used with --write-only")
+	}
+	return t, nil
+}
+
+// parseTags - parses repeated --tag key=value flags.
+func parseTags(tags []string) (map[string]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		k, v, ok := strings.Cut(tag, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("Invalid tag format, expected key=value: %q", tag)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+// parseBlockSize - parses a block size aligned for O_DIRECT.
+func parseBlockSize(s string) (uint64, error) {
+	bs, err := humanize.ParseBytes(s)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid blocksize format: %v", err)
+	}
+
+	if bs < alignSize {
+		return 0, fmt.Errorf("Invalid blocksize must greater than 4k: %d", bs)
+	}
+
+	if bs%alignSize != 0 {
+		return 0, fmt.Errorf("Invalid blocksize must be multiples of 4k: %d", bs)
+	}
+	return bs, nil
+}
+
+// parseObjectSize - parses a size or a range of sizes, e.g. "4KiB..1MiB",
+// aligned for O_DIRECT.
+func parseObjectSize(s string) (uint64, uint64, error) {
+	from, to, ok := strings.Cut(s, "..")
+	if !ok {
+		to = from
+	}
+	var sizes [2]uint64
+	for i, v := range []string{from, to} {
+		size, err := humanize.ParseBytes(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid object-size format: %v", err)
+		}
+		if size < alignSize || size%alignSize != 0 {
+			return 0, 0, fmt.Errorf("Invalid object-size must be multiples of 4k: %d", size)
+		}
+		sizes[i] = size
+	}
+	if sizes[1] < sizes[0] {
+		return 0, 0, fmt.Errorf("Invalid object-size range %q, must be increasing", s)
+	}
+	return sizes[0], sizes[1], nil
+}
+
+// parseErasure - parses an erasure set of data and parity drives, e.g.
+// "12+4".
+func parseErasure(s string) (int, int, error) {
+	d, p, ok := strings.Cut(s, "+")
+	data, err := strconv.Atoi(d)
+	if err != nil || !ok || data <= 0 {
+		return 0, 0, fmt.Errorf("Invalid erasure %q, must be data+parity drives, e.g. 12+4", s)
+	}
+	parity, err := strconv.Atoi(p)
+	if err != nil || parity < 0 || parity > data {
+		return 0, 0, fmt.Errorf("Invalid erasure %q, must be data+parity drives with no more parity than data, e.g. 12+4", s)
+	}
+	return data, parity, nil
+}
+
+// fitWriteBudget - scales down the filesize so that the writes planned
+// stay within --max-write, refuses when that is not possible.
+func fitWriteBudget(perf *dperf.DrivePerf, planned func() uint64) error {
+	if perf.FilePercent > 0 || perf.Fill > 0 {
+		// The writes depend on the drive, Run refuses to exceed the budget.
+		return nil
+	}
+	total := planned()
+	if perf.MaxWrite == 0 || total <= perf.MaxWrite {
+		return nil
+	}
+	// What is written whatever the filesize, e.g. a warmup size, and
+	// the rest in proportion to it.
+	fileSize := perf.FileSize
+	perf.FileSize = 0
+	fixed := planned()
+	perf.FileSize = fileSize
+	var fs uint64
+	if perf.MaxWrite > fixed && total > fixed {
+		fs = uint64(float64(perf.MaxWrite-fixed) / float64(total-fixed) * float64(fileSize))
+		fs -= fs % alignSize
+	}
+	if fs < alignSize {
+		return fmt.Errorf("%w: %s planned, %s allowed", dperf.ErrWriteBudgetExceeded,
+			dperf.FormatBytes(total), dperf.FormatBytes(perf.MaxWrite))
+	}
+	infof("scaling filesize down from %s to %s to stay within --max-write %s",
+		dperf.FormatBytes(perf.FileSize), dperf.FormatBytes(fs), dperf.FormatBytes(perf.MaxWrite))
+	perf.FileSize = fs
+	if total = planned(); total > perf.MaxWrite {
+		return fmt.Errorf("%w: %s planned, %s allowed", dperf.ErrWriteBudgetExceeded,
+			dperf.FormatBytes(total), dperf.FormatBytes(perf.MaxWrite))
+	}
+	return nil
+}
+
+// runPlanned - the writes planned by a run of perf against n drives.
+func runPlanned(perf *dperf.DrivePerf, n int) func() uint64 {
+	return func() uint64 { return perf.PlannedWrite(n) }
+}
+
+// sweepPlanned - the writes planned by a sweep of perf over values
+// against n drives.
+func sweepPlanned(perf *dperf.DrivePerf, parameter string, values []uint64, n int) func() uint64 {
+	return func() uint64 { return perf.SweepPlannedWrite(parameter, values, n) }
+}
+
+// checkPaths - validates the input paths and returns them cleaned.
+func checkPaths(args []string) ([]string, error) {
+	paths := make([]string, 0, len(args))
+	for _, arg := range args {
+		if filepath.Clean(arg) == "" {
+			return nil, errors.New("empty paths are not allowed as input")
+		}
+		if filepath.Clean(arg) == "/" {
+			return nil, errors.New("not allowed to write at the root of the system, please choose a valid path")
+		}
+		path := filepath.Clean(arg)
+
+		stat, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, errors.New("directory at path '" + path + "' does not exist")
 			}
-			paths = append(paths, filepath.Clean(arg))
+			return nil, err
 		}
-		defer startTraces()()
-		return perf.RunAndRender(c.Context(), paths...)
-	},
+
+		// Block devices are tested in place, the library refuses to
+		// write to them without --destructive.
+		isDevice := stat.Mode()&os.ModeDevice != 0 && stat.Mode()&os.ModeCharDevice == 0
+		if !stat.Mode().IsDir() && !isDevice {
+			return nil, errors.New("path '" + path + "' is not a directory or block device")
+		}
+		paths = append(paths, filepath.Clean(arg))
+	}
+	return paths, nil
+}
+
+// stderrLogger - keeps profiler messages off stdout, which is reserved
+// for results so that they can be piped.
+type stderrLogger struct{}
+
+func (stderrLogger) Debug(args ...interface{}) { stderrLog("debug", args...) }
+func (stderrLogger) Info(args ...interface{})  { stderrLog("info", args...) }
+func (stderrLogger) Warn(args ...interface{})  { stderrLog("warn", args...) }
+func (stderrLogger) Error(args ...interface{}) { stderrLog("error", args...) }
+func (stderrLogger) Fatal(args ...interface{}) { stderrLog("fatal", args...); os.Exit(1) }
+
+func (stderrLogger) Debugf(format string, args ...interface{}) {
+	stderrLog("debug", fmt.Sprintf(format, args...))
+}
+
+func (stderrLogger) Infof(format string, args ...interface{}) {
+	stderrLog("info", fmt.Sprintf(format, args...))
+}
+
+func (stderrLogger) Warnf(format string, args ...interface{}) {
+	stderrLog("warn", fmt.Sprintf(format, args...))
+}
+
+func (stderrLogger) Errorf(format string, args ...interface{}) {
+	stderrLog("error", fmt.Sprintf(format, args...))
+}
+
+func (stderrLogger) Fatalf(format string, args ...interface{}) {
+	stderrLog("fatal", fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func stderrLog(level string, args ...interface{}) {
+	if quiet && (level == "debug" || level == "info") {
+		return
+	}
+	fmt.Fprintln(os.Stderr, append([]interface{}{"[" + level + "]"}, args...)...)
+}
+
+// infof - prints an informational message to stderr unless --quiet.
+func infof(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "[info] "+format+"\n", args...)
+	}
 }
 
 func startTraces() func() {
@@ -152,7 +1250,7 @@ func startTraces() func() {
 		MemProfileRate: 4096,
 		MemProfileType: "heap",
 		CloserHook:     nil,
-		Logger:         nil,
+		Logger:         stderrLogger{},
 	}
 	type starter interface {
 		Start() *profile.Profile
@@ -173,7 +1271,7 @@ func startTraces() func() {
 	if pCPUio {
 		stopCPUIO = fgprof.Start(&cpuIOBuf, fgprof.FormatPprof)
 		if verbose {
-			fmt.Println("[info] CPU/IO profiling enabled")
+			infof("CPU/IO profiling enabled")
 		}
 	}
 	started := time.Now()
@@ -184,16 +1282,16 @@ func startTraces() func() {
 		// Light hack around https://github.com/felixge/fgprof/pull/34
 		if stopCPUIO != nil && time.Since(started) > 100*time.Millisecond {
 			if verbose {
-				fmt.Println("[info]  Stop and flush CPU/IO profiling to file", filepath.Join(profileDir, "cpuio.pprof"))
+				infof("Stop and flush CPU/IO profiling to file %s", filepath.Join(profileDir, "cpuio.pprof"))
 			}
 			err := stopCPUIO()
 			if err != nil {
-				fmt.Printf("Failed to stop CPU IO: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Failed to stop CPU IO: %v\n", err)
 				return
 			}
 			err = os.WriteFile(filepath.Join(profileDir, "cpuio.pprof"), cpuIOBuf.Bytes(), 0o666)
 			if err != nil {
-				fmt.Printf("Failed to write CPU IO profile: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Failed to write CPU IO profile: %v\n", err)
 				return
 			}
 		}
@@ -213,14 +1311,196 @@ func init() {
 		"serial", "", serial, "run tests one by one, instead of all at once")
 	dperfCmd.PersistentFlags().BoolVarP(&writeOnly,
 		"write-only", "", writeOnly, "run write only tests")
+	dperfCmd.PersistentFlags().BoolVarP(&readOnly,
+		"read-only", "", readOnly, "run read only tests against existing files, nothing is written")
+	dperfCmd.PersistentFlags().StringVarP(&existing,
+		"existing", "", existing, "file or directory, relative to every drive, whose files are read by --read-only instead of any on the drive")
+	dperfCmd.PersistentFlags().BoolVarP(&destructive,
+		"destructive", "", destructive, "write to the block devices passed instead of directories, destroying all data on them")
+	dperfCmd.PersistentFlags().IntVarP(&filesPerIO,
+		"files-per-io", "", filesPerIO, "spread the filesize of every concurrent I/O over this many files, a block of each in turn, to exercise allocation and fragmentation")
+	dperfCmd.PersistentFlags().StringVarP(&rate,
+		"rate", "", rate, "limit the reads and writes of every drive to this throughput, to measure the latency at a given load, e.g. 200MiB/s")
+	dperfCmd.PersistentFlags().StringVarP(&offset,
+		"offset", "", offset, "start the I/O at this byte offset of the test files or block devices, e.g. 100GiB")
+	dperfCmd.PersistentFlags().StringVarP(&regionSize,
+		"size", "", regionSize, "bytes read and written from --offset on every drive, split between the concurrent I/Os, instead of --filesize")
+	dperfCmd.PersistentFlags().BoolVarP(&syncTest,
+		"sync-test", "", syncTest, "measure the latency of fdatasync after small writes instead of the throughput, blocksize defaults to 4KiB and filesize to 16MiB")
+	dperfCmd.PersistentFlags().IntVarP(&syncBatch,
+		"sync-batch", "", syncBatch, "number of blocks written between two fdatasync calls of --sync-test")
+	dperfCmd.PersistentFlags().IntVarP(&fsyncFreq,
+		"fsync-freq", "", fsyncFreq, "fdatasync the test files after every this many blocks written, reporting the sync latency next to the throughput")
+	dperfCmd.PersistentFlags().StringVarP(&syncMode,
+		"sync-mode", "", syncMode, "how the test files are opened for writing, one of direct, dsync (O_DSYNC), sync (O_SYNC)")
+	dperfCmd.PersistentFlags().StringVarP(&writeMode,
+		"write-mode", "", writeMode, "how the write phase writes the test files, one of fresh (newly created files), overwrite (in place, the files are written once beforehand)")
+	dperfCmd.PersistentFlags().StringVarP(&dataPattern,
+		"data-pattern", "", dataPattern, "data written, one of random, zero, compressible:N with N the percent that compresses away, e.g. compressible:50")
+	dperfCmd.PersistentFlags().StringVarP(&fill,
+		"fill", "", fill, "write until this percent of the capacity of every drive is in use, or it is full, reporting the throughput by fill level, e.g. 90%")
+	dperfCmd.PersistentFlags().DurationVarP(&ramp,
+		"ramp", "", ramp, "write with 1 concurrent I/O per drive at first and ramp up to ioperdrive over this long, reporting the throughput by depth, e.g. 2m")
+	dperfCmd.PersistentFlags().DurationVarP(&soak,
+		"soak", "", soak, "repeat write and read cycles for this long, printing and recording the results of every cycle, e.g. 24h")
+	dperfCmd.PersistentFlags().BoolVarP(&verify,
+		"verify", "", verify, "embed a checksum in every 4KiB written and check it when read back, failing drives whose data is corrupt")
+	dperfCmd.PersistentFlags().BoolVarP(&keepFiles,
+		"keep-files", "", keepFiles, "leave the test files on the drives, in .dperf, for a later run to --reuse")
+	dperfCmd.PersistentFlags().BoolVarP(&reuse,
+		"reuse", "", reuse, "skip the write phase and read the files left by a run with --keep-files, removing them unless kept again")
+	dperfCmd.PersistentFlags().BoolVarP(&warmRead,
+		"warm-read", "", warmRead, "read the files again through the page cache once read from the drives, and compare both")
+	dperfCmd.PersistentFlags().BoolVarP(&mdTest,
+		"metadata-test", "", mdTest, "measure create, stat, rename and unlink of small files per second instead of the throughput")
+	dperfCmd.PersistentFlags().IntVarP(&mdFiles,
+		"metadata-files", "", mdFiles, "number of files per concurrent I/O of --metadata-test")
+	dperfCmd.PersistentFlags().StringVarP(&erasure,
+		"erasure", "", erasure, "lay the --objects out as MinIO does on a drive of an erasure set of data+parity drives, e.g. 12+4")
+	dperfCmd.PersistentFlags().StringVarP(&partSize,
+		"part-size", "", partSize, "split the --erasure objects in parts of this size as multipart uploads do, e.g. 16MiB")
+	dperfCmd.PersistentFlags().IntVarP(&objects,
+		"objects", "", objects, "write and read back this many small objects per concurrent I/O instead of large files, reporting objects per second")
+	dperfCmd.PersistentFlags().StringVarP(&objectSize,
+		"object-size", "", objectSize, "size, or range of sizes, of the --objects, small sizes are the most common")
 	dperfCmd.PersistentFlags().BoolVarP(&verbose,
 		"verbose", "v", verbose, "print READ/WRITE for each paths independently, default only prints aggregated")
+	dperfCmd.PersistentFlags().BoolVarP(&quiet,
+		"quiet", "q", quiet, "do not print informational messages to stderr")
 	dperfCmd.PersistentFlags().StringVarP(&blockSize,
-		"blocksize", "b", blockSize, "read/write block size")
+		"blocksize", "b", blockSize, "read/write block size, a comma separated list or a doubling range, e.g. 4KiB..4MiB, runs the tests once per size")
 	dperfCmd.PersistentFlags().StringVarP(&fileSize,
-		"filesize", "f", fileSize, "amount of data to read/write per drive")
+		"filesize", "f", fileSize, "amount of data to read/write per drive, or a percent of the free space of every drive, e.g. 10%")
+	dperfCmd.PersistentFlags().StringVarP(&maxFileSize,
+		"max-filesize", "", maxFileSize, "cap the files sized by a percent --filesize to this size")
 	dperfCmd.PersistentFlags().IntVarP(&ioPerDrive,
 		"ioperdrive", "i", ioPerDrive, "number of concurrent I/O per drive, default is 4")
+	dperfCmd.PersistentFlags().StringVarP(&warmup,
+		"warmup", "", warmup, "transfer data for this long, or this many bytes per drive, before the tests without measuring it, e.g. 10s or 4GiB")
+	dperfCmd.PersistentFlags().IntVarP(&runs,
+		"runs", "", runs, "test every drive this many times and report the mean, min, max and standard deviation of the throughput and IOPS")
+	dperfCmd.PersistentFlags().DurationVarP(&duration,
+		"duration", "", duration, "run every phase for this long, rewriting and rereading the files, instead of once over --filesize, e.g. 60s")
+	dperfCmd.PersistentFlags().IntVarP(&rwMix,
+		"rwmix", "", rwMix, "percent of reads of a mixed phase reading and overwriting the files written, instead of the read phase, e.g. 70")
+	dperfCmd.PersistentFlags().StringVarP(&access,
+		"access", "", access, "order of the blocks read and written, one of sequential, random (block aligned offsets in random order), both (sequential then random)")
+	dperfCmd.PersistentFlags().StringVarP(&engine,
+		"engine", "", engine, "how the blocks are transferred, one of sync (pread and pwrite), libaio (io_submit and io_getevents), mmap (copies to and from mappings of the files), nvme (NVMe Read commands passed through to the namespace, read-only)")
+	dperfCmd.PersistentFlags().IntVarP(&aioDepth,
+		"aio-depth", "", aioDepth, "operations in flight per concurrent I/O of --engine libaio, every block is split in up to this many")
+	dperfCmd.PersistentFlags().StringSliceVarP(&rwFlags,
+		"rwf", "", rwFlags, "transfer every block with preadv2 and pwritev2 and these flags, dsync (RWF_DSYNC), hipri (RWF_HIPRI, polled)")
+	dperfCmd.PersistentFlags().BoolVarP(&copyFiles,
+		"copy", "", copyFiles, "copy the files of every drive once read with copy_file_range, next to them, and report the throughput of the copies")
+	dperfCmd.PersistentFlags().StringVarP(&copyTo,
+		"copy-to", "", copyTo, "copy the files of every drive into this directory, e.g. on another drive, implies --copy")
+	dperfCmd.PersistentFlags().BoolVarP(&poll,
+		"poll", "", poll, "run the tests again polling for the completion of every block (RWF_HIPRI), and compare the latency of both")
+	dperfCmd.PersistentFlags().StringVarP(&output,
+		"output", "", output, "output format of the results, one of table, json, markdown, tsv, cbor")
+	dperfCmd.PersistentFlags().StringVarP(&outputFile,
+		"output-file", "", outputFile, "write the results to this file instead of stdout")
+	dperfCmd.PersistentFlags().BoolVarP(&outputAppend,
+		"output-append", "", outputAppend, "append the results to --output-file instead of replacing it")
+	dperfCmd.PersistentFlags().StringVarP(&outputMaxSize,
+		"output-max-size", "", outputMaxSize, "rotate --output-file once it reaches this size in append mode, keeping 5 rotated files")
+	dperfCmd.PersistentFlags().StringVarP(&units,
+		"units", "", units, "units of the printed sizes and throughputs, one of iec (MiB/s), si (MB/s), raw (bytes)")
+	dperfCmd.PersistentFlags().BoolVarP(&noColor,
+		"no-color", "", noColor, "disable colors, also disabled when NO_COLOR is set or stdout is not a terminal")
+	dperfCmd.PersistentFlags().StringVarP(&colorTheme,
+		"color-theme", "", colorTheme, "color theme of the tables, one of default, high-contrast")
+	dperfCmd.PersistentFlags().StringVarP(&format,
+		"format", "", format, "print every result with this Go template instead of --output, e.g. '{{.Path}} {{.WriteThroughput}}'")
+	dperfCmd.PersistentFlags().StringArrayVarP(&tags,
+		"tag", "", tags, "key=value metadata embedded in all outputs, can be repeated")
+	dperfCmd.PersistentFlags().Int64VarP(&seed,
+		"seed", "", seed, "seed for the generated data to make runs reproducible, 0 picks a random seed")
+	dperfCmd.PersistentFlags().BoolVarP(&dryRun,
+		"dry-run", "", dryRun, "print what would be done per path and exit without touching the drives")
+	dperfCmd.PersistentFlags().StringVarP(&maxWrite,
+		"max-write", "", maxWrite, "cap the total amount of data written across all drives, filesize is scaled down to fit, not with --duration")
+	dperfCmd.PersistentFlags().StringVarP(&kafkaBrokers,
+		"kafka-brokers", "", kafkaBrokers, "publish the results as JSON to Kafka through these comma separated host:port brokers")
+	dperfCmd.PersistentFlags().StringVarP(&kafkaTopic,
+		"kafka-topic", "", kafkaTopic, "Kafka topic the results are published to")
+	dperfCmd.PersistentFlags().StringVarP(&kafkaKey,
+		"kafka-key", "", kafkaKey, "key of the Kafka messages, defaults to the hostname")
+	dperfCmd.PersistentFlags().BoolVarP(&kafkaProgress,
+		"kafka-progress", "", kafkaProgress, "also publish the progress to Kafka every --progress-interval")
+	dperfCmd.PersistentFlags().BoolVarP(&kafkaTLS,
+		"kafka-tls", "", kafkaTLS, "connect to the Kafka brokers over TLS")
+	dperfCmd.PersistentFlags().StringVarP(&kafkaUser,
+		"kafka-user", "", kafkaUser, "authenticate to the Kafka brokers with SASL/PLAIN as this user")
+	dperfCmd.PersistentFlags().StringVarP(&kafkaPassword,
+		"kafka-password", "", kafkaPassword, "password of --kafka-user, also read from "+kafkaPasswordEnv)
+	dperfCmd.PersistentFlags().StringVarP(&preCmd,
+		"pre-cmd", "", preCmd, "shell command to run before each drive is tested, see DPERF_* environment variables")
+	dperfCmd.PersistentFlags().StringVarP(&postCmd,
+		"post-cmd", "", postCmd, "shell command to run after each drive is tested, see DPERF_* environment variables")
+	dperfCmd.PersistentFlags().StringVarP(&metricsAddr,
+		"metrics-addr", "", metricsAddr, "serve the progress of the run as OpenMetrics on this address at /metrics, e.g. ':9100'")
+	dperfCmd.PersistentFlags().StringVarP(&statsdAddr,
+		"statsd", "", statsdAddr, "send per-drive throughput gauges with DogStatsD tags to this StatsD host:port during and after the run")
+	dperfCmd.PersistentFlags().StringVarP(&historyFile,
+		"history", "", historyFile, "record every run in this JSON Lines file, not a SQLite database, for 'dperf history', e.g. ~/.dperf/history.jsonl")
+	dperfCmd.PersistentFlags().StringVarP(&graphiteAddr,
+		"graphite", "", graphiteAddr, "send per-drive throughput to this Graphite/Carbon plaintext host:port during and after the run")
+	dperfCmd.PersistentFlags().StringVarP(&graphitePrefix,
+		"graphite-prefix", "", graphitePrefix, "prefix of the metrics sent to --graphite")
+	dperfCmd.PersistentFlags().StringVarP(&chartFile,
+		"chart", "", chartFile, "draw the throughput of every drive, and over time for long runs, to this .svg or .png file")
+	dperfCmd.PersistentFlags().DurationVarP(&latencyThreshold,
+		"latency-threshold", "", latencyThreshold, "count and timestamp the block operations slower than this per drive, e.g. '100ms'")
+	dperfCmd.PersistentFlags().BoolVarP(&score,
+		"score", "", score, "rank the drives by a composite score of throughput, IOPS and p99 latency instead of the read throughput")
+	dperfCmd.PersistentFlags().StringVarP(&scoreWeights,
+		"score-weights", "", scoreWeights, "weights of the --score components, e.g. 'throughput=5,iops=3,latency=2' (the default), implies --score")
+	dperfCmd.PersistentFlags().IntVarP(&slowestOps,
+		"slowest-ops", "", slowestOps, "record the time, offset and latency of this many slowest block operations per drive, 0 disables")
+	dperfCmd.PersistentFlags().Float64VarP(&maxDegradation,
+		"max-degradation", "", maxDegradation, "flag drives whose throughput drops by more than this percent from the start to the end of a phase")
+	dperfCmd.PersistentFlags().BoolVarP(&heatmap,
+		"heatmap", "", heatmap, "draw a live heatmap of the latency and a sparkline of the throughput of every drive over the last minute on stderr")
+	dperfCmd.PersistentFlags().BoolVarP(&series,
+		"series", "", series, "sample the throughput of every drive every second and include it in JSON and CBOR results")
+	dperfCmd.PersistentFlags().StringVarP(&seriesCSV,
+		"series-csv", "", seriesCSV, "write the throughput of every drive sampled every second to this CSV file")
+	dperfCmd.PersistentFlags().StringVarP(&histogramDir,
+		"histogram-dir", "", histogramDir, "write the latency histogram of every drive and phase to this directory in the HdrHistogram .hgrm format")
+	dperfCmd.PersistentFlags().StringVarP(&minWrite,
+		"min-write", "", minWrite, "fail unless every drive writes at least this fast, e.g. '500MiB'")
+	dperfCmd.PersistentFlags().StringVarP(&minRead,
+		"min-read", "", minRead, "fail unless every drive reads at least this fast, e.g. '1GiB'")
+	dperfCmd.PersistentFlags().StringVarP(&minTotalWrite,
+		"min-total-write", "", minTotalWrite, "fail unless the drives write at least this fast in total")
+	dperfCmd.PersistentFlags().StringVarP(&minTotalRead,
+		"min-total-read", "", minTotalRead, "fail unless the drives read at least this fast in total")
+	dperfCmd.PersistentFlags().StringVarP(&expectWrite,
+		"expect-write", "", expectWrite, "expected write throughput of a drive, results show the percent of it, e.g. '3GiB'")
+	dperfCmd.PersistentFlags().StringVarP(&expectRead,
+		"expect-read", "", expectRead, "expected read throughput of a drive, results show the percent of it, e.g. '6GiB'")
+	dperfCmd.PersistentFlags().StringVarP(&specFile,
+		"spec-file", "", specFile, "CSV file of 'model,write,read' expected throughputs per drive model")
+	dperfCmd.PersistentFlags().StringVarP(&webhookURL,
+		"webhook", "", webhookURL, "POST the results as JSON to this URL once the run is over")
+	dperfCmd.PersistentFlags().StringVarP(&webhookSecret,
+		"webhook-secret", "", webhookSecret, "sign --webhook requests with this HMAC-SHA256 key, also read from "+webhookSecretEnv)
+	dperfCmd.PersistentFlags().IntVarP(&webhookRetries,
+		"webhook-retries", "", webhookRetries, "number of times a failed --webhook request is retried")
+	dperfCmd.PersistentFlags().StringVarP(&logResultsTo,
+		"log-results", "", logResultsTo, "log the results of every drive as structured fields, one of syslog, journald")
+	dperfCmd.PersistentFlags().StringVarP(&promTextfile,
+		"prom-textfile", "", promTextfile, "write the results as Prometheus metrics to this file, for the node_exporter textfile collector")
+	dperfCmd.PersistentFlags().StringVarP(&stream,
+		"stream", "", stream, "print every progress update to stdout ahead of the results, one of ndjson")
+	dperfCmd.PersistentFlags().StringVarP(&progressURL,
+		"progress-url", "", progressURL, "POST aggregated progress as JSON to this URL during the run")
+	dperfCmd.PersistentFlags().DurationVarP(&statusInterval,
+		"status-interval", "", statusInterval, "print the phase, completion and current throughput of every drive to stderr at this interval, e.g. '5s'")
+	dperfCmd.PersistentFlags().DurationVarP(&progressInterval,
+		"progress-interval", "", progressInterval, "interval between progress updates")
 
 	// Go profiles
 	dperfCmd.PersistentFlags().StringVar(&profileDir,
@@ -270,5 +1550,24 @@ func init() {
 
 // Execute executes plugin command.
 func Execute(ctx context.Context) error {
-	return dperfCmd.ExecuteContext(ctx)
+	err := dperfCmd.ExecuteContext(ctx)
+	printViolations(err)
+	return err
+}
+
+// printViolations - lists on stderr the thresholds missed in every
+// ThresholdError of err, the stages of a job included.
+func printViolations(err error) {
+	switch e := err.(type) {
+	case *dperf.ThresholdError:
+		for _, v := range e.Violations {
+			fmt.Fprintln(os.Stderr, "[fail]", v)
+		}
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			printViolations(err)
+		}
+	case interface{ Unwrap() error }:
+		printViolations(e.Unwrap())
+	}
 }
@@ -0,0 +1,90 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/minio/dperf/pkg/dperf"
+	"github.com/spf13/cobra"
+)
+
+var tolerance = 5.0
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [flags] BASELINE (CURRENT | PATH...)",
+	Short: "Compare the results of a run to a baseline",
+	Long: `
+Compare the results of a run to a baseline
+-------------------------------------------
+  compare prints the per-drive change in write and read throughput from
+  the BASELINE results to the CURRENT results, both written with
+  '--output json' or '--output cbor'. When drive paths are given instead of CURRENT, the
+  drives are tested first. Drives that got slower by more than
+  --tolerance percent are flagged and make compare fail.
+`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Args:          cobra.MinimumNArgs(2),
+	Example: `
+# compare the results from before and after a firmware upgrade
+$ dperf --output json /mnt/drive{1..6} > before.json
+$ dperf --output json /mnt/drive{1..6} > after.json
+$ dperf compare before.json after.json
+
+# test the drives and compare them to a baseline, allowing 10% slowdown
+$ dperf compare --tolerance 10 before.json /mnt/drive{1..6}
+`,
+	RunE: func(c *cobra.Command, args []string) error {
+		if tolerance < 0 {
+			return fmt.Errorf("Invalid tolerance must be positive: %g", tolerance)
+		}
+		baseline, err := dperf.ReadReport(args[0])
+		if err != nil {
+			return err
+		}
+		if len(args) == 2 {
+			if st, err := os.Stat(args[1]); err == nil && st.Mode().IsRegular() {
+				current, err := dperf.ReadReport(args[1])
+				if err != nil {
+					return err
+				}
+				return dperf.CompareAndRender(baseline, current, tolerance)
+			}
+		}
+
+		perf, err := newDrivePerf(c)
+		if err != nil {
+			return err
+		}
+		paths, err := checkPaths(args[1:])
+		if err != nil {
+			return err
+		}
+		if err = fitWriteBudget(perf, runPlanned(perf, len(paths))); err != nil {
+			return err
+		}
+		return perf.RunAndCompare(c.Context(), baseline, tolerance, paths...)
+	},
+}
+
+func init() {
+	compareCmd.Flags().Float64VarP(&tolerance,
+		"tolerance", "", tolerance, "percentage drop in throughput tolerated before a drive is flagged as regressed")
+	dperfCmd.AddCommand(compareCmd)
+}
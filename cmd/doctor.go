@@ -0,0 +1,64 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [flags] PATH...",
+	Short: "Check the drives mounted at PATH... are ready for a run",
+	Long: `
+Check the drives mounted at PATH... are ready for a run
+--------------------------------------------------------
+  doctor verifies permissions, free space, O_DIRECT support, block
+  alignment, I/O scheduler, cgroup I/O limits and competing I/O on each
+  drive and prints a readiness report. The only write is the O_DIRECT
+  check, which writes and removes a 4KiB probe file.
+`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Args:          cobra.MinimumNArgs(1),
+	Example: `
+# check drives 1 to 6 with the options of the intended run
+$ dperf doctor --filesize 10GiB --ioperdrive 8 /mnt/drive{1..6}
+`,
+	RunE: func(c *cobra.Command, args []string) error {
+		perf, err := newDrivePerf(c)
+		if err != nil {
+			return err
+		}
+		paths, err := checkPaths(args)
+		if err != nil {
+			return err
+		}
+		if outputFile != "" {
+			f, err := openOutputFile()
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			perf.Out = f
+		}
+		return perf.DoctorAndRender(c.Context(), paths...)
+	},
+}
+
+func init() {
+	dperfCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,276 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fioSection - the options of a section of a fio job file, in order.
+type fioSection struct {
+	name    string
+	options map[string]string
+	order   []string
+}
+
+// parseFioFile - the sections of the fio job file at path.
+func parseFioFile(path string) ([]*fioSection, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sections []*fioSection
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			sections = append(sections, &fioSection{
+				name:    strings.TrimSpace(line[1 : len(line)-1]),
+				options: map[string]string{},
+			})
+			continue
+		}
+		if len(sections) == 0 {
+			return nil, fmt.Errorf("line %d: option outside of a section", n)
+		}
+		// Options without a value, e.g. time_based, are set.
+		key, value, _ := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		s := sections[len(sections)-1]
+		if _, ok := s.options[key]; !ok {
+			s.order = append(s.order, key)
+		}
+		s.options[key] = value
+	}
+	return sections, scanner.Err()
+}
+
+// readFioJob - reads the fio job file at path as a job, best effort: a
+// stage per fio job, with the global options, mapped to the options of
+// dperf that do the same. Options without an equivalent are ignored.
+func readFioJob(path string) (*job, error) {
+	sections, err := parseFioFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid fio job %s: %w", path, err)
+	}
+	global := &fioSection{options: map[string]string{}}
+	j := &job{}
+	for _, s := range sections {
+		if s.name == "global" {
+			for _, key := range s.order {
+				if _, ok := global.options[key]; !ok {
+					global.order = append(global.order, key)
+				}
+				global.options[key] = s.options[key]
+			}
+			continue
+		}
+		// The global options apply to the jobs that follow them.
+		merged := &fioSection{name: s.name, options: map[string]string{}}
+		for _, src := range []*fioSection{global, s} {
+			for _, key := range src.order {
+				if _, ok := merged.options[key]; !ok {
+					merged.order = append(merged.order, key)
+				}
+				merged.options[key] = src.options[key]
+			}
+		}
+		stage, err := fioStage(merged)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid fio job %s: %s: %w", path, s.name, err)
+		}
+		j.Stages = append(j.Stages, stage)
+	}
+	return j, nil
+}
+
+// fioStage - the stage doing what the fio job s does.
+func fioStage(s *fioSection) (jobStage, error) {
+	stage := jobStage{Name: s.name, Options: map[string]interface{}{}}
+	numJobs, ioDepth := 1, 1
+	for _, key := range []string{"numjobs", "iodepth"} {
+		if v, ok := s.options[key]; ok {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				return stage, fmt.Errorf("invalid %s %q", key, v)
+			}
+			if key == "numjobs" {
+				numJobs = n
+			} else {
+				ioDepth = n
+			}
+		}
+	}
+	// dperf keeps a single I/O in flight per concurrent I/O, the
+	// concurrency of fio is that of all its jobs at their depth.
+	stage.Options["ioperdrive"] = numJobs * ioDepth
+
+	for _, key := range s.order {
+		v := s.options[key]
+		switch key {
+		case "rw", "readwrite":
+			mode, _, _ := strings.Cut(v, ":")
+			switch mode {
+			case "read":
+			case "write":
+				stage.Options["write-only"] = true
+			case "randread":
+				stage.Options["access"] = "random"
+			case "randwrite":
+				stage.Options["access"] = "random"
+				stage.Options["write-only"] = true
+			case "rw", "readwrite":
+				stage.Options["rwmix"] = fioReadMix(s)
+			case "randrw":
+				stage.Options["access"] = "random"
+				stage.Options["rwmix"] = fioReadMix(s)
+			default:
+				return stage, fmt.Errorf("unsupported rw %q", v)
+			}
+		case "bs", "blocksize":
+			// Of distinct read and write sizes, or a range, the first.
+			first, _, _ := strings.Cut(v, ",")
+			first, _, _ = strings.Cut(first, "-")
+			size, err := parseFioSize(first)
+			if err != nil {
+				return stage, err
+			}
+			stage.Options["blocksize"] = size
+		case "size":
+			if strings.HasSuffix(v, "%") {
+				stage.Options["filesize"] = v
+				continue
+			}
+			size, err := parseFioSize(v)
+			if err != nil {
+				return stage, err
+			}
+			// Every job of fio writes size, spread over its depth.
+			size /= uint64(ioDepth)
+			stage.Options["filesize"] = size - size%alignSize
+		case "offset":
+			size, err := parseFioSize(v)
+			if err != nil {
+				return stage, err
+			}
+			stage.Options["offset"] = size
+		case "runtime":
+			d, err := parseFioTime(v)
+			if err != nil {
+				return stage, err
+			}
+			stage.Options["duration"] = d
+		case "directory", "filename":
+			stage.Paths = append(stage.Paths, strings.Split(v, ":")...)
+		case "nrfiles":
+			stage.Options["files-per-io"] = v
+		case "fsync", "fdatasync":
+			if v != "0" {
+				stage.Options["fsync-freq"] = v
+			}
+		case "sync":
+			switch v {
+			case "1", "sync":
+				stage.Options["sync-mode"] = "sync"
+			case "dsync":
+				stage.Options["sync-mode"] = "dsync"
+			}
+		case "verify":
+			if v != "0" && v != "null" {
+				stage.Options["verify"] = true
+			}
+		case "zero_buffers":
+			stage.Options["data-pattern"] = "zero"
+		case "buffer_compress_percentage":
+			stage.Options["data-pattern"] = "compressible:" + v
+		case "rate":
+			first, _, _ := strings.Cut(v, ",")
+			rate, err := parseFioSize(first)
+			if err != nil {
+				return stage, err
+			}
+			// The rate of fio is per job, that of dperf per drive.
+			stage.Options["rate"] = rate * uint64(numJobs)
+		case "numjobs", "iodepth", "rwmixread", "rwmixwrite":
+		case "ioengine":
+			if v == "libaio" || v == "mmap" {
+				stage.Options["engine"] = v
+			}
+		case "name", "description", "direct", "buffered", "time_based",
+			"group_reporting", "stonewall", "wait_for_previous", "new_group", "thread":
+			// Implied by dperf, or of no use to it.
+		default:
+			infof("ignoring fio option %s of %s", key, s.name)
+		}
+	}
+	return stage, nil
+}
+
+// fioReadMix - the percent of reads of the mixed fio job s.
+func fioReadMix(s *fioSection) string {
+	if v, ok := s.options["rwmixread"]; ok {
+		return v
+	}
+	if v, ok := s.options["rwmixwrite"]; ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			return strconv.Itoa(100 - n)
+		}
+	}
+	return "50"
+}
+
+// parseFioSize - parses a size as fio does, k, m, g and t being powers
+// of 1024 with or without a trailing b or ib, e.g. 4k or 1MiB.
+func parseFioSize(s string) (uint64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "b"), "i")
+	mult := uint64(1)
+	if v != "" {
+		if i := strings.IndexByte("kmgtp", v[len(v)-1]); i >= 0 {
+			mult = 1 << (10 * (i + 1))
+			v = v[:len(v)-1]
+		}
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// parseFioTime - parses a time as fio does, in seconds unless it has a
+// unit.
+func parseFioTime(s string) (time.Duration, error) {
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return d, nil
+}
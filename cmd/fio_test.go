@@ -0,0 +1,299 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFioFile - writes the fio job file job in a temporary directory.
+func writeFioFile(t *testing.T, job string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "job.fio")
+	if err := os.WriteFile(path, []byte(job), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseFioFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      string
+		sections []fioSection
+		err      string
+	}{
+		{
+			name: "sections",
+			job: `; a comment
+[global]
+ioengine=libaio
+# another comment
+direct = 1
+
+[seq-read]
+rw=read
+time_based
+bs=1m
+bs=4m
+`,
+			sections: []fioSection{
+				{name: "global", options: map[string]string{"ioengine": "libaio", "direct": "1"}, order: []string{"ioengine", "direct"}},
+				{name: "seq-read", options: map[string]string{"rw": "read", "time_based": "", "bs": "4m"}, order: []string{"rw", "time_based", "bs"}},
+			},
+		},
+		{
+			name:     "empty section",
+			job:      "[ job one ]\n",
+			sections: []fioSection{{name: "job one", options: map[string]string{}}},
+		},
+		{name: "option outside of a section", job: "rw=read\n[job]\n", err: "line 1: option outside of a section"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sections, err := parseFioFile(writeFioFile(t, test.job))
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sections) != len(test.sections) {
+				t.Fatalf("%d sections, want %d", len(sections), len(test.sections))
+			}
+			for i, s := range sections {
+				if !reflect.DeepEqual(*s, test.sections[i]) {
+					t.Errorf("section %d = %+v, want %+v", i, *s, test.sections[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFioStage(t *testing.T) {
+	saved := quiet
+	quiet = true
+	t.Cleanup(func() { quiet = saved })
+
+	tests := []struct {
+		name    string
+		options [][2]string
+		want    map[string]interface{}
+		paths   []string
+		err     string
+	}{
+		{
+			name:    "defaults",
+			options: nil,
+			want:    map[string]interface{}{"ioperdrive": 1},
+		},
+		{
+			name:    "sequential write",
+			options: [][2]string{{"rw", "write"}, {"bs", "1m"}, {"iodepth", "4"}, {"numjobs", "2"}},
+			want:    map[string]interface{}{"ioperdrive": 8, "write-only": true, "blocksize": uint64(1 << 20)},
+		},
+		{
+			name:    "random read with a read ahead suffix",
+			options: [][2]string{{"readwrite", "randread:8"}, {"bs", "4k,64k"}},
+			want:    map[string]interface{}{"ioperdrive": 1, "access": "random", "blocksize": uint64(4 << 10)},
+		},
+		{
+			name:    "random mix",
+			options: [][2]string{{"rw", "randrw"}, {"rwmixwrite", "30"}, {"bs", "8k-64k"}},
+			want:    map[string]interface{}{"ioperdrive": 1, "access": "random", "rwmix": "70", "blocksize": uint64(8 << 10)},
+		},
+		{
+			name:    "mix defaults to half reads",
+			options: [][2]string{{"rw", "rw"}},
+			want:    map[string]interface{}{"ioperdrive": 1, "rwmix": "50"},
+		},
+		{
+			name:    "size spread over the depth, 4KiB aligned",
+			options: [][2]string{{"iodepth", "3"}, {"size", "10g"}, {"runtime", "30"}, {"offset", "1m"}},
+			want:    map[string]interface{}{"ioperdrive": 3, "filesize": uint64(3579138048), "duration": 30 * time.Second, "offset": uint64(1 << 20)},
+		},
+		{
+			name:    "size in percent",
+			options: [][2]string{{"size", "50%"}, {"runtime", "2m"}},
+			want:    map[string]interface{}{"ioperdrive": 1, "filesize": "50%", "duration": 2 * time.Minute},
+		},
+		{
+			name:    "durability and data",
+			options: [][2]string{{"fdatasync", "16"}, {"sync", "dsync"}, {"verify", "crc32c"}, {"buffer_compress_percentage", "50"}, {"rate", "100m"}, {"numjobs", "4"}},
+			want: map[string]interface{}{
+				"ioperdrive": 4, "fsync-freq": "16", "sync-mode": "dsync", "verify": true,
+				"data-pattern": "compressible:50", "rate": uint64(400 << 20),
+			},
+		},
+		{
+			name:    "paths and engine",
+			options: [][2]string{{"directory", "/mnt/drive1:/mnt/drive2"}, {"ioengine", "libaio"}, {"nrfiles", "4"}},
+			want:    map[string]interface{}{"ioperdrive": 1, "engine": "libaio", "files-per-io": "4"},
+			paths:   []string{"/mnt/drive1", "/mnt/drive2"},
+		},
+		{
+			name:    "options of no use",
+			options: [][2]string{{"direct", "1"}, {"time_based", ""}, {"group_reporting", ""}, {"ioengine", "io_uring"}, {"fsync", "0"}, {"verify", "0"}},
+			want:    map[string]interface{}{"ioperdrive": 1},
+		},
+		{
+			name:    "unknown options",
+			options: [][2]string{{"cpus_allowed", "0-3"}, {"norandommap", ""}, {"bs", "128k"}},
+			want:    map[string]interface{}{"ioperdrive": 1, "blocksize": uint64(128 << 10)},
+		},
+		{name: "invalid iodepth", options: [][2]string{{"iodepth", "0"}}, err: `invalid iodepth "0"`},
+		{name: "invalid numjobs", options: [][2]string{{"numjobs", "many"}}, err: `invalid numjobs "many"`},
+		{name: "unsupported rw", options: [][2]string{{"rw", "trim"}}, err: `unsupported rw "trim"`},
+		{name: "invalid size", options: [][2]string{{"bs", "4x"}}, err: `invalid size "4x"`},
+		{name: "invalid runtime", options: [][2]string{{"runtime", "soon"}}, err: `invalid time "soon"`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &fioSection{name: "job", options: map[string]string{}}
+			for _, o := range test.options {
+				s.options[o[0]] = o[1]
+				s.order = append(s.order, o[0])
+			}
+			stage, err := fioStage(s)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(stage.Options, test.want) {
+				t.Errorf("options = %v, want %v", stage.Options, test.want)
+			}
+			if !reflect.DeepEqual(stage.Paths, test.paths) {
+				t.Errorf("paths = %v, want %v", stage.Paths, test.paths)
+			}
+		})
+	}
+}
+
+func TestReadFioJob(t *testing.T) {
+	saved := quiet
+	quiet = true
+	t.Cleanup(func() { quiet = saved })
+
+	job := `[global]
+directory=/mnt/drive1
+bs=4k
+rw=randread
+
+[read]
+
+[write]
+rw=write
+bs=1m
+unknown_option=1
+
+[global]
+iodepth=2
+
+[after]
+`
+	j, err := readFioJob(writeFioFile(t, job))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []jobStage{
+		{Name: "read", Paths: []string{"/mnt/drive1"}, Options: map[string]interface{}{"ioperdrive": 1, "blocksize": uint64(4 << 10), "access": "random"}},
+		{Name: "write", Paths: []string{"/mnt/drive1"}, Options: map[string]interface{}{"ioperdrive": 1, "blocksize": uint64(1 << 20), "write-only": true}},
+		// A later global section applies to the jobs that follow it.
+		{Name: "after", Paths: []string{"/mnt/drive1"}, Options: map[string]interface{}{"ioperdrive": 2, "blocksize": uint64(4 << 10), "access": "random"}},
+	}
+	if !reflect.DeepEqual(j.Stages, want) {
+		t.Fatalf("stages = %+v, want %+v", j.Stages, want)
+	}
+
+	if _, err = readFioJob(writeFioFile(t, "[job]\nrw=trim\n")); err == nil || !strings.Contains(err.Error(), `job: unsupported rw "trim"`) {
+		t.Fatalf("error = %v, want the unsupported rw of the job", err)
+	}
+}
+
+func TestParseFioSize(t *testing.T) {
+	tests := []struct {
+		size string
+		want uint64
+		err  bool
+	}{
+		{size: "4096", want: 4096},
+		{size: "4k", want: 4 << 10},
+		{size: "4K", want: 4 << 10},
+		{size: "4kb", want: 4 << 10},
+		{size: "4KiB", want: 4 << 10},
+		{size: "1m", want: 1 << 20},
+		{size: "2g", want: 2 << 30},
+		{size: "1t", want: 1 << 40},
+		{size: " 8k ", want: 8 << 10},
+		{size: "", err: true},
+		{size: "k", err: true},
+		{size: "4x", err: true},
+		{size: "-1k", err: true},
+	}
+	for _, test := range tests {
+		got, err := parseFioSize(test.size)
+		if test.err {
+			if err == nil {
+				t.Errorf("parseFioSize(%q) = %d, want an error", test.size, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("parseFioSize(%q) = %d, %v, want %d", test.size, got, err, test.want)
+		}
+	}
+}
+
+func TestParseFioTime(t *testing.T) {
+	tests := []struct {
+		time string
+		want time.Duration
+		err  bool
+	}{
+		{time: "60", want: time.Minute},
+		{time: "90s", want: 90 * time.Second},
+		{time: "5m", want: 5 * time.Minute},
+		{time: "1h30m", want: 90 * time.Minute},
+		{time: "", err: true},
+		{time: "1d", err: true},
+	}
+	for _, test := range tests {
+		got, err := parseFioTime(test.time)
+		if test.err {
+			if err == nil {
+				t.Errorf("parseFioTime(%q) = %v, want an error", test.time, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("parseFioTime(%q) = %v, %v, want %v", test.time, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// graphiteClient - sends metrics to Carbon using the plaintext protocol,
+// tags are sent as Graphite tags. Every batch is sent on a new connection.
+type graphiteClient struct {
+	prefix string
+	// tags appended to every metric, ";k=v" encoded.
+	tags string
+}
+
+func newGraphiteClient(tags map[string]string) *graphiteClient {
+	g := &graphiteClient{prefix: strings.TrimSuffix(graphitePrefix, ".")}
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g.tags += ";" + graphiteTagEscaper.Replace(k) + "=" + graphiteTagEscaper.Replace(tags[k])
+	}
+	return g
+}
+
+// graphiteBatch - metrics of a single send, sharing a timestamp.
+type graphiteBatch struct {
+	g   *graphiteClient
+	ts  int64
+	buf bytes.Buffer
+}
+
+func (g *graphiteClient) newBatch() *graphiteBatch {
+	return &graphiteBatch{g: g, ts: time.Now().Unix()}
+}
+
+// add - queues a metric, name is relative to the prefix.
+func (b *graphiteBatch) add(name string, value uint64) {
+	if b.g.prefix != "" {
+		name = b.g.prefix + "." + name
+	}
+	fmt.Fprintf(&b.buf, "%s%s %d %d\n", name, b.g.tags, value, b.ts)
+}
+
+// latency - queues the latency percentiles of a phase in microseconds,
+// e.g. <node>.write.latency_us.p99.
+func (b *graphiteBatch) latency(name string, l *dperf.LatencyStats) {
+	if l == nil {
+		return
+	}
+	for _, p := range l.Percentiles() {
+		b.add(name+".latency_us."+p.Name, uint64(p.Latency.Microseconds()))
+	}
+}
+
+func (b *graphiteBatch) send() error {
+	if b.buf.Len() == 0 {
+		return nil
+	}
+	conn, err := net.DialTimeout("tcp", graphiteAddr, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err = conn.Write(b.buf.Bytes()); err != nil {
+		conn.Close()
+		return err
+	}
+	return conn.Close()
+}
+
+// sendProgress - sends the current throughput of every drive.
+func (g *graphiteClient) sendProgress(drives []dperf.DriveProgress) {
+	b := g.newBatch()
+	for _, p := range drives {
+		node := graphiteNode(p.Path) + ".progress." + string(p.Phase)
+		b.add(node+".bytes_per_second", p.Throughput)
+		b.add(node+".bytes", p.Bytes)
+	}
+	if err := b.send(); err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] unable to send progress to %s: %v\n", graphiteAddr, err)
+	}
+}
+
+// sendReport - sends the results of the run.
+func (g *graphiteClient) sendReport(report *dperf.Report) error {
+	b := g.newBatch()
+	for _, result := range report.Results {
+		node := graphiteNode(result.Path)
+		var failed uint64
+		if result.Error != nil {
+			failed = 1
+		} else {
+			b.add(node+".write.bytes_per_second", result.WriteThroughput)
+			b.add(node+".read.bytes_per_second", result.ReadThroughput)
+			b.add(node+".write.iops", result.WriteIOPS)
+			b.add(node+".read.iops", result.ReadIOPS)
+			b.latency(node+".write", result.WriteLatency)
+			b.latency(node+".read", result.ReadLatency)
+			b.latency(node+".sync", result.SyncLatency)
+		}
+		b.add(node+".written.bytes", result.TotalBytesWritten)
+		b.add(node+".read.bytes", result.TotalBytesRead)
+		b.add(node+".write.ops", result.WriteOps)
+		b.add(node+".read.ops", result.ReadOps)
+		b.add(node+".failed", failed)
+	}
+	b.add("total.write.bytes_per_second", report.TotalWriteThroughput)
+	b.add("total.read.bytes_per_second", report.TotalReadThroughput)
+	b.add("total.write.iops", report.TotalWriteIOPS)
+	b.add("total.read.iops", report.TotalReadIOPS)
+	if err := b.send(); err != nil {
+		return fmt.Errorf("unable to send results to %s: %w", graphiteAddr, err)
+	}
+	return nil
+}
+
+// graphiteNode - maps a drive path to a single metric path node,
+// e.g. /mnt/drive1 to drives._mnt_drive1.
+func graphiteNode(path string) string {
+	return "drives." + graphiteNodeEscaper.Replace(path)
+}
+
+var (
+	graphiteNodeEscaper = strings.NewReplacer("/", "_", ".", "_", " ", "_", ";", "_")
+	graphiteTagEscaper  = strings.NewReplacer(";", "_", "~", "_", "=", "_", " ", "_", "\n", "_")
+)
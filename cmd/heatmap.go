@@ -0,0 +1,91 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+const (
+	// heatmapInterval - time covered by a column of the heatmap.
+	heatmapInterval = time.Second
+	// heatmapWidth - columns kept on screen, older ones scroll out.
+	heatmapWidth = 60
+)
+
+// startHeatmap - redraws the latency heatmap and the throughput sparkline
+// of every drive on stderr every second as requested by --heatmap, the
+// returned function stops it and leaves the last frame on screen.
+func startHeatmap(ctx context.Context, perf *dperf.DrivePerf) (func(), error) {
+	if !heatmap {
+		return func() {}, nil
+	}
+	if !isatty.IsTerminal(os.Stderr.Fd()) {
+		return nil, errors.New("--heatmap needs stderr to be a terminal")
+	}
+
+	hm := &dperf.LatencyHeatmap{Width: heatmapWidth}
+	addLatency(perf, hm.Record)
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(heatmapInterval)
+		defer ticker.Stop()
+		lines := 0
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if lines > 0 {
+					// Move back to the first line of the previous frame and clear it.
+					fmt.Fprintf(os.Stderr, "\x1b[%dF\x1b[J", lines)
+				}
+				lines, _ = hm.Render(os.Stderr, perf.Timeline)
+				hm.Tick()
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}, nil
+}
+
+// addLatency - chains fn to the latency callback of perf.
+func addLatency(perf *dperf.DrivePerf, fn func(string, dperf.Phase, time.Duration)) {
+	prev := perf.Latency
+	if prev == nil {
+		perf.Latency = fn
+		return
+	}
+	perf.Latency = func(path string, phase dperf.Phase, latency time.Duration) {
+		prev(path, phase, latency)
+		fn(path, phase, latency)
+	}
+}
@@ -0,0 +1,84 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/dperf/pkg/dperf"
+	"github.com/spf13/cobra"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [flags] [PATH]",
+	Short: "List previous runs, or the results of the drive mounted at PATH over time",
+	Long: `
+List previous runs, or the results of the drive mounted at PATH over time
+--------------------------------------------------------------------------
+  Runs are recorded in the file set by --history. history lists the recorded
+  runs, or the throughput of a single drive across the runs along with
+  the change from one run to the next.
+
+  The history is a JSON Lines file, one report per line, rather than a
+  SQLite database: it needs no database driver, appending a run cannot
+  corrupt the previous ones, and it can be read with grep, jq or
+  'dperf report'.
+`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Args:          cobra.MaximumNArgs(1),
+	Example: `
+# record the runs
+$ dperf --history ~/.dperf/history.jsonl /mnt/drive{1..6}
+
+# list all previous runs
+$ dperf history --history ~/.dperf/history.jsonl
+
+# show the throughput trend of a drive
+$ dperf history --history ~/.dperf/history.jsonl /mnt/drive1
+`,
+	RunE: func(c *cobra.Command, args []string) error {
+		if historyFile == "" {
+			return errors.New("--history is not set")
+		}
+		reports, err := dperf.ReadHistory(historyFile)
+		if err != nil {
+			return err
+		}
+		var path string
+		if len(args) == 1 {
+			path = filepath.Clean(args[0])
+		}
+		err = dperf.RenderHistory(os.Stdout, reports, path)
+		if errors.Is(err, dperf.ErrNoRuns) {
+			infof("%v", err)
+			return nil
+		}
+		return err
+	},
+}
+
+// recordHistory - records the run in --history.
+func recordHistory(report *dperf.Report) error {
+	return dperf.AppendHistory(historyFile, report)
+}
+
+func init() {
+	dperfCmd.AddCommand(historyCmd)
+}
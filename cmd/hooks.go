@@ -0,0 +1,64 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// setupHooks - runs --pre-cmd and --post-cmd around each drive test.
+func setupHooks(perf *dperf.DrivePerf) {
+	if preCmd != "" {
+		perf.PreRun = func(ctx context.Context, path string) error {
+			return runHook(ctx, preCmd, "DPERF_PHASE=pre", "DPERF_PATH="+path)
+		}
+	}
+	if postCmd != "" {
+		perf.PostRun = func(ctx context.Context, result *dperf.DrivePerfResult) {
+			env := []string{
+				"DPERF_PHASE=post",
+				"DPERF_PATH=" + result.Path,
+				"DPERF_WRITE_THROUGHPUT=" + strconv.FormatUint(result.WriteThroughput, 10),
+				"DPERF_READ_THROUGHPUT=" + strconv.FormatUint(result.ReadThroughput, 10),
+				"DPERF_WRITE_IOPS=" + strconv.FormatUint(result.WriteIOPS, 10),
+				"DPERF_READ_IOPS=" + strconv.FormatUint(result.ReadIOPS, 10),
+			}
+			if result.Error != nil {
+				env = append(env, "DPERF_ERROR="+result.Error.Error())
+			}
+			if err := runHook(ctx, postCmd, env...); err != nil {
+				fmt.Fprintf(os.Stderr, "[warn] post-cmd failed for %s: %v\n", result.Path, err)
+			}
+		}
+	}
+}
+
+// runHook - runs command through the shell with env added to the
+// environment, its output goes to stderr to keep stdout for results.
+func runHook(ctx context.Context, command string, env ...string) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
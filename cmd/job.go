@@ -0,0 +1,246 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var jobCmd = &cobra.Command{
+	Use:   "run [flags] JOB",
+	Short: "Run the stages of the job file JOB one after the other",
+	Long: `
+Run the stages of the job file JOB one after the other
+-------------------------------------------------------
+  A job is a YAML, TOML or JSON file of stages, each a run of dperf with
+  its own options, named like the flags, and paths. A fio job file, named
+  *.fio, is read as a stage per fio job, its rw, bs, iodepth, numjobs,
+  size and runtime among others mapped to the options of dperf, the
+  other fio options ignored. Options and paths at
+  the top of the job apply to every stage, the flags of the command line
+  to every stage that does not set them. The results of every stage are
+  tagged with stage=NAME. The stages are all validated before the first
+  one starts, a stage that fails does not stop the next ones.
+`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Args:          cobra.ExactArgs(1),
+	Example: `
+# qualify new drives with the stages of qualify.yaml
+$ cat qualify.yaml
+paths: [/mnt/drive1, /mnt/drive2, /mnt/drive3, /mnt/drive4]
+options:
+  duration: 1m
+stages:
+  - name: sequential write
+    options:
+      write-only: true
+      blocksize: 4MiB
+  - name: random 4KiB at qd8
+    options:
+      access: random
+      blocksize: 4KiB
+      ioperdrive: 8
+  - name: metadata churn
+    options:
+      metadata-test: true
+$ dperf run qualify.yaml
+
+# reuse a fio job file, the paths given by its directory options
+$ dperf run randread.fio
+`,
+	RunE: func(c *cobra.Command, args []string) error {
+		job, err := readJob(args[0])
+		if err != nil {
+			return err
+		}
+		flags := saveFlags(c.Flags())
+
+		// Invalid options fail the job before anything is written.
+		for i, stage := range job.Stages {
+			if err := job.apply(c, flags, i); err != nil {
+				return err
+			}
+			if _, err := newDrivePerf(c); err != nil {
+				return fmt.Errorf("stage %q: %w", stage.Name, err)
+			}
+			if _, err := checkPaths(job.paths(i)); err != nil {
+				return fmt.Errorf("stage %q: %w", stage.Name, err)
+			}
+		}
+
+		var errs []error
+		for i, stage := range job.Stages {
+			if err := job.apply(c, flags, i); err != nil {
+				return err
+			}
+			if i > 0 && outputFile != "" {
+				// The results of the stages follow one another.
+				outputAppend = true
+			}
+			infof("stage %d of %d: %s", i+1, len(job.Stages), stage.Name)
+			if err := runDperf(c, job.paths(i)); err != nil {
+				errs = append(errs, fmt.Errorf("stage %q: %w", stage.Name, err))
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// jobStage - a run of dperf in a job.
+type jobStage struct {
+	Name    string
+	Paths   []string
+	Options map[string]interface{}
+}
+
+// job - stages run one after the other, with the paths and options
+// common to all of them.
+type job struct {
+	Paths   []string
+	Options map[string]interface{}
+	Stages  []jobStage
+}
+
+// readJob - reads the job file at path, of any format viper reads or a
+// fio job file.
+func readJob(path string) (*job, error) {
+	j := &job{}
+	if strings.EqualFold(filepath.Ext(path), ".fio") {
+		var err error
+		if j, err = readFioJob(path); err != nil {
+			return nil, err
+		}
+	} else {
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("Invalid job %s: %w", path, err)
+		}
+		if err := v.Unmarshal(j); err != nil {
+			return nil, fmt.Errorf("Invalid job %s: %w", path, err)
+		}
+	}
+	if len(j.Stages) == 0 {
+		return nil, fmt.Errorf("Invalid job %s: no stages", path)
+	}
+	for i := range j.Stages {
+		if j.Stages[i].Name == "" {
+			j.Stages[i].Name = fmt.Sprintf("stage-%d", i+1)
+		}
+		if len(j.paths(i)) == 0 {
+			return nil, fmt.Errorf("Invalid job %s: stage %q has no paths", path, j.Stages[i].Name)
+		}
+	}
+	return j, nil
+}
+
+// paths - the paths of the stage i.
+func (j *job) paths(i int) []string {
+	if len(j.Stages[i].Paths) > 0 {
+		return j.Stages[i].Paths
+	}
+	return j.Paths
+}
+
+// apply - sets the flags to those of the command line, then to the
+// options of the job and of the stage i.
+func (j *job) apply(c *cobra.Command, flags []savedFlag, i int) error {
+	restoreFlags(flags)
+	stage := j.Stages[i]
+	for _, options := range []map[string]interface{}{j.Options, stage.Options} {
+		for name, value := range options {
+			if err := setOption(c.Flags(), name, value); err != nil {
+				return fmt.Errorf("stage %q: %w", stage.Name, err)
+			}
+		}
+	}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "stage=") {
+			return nil
+		}
+	}
+	return c.Flags().Set("tag", "stage="+stage.Name)
+}
+
+// setOption - sets the flag name to value, every element of it for a
+// list.
+func setOption(flags *pflag.FlagSet, name string, value interface{}) error {
+	f := flags.Lookup(name)
+	if f == nil {
+		return fmt.Errorf("unknown option %q", name)
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	if sv, ok := f.Value.(pflag.SliceValue); ok && f.Changed {
+		// A list replaces the one set before, of the command line or
+		// of the options of the job.
+		sv.Replace(nil)
+	}
+	for _, v := range values {
+		if err := flags.Set(name, fmt.Sprint(v)); err != nil {
+			return fmt.Errorf("Invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// savedFlag - the value of a flag as set on the command line.
+type savedFlag struct {
+	flag    *pflag.Flag
+	value   string
+	slice   []string
+	changed bool
+}
+
+// saveFlags - the values of all the flags of the command line.
+func saveFlags(flags *pflag.FlagSet) []savedFlag {
+	var saved []savedFlag
+	flags.VisitAll(func(f *pflag.Flag) {
+		s := savedFlag{flag: f, value: f.Value.String(), changed: f.Changed}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			s.slice = sv.GetSlice()
+		}
+		saved = append(saved, s)
+	})
+	return saved
+}
+
+// restoreFlags - sets the flags back to their saved values.
+func restoreFlags(saved []savedFlag) {
+	for _, s := range saved {
+		if sv, ok := s.flag.Value.(pflag.SliceValue); ok {
+			sv.Replace(s.slice)
+		} else {
+			s.flag.Value.Set(s.value)
+		}
+		s.flag.Changed = s.changed
+	}
+}
+
+func init() {
+	dperfCmd.AddCommand(jobCmd)
+}
@@ -0,0 +1,235 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// testJobFlags - a flag set with a flag of every kind the options of a
+// job set.
+func testJobFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("dperf", pflag.ContinueOnError)
+	flags.String("blocksize", "4MiB", "")
+	flags.Int("ioperdrive", 4, "")
+	flags.Bool("verify", false, "")
+	flags.StringSlice("rwf", nil, "")
+	return flags
+}
+
+func TestSetOption(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		// option and value set.
+		option string
+		value  interface{}
+		// flag and want are the value of the flag after.
+		flag string
+		want string
+		err  string
+	}{
+		{name: "string", option: "blocksize", value: "64KiB", flag: "blocksize", want: "64KiB"},
+		{name: "int", option: "ioperdrive", value: 8, flag: "ioperdrive", want: "8"},
+		{name: "int as string", option: "ioperdrive", value: "16", flag: "ioperdrive", want: "16"},
+		{name: "bool", option: "verify", value: true, flag: "verify", want: "true"},
+		{name: "over the command line", args: []string{"--blocksize", "1MiB"}, option: "blocksize", value: "8KiB", flag: "blocksize", want: "8KiB"},
+		{name: "list", option: "rwf", value: []interface{}{"dsync", "hipri"}, flag: "rwf", want: "[dsync,hipri]"},
+		{name: "single element list", option: "rwf", value: "hipri", flag: "rwf", want: "[hipri]"},
+		{name: "list replaces the command line", args: []string{"--rwf", "dsync"}, option: "rwf", value: []interface{}{"hipri"}, flag: "rwf", want: "[hipri]"},
+		{name: "unknown option", option: "iodepth", value: 8, err: `unknown option "iodepth"`},
+		{name: "invalid value", option: "ioperdrive", value: "many", err: "Invalid ioperdrive"},
+		{name: "invalid list element", option: "verify", value: []interface{}{true, "maybe"}, err: "Invalid verify"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := testJobFlags()
+			if err := flags.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			err := setOption(flags, test.option, test.value)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := flags.Lookup(test.flag)
+			if got := f.Value.String(); got != test.want {
+				t.Fatalf("%s = %s, want %s", test.flag, got, test.want)
+			}
+			if !f.Changed {
+				t.Fatalf("%s is not changed", test.flag)
+			}
+		})
+	}
+}
+
+func TestRestoreFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		options map[string]interface{}
+	}{
+		{name: "defaults", options: map[string]interface{}{"blocksize": "8KiB", "rwf": "dsync", "verify": true}},
+		{name: "command line", args: []string{"--blocksize", "1MiB", "--rwf", "dsync,hipri"}, options: map[string]interface{}{"blocksize": "8KiB", "rwf": []interface{}{"hipri"}, "ioperdrive": 1}},
+		{name: "nothing set", args: []string{"--verify"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flags := testJobFlags()
+			if err := flags.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			type state struct {
+				value   string
+				changed bool
+			}
+			want := map[string]state{}
+			flags.VisitAll(func(f *pflag.Flag) {
+				want[f.Name] = state{f.Value.String(), f.Changed}
+			})
+
+			saved := saveFlags(flags)
+			for name, value := range test.options {
+				if err := setOption(flags, name, value); err != nil {
+					t.Fatal(err)
+				}
+			}
+			restoreFlags(saved)
+
+			flags.VisitAll(func(f *pflag.Flag) {
+				if got := (state{f.Value.String(), f.Changed}); got != want[f.Name] {
+					t.Errorf("%s = %+v after restore, want %+v", f.Name, got, want[f.Name])
+				}
+			})
+		})
+	}
+}
+
+func TestJobApply(t *testing.T) {
+	saved := tags
+	t.Cleanup(func() { tags = saved })
+
+	c := &cobra.Command{}
+	c.Flags().AddFlagSet(testJobFlags())
+	c.Flags().StringSliceVar(&tags, "tag", nil, "")
+	if err := c.Flags().Parse([]string{"--blocksize", "1MiB", "--tag", "rack=r1"}); err != nil {
+		t.Fatal(err)
+	}
+	flags := saveFlags(c.Flags())
+
+	j := &job{
+		Options: map[string]interface{}{"ioperdrive": 8, "verify": true},
+		Stages: []jobStage{
+			{Name: "small", Options: map[string]interface{}{"blocksize": "4KiB", "ioperdrive": 32}},
+			{Name: "large"},
+			{Name: "tagged", Options: map[string]interface{}{"tag": "stage=mine"}},
+		},
+	}
+	tests := []struct {
+		blocksize  string
+		ioperdrive string
+		tags       []string
+	}{
+		// The options of the stage win over those of the job, those of
+		// the job over the command line.
+		{"4KiB", "32", []string{"rack=r1", "stage=small"}},
+		// Nothing of the stage before is left.
+		{"1MiB", "8", []string{"rack=r1", "stage=large"}},
+		// A stage tag of the stage is kept.
+		{"1MiB", "8", []string{"stage=mine"}},
+	}
+	for i, test := range tests {
+		if err := j.apply(c, flags, i); err != nil {
+			t.Fatal(err)
+		}
+		got := []string{c.Flags().Lookup("blocksize").Value.String(), c.Flags().Lookup("ioperdrive").Value.String()}
+		if want := []string{test.blocksize, test.ioperdrive}; !reflect.DeepEqual(got, want) {
+			t.Errorf("stage %s: blocksize and ioperdrive = %v, want %v", j.Stages[i].Name, got, want)
+		}
+		if c.Flags().Lookup("verify").Value.String() != "true" {
+			t.Errorf("stage %s: verify of the job is not set", j.Stages[i].Name)
+		}
+		if !reflect.DeepEqual(tags, test.tags) {
+			t.Errorf("stage %s: tags = %v, want %v", j.Stages[i].Name, tags, test.tags)
+		}
+	}
+}
+
+func TestReadJob(t *testing.T) {
+	tests := []struct {
+		name   string
+		job    string
+		stages []string
+		paths  [][]string
+		err    string
+	}{
+		{
+			name: "stages",
+			job: `paths: [/mnt/drive1, /mnt/drive2]
+stages:
+  - name: write
+    options:
+      write-only: true
+  - paths: [/mnt/drive3]
+`,
+			stages: []string{"write", "stage-2"},
+			paths:  [][]string{{"/mnt/drive1", "/mnt/drive2"}, {"/mnt/drive3"}},
+		},
+		{name: "no stages", job: "paths: [/mnt/drive1]\n", err: "no stages"},
+		{name: "no paths", job: "stages:\n  - name: write\n", err: `stage "write" has no paths`},
+		{name: "invalid", job: "stages: [\n", err: "Invalid job"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "job.yaml")
+			if err := os.WriteFile(path, []byte(test.job), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			j, err := readJob(path)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("error = %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stages []string
+			var paths [][]string
+			for i, stage := range j.Stages {
+				stages = append(stages, stage.Name)
+				paths = append(paths, j.paths(i))
+			}
+			if !reflect.DeepEqual(stages, test.stages) || !reflect.DeepEqual(paths, test.paths) {
+				t.Fatalf("stages %v with paths %v, want %v with %v", stages, paths, test.stages, test.paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/dperf/internal/kafka"
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// kafkaPasswordEnv - alternative to --kafka-password that keeps the
+// password out of the process list.
+const kafkaPasswordEnv = "DPERF_KAFKA_PASSWORD"
+
+// kafkaClient - publishes the results, and the progress with
+// --kafka-progress, as JSON messages to --kafka-topic.
+type kafkaClient struct {
+	producer *kafka.Producer
+	topic    string
+	tags     map[string]string
+}
+
+func newKafkaClient(tags map[string]string) (*kafkaClient, error) {
+	var brokers []string
+	for _, b := range strings.Split(kafkaBrokers, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			brokers = append(brokers, b)
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, errors.New("--kafka-brokers is empty")
+	}
+	if kafkaTopic == "" {
+		return nil, errors.New("--kafka-topic must be set with --kafka-brokers")
+	}
+	key := kafkaKey
+	if key == "" {
+		key, _ = os.Hostname()
+	}
+	producer := kafka.NewProducer(brokers, kafkaTopic, []byte(key))
+	if kafkaTLS {
+		producer.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	producer.User, producer.Password = kafkaUser, kafkaPassword
+	if producer.Password == "" {
+		producer.Password = os.Getenv(kafkaPasswordEnv)
+	}
+	if producer.User == "" && producer.Password != "" {
+		return nil, errors.New("--kafka-user must be set with a Kafka password")
+	}
+	return &kafkaClient{producer: producer, topic: kafkaTopic, tags: tags}, nil
+}
+
+// sendProgress - publishes the progress of every drive with --kafka-progress.
+func (k *kafkaClient) sendProgress(drives []dperf.DriveProgress) {
+	if !kafkaProgress {
+		return
+	}
+	err := k.publish(progressReport{
+		Time:   time.Now().UTC(),
+		Tags:   k.tags,
+		Drives: drives,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] unable to publish progress to kafka topic %s: %v\n", k.topic, err)
+	}
+}
+
+// sendReport - publishes the JSON report of the run.
+func (k *kafkaClient) sendReport(report *dperf.Report) error {
+	if err := k.publish(report); err != nil {
+		return fmt.Errorf("unable to publish results to kafka topic %s: %w", k.topic, err)
+	}
+	return nil
+}
+
+func (k *kafkaClient) publish(v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return k.producer.Publish(value)
+}
@@ -0,0 +1,73 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+)
+
+// outputFileKeep - number of rotated output files kept, as FILE.1 (the
+// most recent) to FILE.N.
+const outputFileKeep = 5
+
+// openOutputFile - opens --output-file for the results. In append mode
+// the file is rotated first once it reached --output-max-size.
+func openOutputFile() (*os.File, error) {
+	var maxSize uint64
+	if outputMaxSize != "" {
+		if !outputAppend {
+			return nil, errors.New("--output-max-size requires --output-append")
+		}
+		var err error
+		maxSize, err = humanize.ParseBytes(outputMaxSize)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid output-max-size format: %v", err)
+		}
+	}
+	if !outputAppend {
+		return os.Create(outputFile)
+	}
+	if maxSize > 0 {
+		st, err := os.Stat(outputFile)
+		if err == nil && uint64(st.Size()) >= maxSize {
+			if err = rotateOutputFile(); err != nil {
+				return nil, fmt.Errorf("unable to rotate %s: %w", outputFile, err)
+			}
+		}
+	}
+	return os.OpenFile(outputFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+}
+
+// rotateOutputFile - shifts FILE.N-1 to FILE.N, ..., FILE to FILE.1,
+// dropping the oldest.
+func rotateOutputFile() error {
+	for i := outputFileKeep - 1; i >= 0; i-- {
+		from := outputFile
+		if i > 0 {
+			from = fmt.Sprintf("%s.%d", outputFile, i)
+		}
+		err := os.Rename(from, fmt.Sprintf("%s.%d", outputFile, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// progressReport - payload POSTed to --progress-url
+type progressReport struct {
+	Time   time.Time             `json:"time"`
+	Done   bool                  `json:"done"`
+	Tags   map[string]string     `json:"tags,omitempty"`
+	Drives []dperf.DriveProgress `json:"drives"`
+}
+
+// startProgressReporting - wires up the progress consumers requested on
+// the command line, the returned function stops them once the run is over.
+func startProgressReporting(ctx context.Context, perf *dperf.DrivePerf, sinks []metricsSink) (func(), error) {
+	if progressURL == "" && metricsAddr == "" && len(sinks) == 0 {
+		return func() {}, nil
+	}
+
+	tracker := &dperf.ProgressTracker{}
+	addProgress(perf, tracker.Update)
+
+	stopMetrics := func() {}
+	if metricsAddr != "" {
+		var err error
+		stopMetrics, err = startMetricsServer(perf, tracker)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	// report - pushes the progress to every consumer that polls the tracker.
+	report := func(ctx context.Context, done bool) {
+		if progressURL != "" {
+			postProgress(ctx, perf, tracker, done)
+		}
+		if !done {
+			for _, sink := range sinks {
+				sink.sendProgress(tracker.Snapshot())
+			}
+		}
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if progressURL == "" && len(sinks) == 0 {
+			return
+		}
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report(ctx, false)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+		report(context.Background(), true)
+		stopMetrics()
+	}, nil
+}
+
+// startMetricsServer - serves the progress of the run as OpenMetrics on
+// --metrics-addr until the returned function is called.
+func startMetricsServer(perf *dperf.DrivePerf, tracker *dperf.ProgressTracker) (func(), error) {
+	l, err := net.Listen("tcp", metricsAddr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on --metrics-addr: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		tracker.WriteOpenMetrics(w, perf.Tags)
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go srv.Serve(l)
+	infof("serving metrics on http://%s/metrics", l.Addr())
+	return func() { srv.Close() }, nil
+}
+
+// addProgress - chains fn with the progress consumers already set on perf.
+func addProgress(perf *dperf.DrivePerf, fn func(dperf.ProgressUpdate)) {
+	prev := perf.Progress
+	if prev == nil {
+		perf.Progress = fn
+		return
+	}
+	perf.Progress = func(u dperf.ProgressUpdate) {
+		prev(u)
+		fn(u)
+	}
+}
+
+// setupStream - prints every progress update to stdout as requested by
+// --stream, ahead of the results.
+func setupStream(perf *dperf.DrivePerf) {
+	if stream != streamNDJSON {
+		return
+	}
+	var mu sync.Mutex
+	enc := json.NewEncoder(os.Stdout)
+	addProgress(perf, func(u dperf.ProgressUpdate) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(u)
+	})
+}
+
+func postProgress(ctx context.Context, perf *dperf.DrivePerf, tracker *dperf.ProgressTracker, done bool) {
+	report := progressReport{
+		Time:   time.Now().UTC(),
+		Done:   done,
+		Tags:   perf.Tags,
+		Drives: tracker.Snapshot(),
+	}
+	if err := postJSON(ctx, progressURL, report); err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] unable to post progress to %s: %v\n", progressURL, err)
+	}
+}
+
+// postJSON - POSTs v encoded as JSON to url.
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return post(ctx, url, body, nil)
+}
+
+// statusError - non-2xx response to a POST.
+type statusError struct {
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return "unexpected response " + e.status
+}
+
+// post - POSTs a JSON body to url along with extra headers.
+func post(ctx context.Context, url string, body []byte, header http.Header) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return &statusError{status: resp.Status, code: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// publisher - hands the report of a run to a sink other than stdout.
+type publisher func(report *dperf.Report) error
+
+// metricsSink - a metrics system fed with the progress during the run
+// and with the results once it is over.
+type metricsSink interface {
+	sendProgress(drives []dperf.DriveProgress)
+	sendReport(report *dperf.Report) error
+}
+
+// dialMetricsSinks - connects to the metrics systems requested on the
+// command line.
+func dialMetricsSinks(tags map[string]string) ([]metricsSink, error) {
+	var sinks []metricsSink
+	if statsdAddr != "" {
+		s, err := dialStatsd(tags)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+	if graphiteAddr != "" {
+		sinks = append(sinks, newGraphiteClient(tags))
+	}
+	if kafkaBrokers != "" {
+		k, err := newKafkaClient(tags)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, k)
+	}
+	return sinks, nil
+}
+
+// setupPublishers - wires up the result sinks requested on the command line.
+func setupPublishers(perf *dperf.DrivePerf, sinks []metricsSink, timeline *dperf.Timeline) {
+	var publishers []publisher
+	if promTextfile != "" {
+		publishers = append(publishers, writePromTextfile)
+	}
+	if chartFile != "" {
+		publishers = append(publishers, func(report *dperf.Report) error {
+			return dperf.WriteChart(chartFile, report, timeline)
+		})
+	}
+	if seriesCSV != "" {
+		publishers = append(publishers, func(report *dperf.Report) error {
+			return dperf.WriteSeriesCSV(seriesCSV, report)
+		})
+	}
+	if histogramDir != "" {
+		publishers = append(publishers, func(report *dperf.Report) error {
+			if err := dperf.WriteHistograms(histogramDir, report); err != nil {
+				return fmt.Errorf("unable to write histograms to %s: %w", histogramDir, err)
+			}
+			return nil
+		})
+	}
+	for _, sink := range sinks {
+		publishers = append(publishers, sink.sendReport)
+	}
+	if webhookURL != "" {
+		publishers = append(publishers, sendWebhook)
+	}
+	if logResultsTo != "" {
+		publishers = append(publishers, logResults)
+	}
+	if historyFile != "" {
+		publishers = append(publishers, recordHistory)
+	}
+	if len(publishers) == 0 {
+		return
+	}
+	perf.Publish = func(report *dperf.Report) error {
+		var errs []error
+		for _, publish := range publishers {
+			errs = append(errs, publish(report))
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// writePromTextfile - writes the report to --prom-textfile, the file is
+// replaced atomically so the textfile collector never reads a partial file.
+func writePromTextfile(report *dperf.Report) error {
+	f, err := os.CreateTemp(filepath.Dir(promTextfile), "."+filepath.Base(promTextfile)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", promTextfile, err)
+	}
+	defer os.Remove(f.Name())
+
+	err = report.WritePrometheus(f)
+	if err == nil {
+		err = f.Chmod(0o644)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), promTextfile)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write %s: %w", promTextfile, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/minio/dperf/pkg/dperf"
+	"github.com/spf13/cobra"
+)
+
+var slowest = 10
+
+var reportCmd = &cobra.Command{
+	Use:   "report [flags] (DIR | FILE)...",
+	Short: "Aggregate the results of many runs, e.g. collected from a fleet of hosts",
+	Long: `
+Aggregate the results of many runs, e.g. collected from a fleet of hosts
+-------------------------------------------------------------------------
+  report reads results written with '--output json' or '--output cbor'
+  and history files, directories are searched recursively. It prints the
+  distribution of the per-drive throughput, the average throughput of
+  every drive model and the drives furthest below the fleet median.
+`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Args:          cobra.MinimumNArgs(1),
+	Example: `
+# summarize the results collected from all hosts
+$ dperf report /srv/dperf-results/
+
+# list the 50 slowest drives as JSON
+$ dperf report --slowest 50 --output json /srv/dperf-results/
+`,
+	RunE: func(c *cobra.Command, args []string) error {
+		if slowest < 0 {
+			return fmt.Errorf("Invalid slowest must be positive: %d", slowest)
+		}
+		switch output {
+		case dperf.OutputTable, dperf.OutputJSON:
+		default:
+			return fmt.Errorf("Invalid output format %q, must be one of table, json", output)
+		}
+		reports, err := dperf.ReadReports(args...)
+		if err != nil {
+			return err
+		}
+		if len(reports) == 0 {
+			return errors.New("no results found")
+		}
+		return dperf.AggregateReports(reports, slowest).Render(os.Stdout, output)
+	},
+}
+
+func init() {
+	reportCmd.Flags().IntVarP(&slowest,
+		"slowest", "", slowest, "number of slowest drives to list")
+	dperfCmd.AddCommand(reportCmd)
+}
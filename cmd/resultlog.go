@@ -0,0 +1,144 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// Supported --log-results destinations
+const (
+	logResultsSyslog   = "syslog"
+	logResultsJournald = "journald"
+)
+
+// journaldSocket - native protocol socket of systemd-journald.
+const journaldSocket = "/run/systemd/journal/socket"
+
+// logField - a structured field of a result log entry.
+type logField struct {
+	key, value string
+}
+
+// resultLogEntries - one entry per drive followed by the totals.
+func resultLogEntries(report *dperf.Report) (entries [][]logField) {
+	keys := make([]string, 0, len(report.Tags))
+	for k := range report.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var tags []logField
+	for _, k := range keys {
+		tags = append(tags, logField{"tag_" + k, report.Tags[k]})
+	}
+	for _, result := range report.Results {
+		entry := []logField{
+			{"path", result.Path},
+			{"write_bytes_per_second", strconv.FormatUint(result.WriteThroughput, 10)},
+			{"read_bytes_per_second", strconv.FormatUint(result.ReadThroughput, 10)},
+			{"write_iops", strconv.FormatUint(result.WriteIOPS, 10)},
+			{"read_iops", strconv.FormatUint(result.ReadIOPS, 10)},
+			{"written_bytes", strconv.FormatUint(result.TotalBytesWritten, 10)},
+			{"read_bytes", strconv.FormatUint(result.TotalBytesRead, 10)},
+			{"write_ops", strconv.FormatUint(result.WriteOps, 10)},
+			{"read_ops", strconv.FormatUint(result.ReadOps, 10)},
+			{"write_seconds", strconv.FormatFloat(result.WriteElapsed.Seconds(), 'f', 3, 64)},
+			{"read_seconds", strconv.FormatFloat(result.ReadElapsed.Seconds(), 'f', 3, 64)},
+		}
+		if result.Error != nil {
+			entry = append(entry, logField{"error", result.Error.Error()})
+		}
+		entries = append(entries, append(entry, tags...))
+	}
+	entries = append(entries, append([]logField{
+		{"path", "total"},
+		{"write_bytes_per_second", strconv.FormatUint(report.TotalWriteThroughput, 10)},
+		{"read_bytes_per_second", strconv.FormatUint(report.TotalReadThroughput, 10)},
+		{"write_iops", strconv.FormatUint(report.TotalWriteIOPS, 10)},
+		{"read_iops", strconv.FormatUint(report.TotalReadIOPS, 10)},
+	}, tags...))
+	return entries
+}
+
+// logfmt - encodes fields as key=value pairs, quoting values as needed.
+func logfmt(fields []logField) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		v := f.value
+		if v == "" || strings.ContainsAny(v, " =\"\n") {
+			v = strconv.Quote(v)
+		}
+		parts[i] = f.key + "=" + v
+	}
+	return strings.Join(parts, " ")
+}
+
+// logResults - logs the results as structured entries to --log-results.
+func logResults(report *dperf.Report) error {
+	entries := resultLogEntries(report)
+	if logResultsTo == logResultsJournald {
+		return logJournald(entries)
+	}
+	return logSyslog(entries)
+}
+
+// logJournald - sends the entries using the journald native protocol, each
+// field is stored as DPERF_<KEY> next to a logfmt MESSAGE.
+func logJournald(entries [][]logField) error {
+	conn, err := net.Dial("unixgram", journaldSocket)
+	if err != nil {
+		return fmt.Errorf("unable to log results to journald: %w", err)
+	}
+	defer conn.Close()
+
+	for _, entry := range entries {
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "MESSAGE=%s\nPRIORITY=6\nSYSLOG_IDENTIFIER=dperf\n", journaldValue(logfmt(entry)))
+		for _, f := range entry {
+			fmt.Fprintf(&buf, "DPERF_%s=%s\n", journaldKey(f.key), journaldValue(f.value))
+		}
+		if _, err = conn.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("unable to log results to journald: %w", err)
+		}
+	}
+	return nil
+}
+
+// journaldKey - field names may only contain A-Z, 0-9 and '_'.
+func journaldKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, k)
+}
+
+// journaldValue - newlines would need the binary encoding, replace them.
+func journaldValue(v string) string {
+	return strings.ReplaceAll(v, "\n", " ")
+}
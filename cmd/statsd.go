@@ -0,0 +1,149 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// statsdMaxPacket - keeps datagrams below the common Ethernet MTU.
+const statsdMaxPacket = 1432
+
+// statsdClient - sends gauges to a StatsD server using DogStatsD tags,
+// it is safe for concurrent use.
+type statsdClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+	// tags added to every gauge.
+	tags []string
+	buf  bytes.Buffer
+}
+
+// dialStatsd - returns a client for --statsd.
+func dialStatsd(tags map[string]string) (*statsdClient, error) {
+	conn, err := net.Dial("udp", statsdAddr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to --statsd: %w", err)
+	}
+	s := &statsdClient{conn: conn}
+	for k, v := range tags {
+		s.tags = append(s.tags, statsdTag(k, v))
+	}
+	sort.Strings(s.tags)
+	return s, nil
+}
+
+// gauge - queues a gauge, tags are name/value pairs.
+func (s *statsdClient) gauge(name string, value uint64, tags ...string) {
+	var line strings.Builder
+	line.WriteString("dperf.")
+	line.WriteString(name)
+	line.WriteByte(':')
+	line.WriteString(strconv.FormatUint(value, 10))
+	line.WriteString("|g")
+	all := append([]string{}, s.tags...)
+	for i := 0; i+1 < len(tags); i += 2 {
+		all = append(all, statsdTag(tags[i], tags[i+1]))
+	}
+	if len(all) > 0 {
+		line.WriteString("|#")
+		line.WriteString(strings.Join(all, ","))
+	}
+	line.WriteByte('\n')
+
+	if s.buf.Len() > 0 && s.buf.Len()+line.Len() > statsdMaxPacket {
+		s.flush()
+	}
+	s.buf.WriteString(line.String())
+}
+
+// flush - sends the queued gauges, StatsD is fire and forget so errors
+// are ignored.
+func (s *statsdClient) flush() {
+	if s.buf.Len() == 0 {
+		return
+	}
+	s.conn.Write(s.buf.Bytes())
+	s.buf.Reset()
+}
+
+// sendProgress - sends the current throughput of every drive.
+func (s *statsdClient) sendProgress(drives []dperf.DriveProgress) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range drives {
+		s.gauge("progress.bytes_per_second", p.Throughput, "path", p.Path, "phase", string(p.Phase))
+		s.gauge("progress.bytes", p.Bytes, "path", p.Path, "phase", string(p.Phase))
+	}
+	s.flush()
+}
+
+// latency - sends the latency percentiles of a phase in microseconds,
+// e.g. write.latency_us.p99.
+func (s *statsdClient) latency(phase string, l *dperf.LatencyStats, path string) {
+	if l == nil {
+		return
+	}
+	for _, p := range l.Percentiles() {
+		s.gauge(phase+".latency_us."+p.Name, uint64(p.Latency.Microseconds()), "path", path)
+	}
+}
+
+// sendReport - sends the results of the run.
+func (s *statsdClient) sendReport(report *dperf.Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, result := range report.Results {
+		var failed uint64
+		if result.Error != nil {
+			failed = 1
+		} else {
+			s.gauge("write.bytes_per_second", result.WriteThroughput, "path", result.Path)
+			s.gauge("read.bytes_per_second", result.ReadThroughput, "path", result.Path)
+			s.gauge("write.iops", result.WriteIOPS, "path", result.Path)
+			s.gauge("read.iops", result.ReadIOPS, "path", result.Path)
+			s.latency("write", result.WriteLatency, result.Path)
+			s.latency("read", result.ReadLatency, result.Path)
+			s.latency("sync", result.SyncLatency, result.Path)
+		}
+		s.gauge("written.bytes", result.TotalBytesWritten, "path", result.Path)
+		s.gauge("read.bytes", result.TotalBytesRead, "path", result.Path)
+		s.gauge("write.ops", result.WriteOps, "path", result.Path)
+		s.gauge("read.ops", result.ReadOps, "path", result.Path)
+		s.gauge("drive.failed", failed, "path", result.Path)
+	}
+	s.gauge("total.write.bytes_per_second", report.TotalWriteThroughput)
+	s.gauge("total.read.bytes_per_second", report.TotalReadThroughput)
+	s.gauge("total.write.iops", report.TotalWriteIOPS)
+	s.gauge("total.read.iops", report.TotalReadIOPS)
+	s.flush()
+	return nil
+}
+
+var statsdTagEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+func statsdTag(k, v string) string {
+	return statsdTagEscaper.Replace(k) + ":" + statsdTagEscaper.Replace(v)
+}
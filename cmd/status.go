@@ -0,0 +1,85 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// startStatusLines - prints the phase, completion and current throughput
+// of every drive to stderr every --status-interval, one line per drive,
+// for CI logs and dumb terminals. The returned function stops it.
+func startStatusLines(ctx context.Context, perf *dperf.DrivePerf) func() {
+	if statusInterval <= 0 {
+		return func() {}
+	}
+
+	tracker := &dperf.ProgressTracker{}
+	addProgress(perf, tracker.Update)
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(statusInterval)
+		defer ticker.Stop()
+		last := make(map[string]dperf.DriveProgress)
+		lastAt := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				dt := now.Sub(lastAt).Seconds()
+				for _, p := range tracker.Snapshot() {
+					fmt.Fprintln(os.Stderr, statusLine(p, last[p.Path], dt))
+					last[p.Path] = p
+				}
+				lastAt = now
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// statusLine - status of a drive from its progress p and its progress
+// prev dt seconds earlier.
+func statusLine(p, prev dperf.DriveProgress, dt float64) string {
+	var percent float64
+	if p.Total > 0 {
+		percent = float64(p.Bytes) / float64(p.Total) * 100
+	}
+	bytes := p.Bytes
+	if prev.Phase == p.Phase {
+		bytes -= prev.Bytes
+	}
+	rate := "-"
+	if p.Bytes < p.Total && dt > 0 {
+		rate = dperf.FormatBytes(uint64(float64(bytes)/dt)) + "/s"
+	}
+	return fmt.Sprintf("[status] %s %s %.1f%% %s", p.Path, p.Phase, percent, rate)
+}
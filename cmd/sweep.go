@@ -0,0 +1,132 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/minio/dperf/pkg/dperf"
+	"github.com/spf13/cobra"
+)
+
+var sweepIOPerDrive = "1,2,4,8,16"
+
+var sweepCmd = &cobra.Command{
+	Use:   "sweep [flags] PATH...",
+	Short: "Measure the drives mounted at PATH... at increasing concurrency",
+	Long: `
+Measure the drives mounted at PATH... at increasing concurrency
+----------------------------------------------------------------
+  sweep runs the tests once for every number of concurrent I/O per drive
+  of --ioperdrive-list and prints the throughput, IOPS and tail latency of
+  each drive by concurrency, to tell the queue depth a drive saturates at.
+  The thresholds apply to every run and --max-write bounds the writes of
+  all of them.
+`,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	Args:          cobra.MinimumNArgs(1),
+	Example: `
+# measure drives 1 to 6 with 1 to 16 concurrent I/O each
+$ dperf sweep --ioperdrive-list 1,2,4,8,16 /mnt/drive{1..6}
+`,
+	RunE: func(c *cobra.Command, args []string) error {
+		perf, err := newDrivePerf(c)
+		if err != nil {
+			return err
+		}
+		if perf.Format != nil || dryRun || soak > 0 {
+			return errors.New("sweep cannot be combined with --format, --dry-run or --soak")
+		}
+		if c.Flags().Changed("ioperdrive") {
+			return errors.New("sweep cannot be combined with --ioperdrive, use --ioperdrive-list")
+		}
+		values, err := parseIOPerDriveList(sweepIOPerDrive)
+		if err != nil {
+			return err
+		}
+		paths, err := checkPaths(args)
+		if err != nil {
+			return err
+		}
+		if err = fitWriteBudget(perf, sweepPlanned(perf, dperf.SweepIOPerDrive, values, len(paths))); err != nil {
+			return err
+		}
+		return runWithReporting(c, perf, func(ctx context.Context) error {
+			return perf.SweepAndRender(ctx, dperf.SweepIOPerDrive, values, paths...)
+		})
+	},
+}
+
+// parseIOPerDriveList - parses a comma separated list of concurrencies,
+// e.g. "1,2,4,8".
+func parseIOPerDriveList(s string) ([]uint64, error) {
+	var values []uint64
+	for _, v := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 31)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("Invalid ioperdrive-list %q, must be a list of numbers greater than 0, e.g. 1,2,4,8", s)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// parseBlockSizes - parses a block size, a comma separated list of them
+// or a range from one size to another doubling the size at every step,
+// e.g. "4KiB..4MiB".
+func parseBlockSizes(s string) ([]uint64, error) {
+	if from, to, ok := strings.Cut(s, ".."); ok {
+		first, err := parseBlockSize(strings.TrimSpace(from))
+		if err != nil {
+			return nil, err
+		}
+		last, err := parseBlockSize(strings.TrimSpace(to))
+		if err != nil {
+			return nil, err
+		}
+		if last < first {
+			return nil, fmt.Errorf("Invalid blocksize range %q, must be increasing", s)
+		}
+		sizes := []uint64{first}
+		// Doubling stops before it overflows.
+		for bs := first; bs <= last/2; {
+			bs *= 2
+			sizes = append(sizes, bs)
+		}
+		return sizes, nil
+	}
+	var sizes []uint64
+	for _, v := range strings.Split(s, ",") {
+		bs, err := parseBlockSize(strings.TrimSpace(v))
+		if err != nil {
+			return nil, err
+		}
+		sizes = append(sizes, bs)
+	}
+	return sizes, nil
+}
+
+func init() {
+	sweepCmd.Flags().StringVar(&sweepIOPerDrive,
+		"ioperdrive-list", sweepIOPerDrive, "comma separated numbers of concurrent I/O per drive to test")
+	dperfCmd.AddCommand(sweepCmd)
+}
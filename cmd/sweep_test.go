@@ -0,0 +1,68 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBlockSizes(t *testing.T) {
+	const (
+		kib = 1 << 10
+		mib = 1 << 20
+	)
+	tests := []struct {
+		sizes string
+		want  []uint64
+		err   string
+	}{
+		{sizes: "4MiB", want: []uint64{4 * mib}},
+		{sizes: "4KiB,64KiB,1MiB", want: []uint64{4 * kib, 64 * kib, mib}},
+		{sizes: "4KiB, 8KiB", want: []uint64{4 * kib, 8 * kib}},
+		{sizes: "1MiB,4KiB", want: []uint64{mib, 4 * kib}},
+		{sizes: "4KiB..64KiB", want: []uint64{4 * kib, 8 * kib, 16 * kib, 32 * kib, 64 * kib}},
+		{sizes: "4KiB .. 16KiB", want: []uint64{4 * kib, 8 * kib, 16 * kib}},
+		{sizes: "4KiB..4KiB", want: []uint64{4 * kib}},
+		// The range ends at the last doubling not past its end.
+		{sizes: "4KiB..20KiB", want: []uint64{4 * kib, 8 * kib, 16 * kib}},
+		{sizes: "12KiB..48KiB", want: []uint64{12 * kib, 24 * kib, 48 * kib}},
+		{sizes: "4EiB..8EiB", want: []uint64{4 << 60, 8 << 60}},
+		{sizes: "8KiB..4KiB", err: "must be increasing"},
+		{sizes: "", err: "Invalid blocksize format"},
+		{sizes: "4KiB,,8KiB", err: "Invalid blocksize format"},
+		{sizes: "4KiB,", err: "Invalid blocksize format"},
+		{sizes: "..8KiB", err: "Invalid blocksize format"},
+		{sizes: "4KiB..8KiB,16KiB", err: "Invalid blocksize format"},
+		{sizes: "2KiB", err: "must greater than 4k"},
+		{sizes: "4KiB,6KiB", err: "must be multiples of 4k"},
+		{sizes: "6KiB..64KiB", err: "must be multiples of 4k"},
+	}
+	for _, test := range tests {
+		got, err := parseBlockSizes(test.sizes)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("parseBlockSizes(%q) = %v, %v, want error %q", test.sizes, got, err, test.err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseBlockSizes(%q) = %v, %v, want %v", test.sizes, got, err, test.want)
+		}
+	}
+}
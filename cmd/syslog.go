@@ -0,0 +1,41 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// logSyslog - logs every entry as a logfmt message to the local syslog.
+func logSyslog(entries [][]logField) error {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "dperf")
+	if err != nil {
+		return fmt.Errorf("unable to log results to syslog: %w", err)
+	}
+	defer w.Close()
+
+	for _, entry := range entries {
+		if err = w.Info(logfmt(entry)); err != nil {
+			return fmt.Errorf("unable to log results to syslog: %w", err)
+		}
+	}
+	return nil
+}
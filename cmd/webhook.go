@@ -0,0 +1,91 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cmd
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/minio/dperf/pkg/dperf"
+)
+
+// webhookSignatureHeader - carries "sha256=<hex HMAC-SHA256 of the body>"
+// when a webhook secret is set.
+const webhookSignatureHeader = "X-Dperf-Signature"
+
+// webhookSecretEnv - alternative to --webhook-secret that keeps the
+// secret out of the process list.
+const webhookSecretEnv = "DPERF_WEBHOOK_SECRET"
+
+// sendWebhook - POSTs the JSON report to --webhook, retrying failed
+// attempts with an exponential backoff.
+func sendWebhook(report *dperf.Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	header := make(http.Header)
+	secret := webhookSecret
+	if secret == "" {
+		secret = os.Getenv(webhookSecretEnv)
+	}
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err = post(context.Background(), webhookURL, body, header)
+		if err == nil || attempt >= webhookRetries || !retryable(err) {
+			break
+		}
+		fmt.Fprintf(os.Stderr, "[warn] unable to send the results to %s, retrying in %s: %v\n", webhookURL, backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("unable to send the results to %s: %w", webhookURL, err)
+	}
+	return nil
+}
+
+// retryable - client errors other than timeouts and throttling are not
+// going to succeed on a retry.
+func retryable(err error) bool {
+	var serr *statusError
+	if !errors.As(err, &serr) {
+		return true
+	}
+	switch {
+	case serr.code == http.StatusRequestTimeout, serr.code == http.StatusTooManyRequests:
+		return true
+	case serr.code >= 400 && serr.code < 500:
+		return false
+	}
+	return true
+}
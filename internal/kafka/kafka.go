@@ -0,0 +1,546 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package kafka is a minimal Kafka producer, just enough for dperf to
+// publish its results: one uncompressed record per message to the
+// leader of the partition of the message key, with acks from the leader,
+// over TLS and SASL/PLAIN if configured to.
+package kafka
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kafka API keys and the versions used, Produce v3 is the first version
+// taking record batches and the oldest one Kafka 4 still accepts.
+const (
+	apiProduce          = 0
+	apiMetadata         = 3
+	apiSaslHandshake    = 17
+	apiSaslAuthenticate = 36
+	produceVersion      = 3
+	metadataVersion     = 4
+	saslVersion         = 1
+	saslAuthVersion     = 0
+	clientID            = "dperf"
+	requestTimeout      = 10 * time.Second
+	maxResponseSize     = 64 << 20
+	// attempts - sends of a message, the leader is looked up again
+	// after a failed one.
+	attempts = 3
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// Producer publishes one message per send to the leader of the partition
+// of the message key. The connection to the leader is kept for the
+// following sends, a send failing on it, or refused by a broker that no
+// longer leads the partition, looks up the leader again and is retried.
+type Producer struct {
+	// TLS if set is the config of the connections to the brokers.
+	TLS *tls.Config
+	// User and Password if set authenticate with SASL/PLAIN.
+	User, Password string
+
+	mu      sync.Mutex
+	brokers []string
+	topic   string
+	key     []byte
+	// conn to the leader of partition, nil until looked up.
+	conn      net.Conn
+	partition int32
+	// correlation id of the last request.
+	id int32
+}
+
+// NewProducer returns a producer of messages with key to topic, the
+// leader of the partition of key is looked up through brokers.
+func NewProducer(brokers []string, topic string, key []byte) *Producer {
+	return &Producer{brokers: brokers, topic: topic, key: key}
+}
+
+// Publish sends value as a message, retrying on another connection or
+// leader where that may succeed.
+func (k *Producer) Publish(value []byte) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		if k.conn == nil {
+			if err := k.connectLeader(); err != nil {
+				return err
+			}
+		}
+		err := k.produce(value)
+		if err == nil || attempt >= attempts || !retryable(err) {
+			return err
+		}
+		// The leader moved or the connection broke, look it up again.
+		k.conn.Close()
+		k.conn = nil
+	}
+}
+
+// Close closes the connection to the leader, if any.
+func (k *Producer) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.conn == nil {
+		return nil
+	}
+	err := k.conn.Close()
+	k.conn = nil
+	return err
+}
+
+// produce - sends value to the partition of the key on the connection
+// to its leader.
+func (k *Producer) produce(value []byte) error {
+	resp, err := k.roundTrip(k.conn, apiProduce, produceVersion, produceRequest(k.topic, k.partition, k.key, value, time.Now()))
+	if err != nil {
+		return err
+	}
+	d := decoder{buf: resp}
+	for topics := d.int32(); topics > 0; topics-- {
+		d.string()
+		for partitions := d.int32(); partitions > 0; partitions-- {
+			d.int32()
+			if code := d.int16(); code != 0 && d.err == nil {
+				return brokerError(code)
+			}
+			d.int64() // base offset
+			d.int64() // log append time
+		}
+	}
+	return d.err
+}
+
+// produceRequest - the body of a Produce request of a record batch
+// holding a single record for partition of topic.
+func produceRequest(topic string, partition int32, key, value []byte, ts time.Time) []byte {
+	var req encoder
+	req.int16(-1) // transactional id
+	req.int16(1)  // acks from the leader
+	req.int32(int32(requestTimeout / time.Millisecond))
+	req.int32(1)
+	req.string(topic)
+	req.int32(1)
+	req.int32(partition)
+	batch := recordBatch(key, value, ts)
+	req.int32(int32(len(batch)))
+	req.raw(batch)
+	return req.buf
+}
+
+// retryable - reports if a send failing with err may succeed on
+// another connection or against another leader.
+func retryable(err error) bool {
+	var kerr brokerError
+	if errors.As(err, &kerr) {
+		return kerr == errLeaderNotAvailable || kerr == errNotLeader || kerr == errRequestTimedOut || kerr == errNotEnoughReplicas
+	}
+	return true
+}
+
+// connectLeader - asks the brokers in turn for the leader of the
+// partition of the message key and connects to it.
+func (k *Producer) connectLeader() error {
+	var err error
+	for _, broker := range k.brokers {
+		var leader string
+		if leader, k.partition, err = k.metadata(broker); err != nil {
+			continue
+		}
+		if k.conn, err = k.dial(leader); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// dial - connects to the broker at addr, over TLS and authenticated if
+// configured to.
+func (k *Producer) dial(addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+	var conn net.Conn
+	var err error
+	if k.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, k.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if k.User != "" {
+		if err = k.authenticate(conn); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("kafka SASL authentication with %s: %w", addr, err)
+		}
+	}
+	return conn, nil
+}
+
+// authenticate - authenticates the connection with SASL/PLAIN.
+func (k *Producer) authenticate(conn net.Conn) error {
+	var req encoder
+	req.string("PLAIN")
+	resp, err := k.roundTrip(conn, apiSaslHandshake, saslVersion, req.buf)
+	if err != nil {
+		return err
+	}
+	d := decoder{buf: resp}
+	if code := d.int16(); code != 0 && d.err == nil {
+		return brokerError(code)
+	}
+	if d.err != nil {
+		return d.err
+	}
+
+	resp, err = k.roundTrip(conn, apiSaslAuthenticate, saslAuthVersion, saslPlain(k.User, k.Password))
+	if err != nil {
+		return err
+	}
+	d = decoder{buf: resp}
+	code := d.int16()
+	msg := d.string()
+	if d.err != nil {
+		return d.err
+	}
+	if code != 0 {
+		if msg != "" {
+			return fmt.Errorf("%w: %s", brokerError(code), msg)
+		}
+		return brokerError(code)
+	}
+	return nil
+}
+
+// saslPlain - the body of a SaslAuthenticate request carrying the
+// PLAIN credentials of user.
+func saslPlain(user, password string) []byte {
+	var req encoder
+	token := "\x00" + user + "\x00" + password
+	req.int32(int32(len(token)))
+	req.raw([]byte(token))
+	return req.buf
+}
+
+// metadata - asks broker for the leader of the partition of the key.
+func (k *Producer) metadata(broker string) (string, int32, error) {
+	conn, err := k.dial(broker)
+	if err != nil {
+		return "", 0, err
+	}
+	defer conn.Close()
+
+	var req encoder
+	req.int32(1)
+	req.string(k.topic)
+	req.int8(1) // allow auto topic creation
+	resp, err := k.roundTrip(conn, apiMetadata, metadataVersion, req.buf)
+	if err != nil {
+		return "", 0, err
+	}
+	return partitionLeader(resp, k.topic, k.key)
+}
+
+// partitionLeader - the address of the leader of the partition of key in
+// topic and the partition, from a Metadata response.
+func partitionLeader(resp []byte, topic string, key []byte) (string, int32, error) {
+	d := decoder{buf: resp}
+	d.int32() // throttle time
+	brokers := make(map[int32]string)
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.string() // cluster id
+	d.int32()  // controller id
+
+	var leaders []int32
+	for topics := d.int32(); topics > 0 && d.err == nil; topics-- {
+		code := d.int16()
+		name := d.string()
+		d.int8() // internal
+		for partitions := d.int32(); partitions > 0 && d.err == nil; partitions-- {
+			d.int16()
+			index := d.int32()
+			leader := d.int32()
+			d.int32Array() // replicas
+			d.int32Array() // in-sync replicas
+			if name != topic {
+				continue
+			}
+			if int(index) >= len(leaders) {
+				leaders = append(leaders, make([]int32, int(index)+1-len(leaders))...)
+			}
+			leaders[index] = leader
+		}
+		if name == topic && code != 0 && d.err == nil {
+			return "", 0, brokerError(code)
+		}
+	}
+	if d.err != nil {
+		return "", 0, d.err
+	}
+	if len(leaders) == 0 {
+		return "", 0, errLeaderNotAvailable
+	}
+
+	partition := int32(murmur2(key)&0x7fffffff) % int32(len(leaders))
+	addr, ok := brokers[leaders[partition]]
+	if !ok {
+		return "", 0, errLeaderNotAvailable
+	}
+	return addr, partition, nil
+}
+
+// roundTrip - sends a request and returns the response body following
+// the correlation id.
+func (k *Producer) roundTrip(conn net.Conn, apiKey, version int16, body []byte) ([]byte, error) {
+	k.id++
+	var req encoder
+	req.int32(0) // size, set below
+	req.int16(apiKey)
+	req.int16(version)
+	req.int32(k.id)
+	req.string(clientID)
+	req.raw(body)
+	binary.BigEndian.PutUint32(req.buf, uint32(len(req.buf)-4))
+
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+	if _, err := conn.Write(req.buf); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(conn, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < 4 || n > maxResponseSize {
+		return nil, fmt.Errorf("invalid kafka response size %d", n)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return nil, err
+	}
+	if id := int32(binary.BigEndian.Uint32(resp)); id != k.id {
+		return nil, fmt.Errorf("kafka response for request %d, expected %d", id, k.id)
+	}
+	return resp[4:], nil
+}
+
+// recordBatch - a v2 record batch holding a single record.
+func recordBatch(key, value []byte, ts time.Time) []byte {
+	var rec encoder
+	rec.int8(0)   // attributes
+	rec.varint(0) // timestamp delta
+	rec.varint(0) // offset delta
+	rec.varint(int64(len(key)))
+	rec.raw(key)
+	rec.varint(int64(len(value)))
+	rec.raw(value)
+	rec.varint(0) // headers
+
+	// Everything from the attributes on is covered by the CRC.
+	var body encoder
+	body.int16(0) // attributes, no compression
+	body.int32(0) // last offset delta
+	body.int64(ts.UnixMilli())
+	body.int64(ts.UnixMilli())
+	body.int64(-1) // producer id
+	body.int16(-1) // producer epoch
+	body.int32(-1) // base sequence
+	body.int32(1)
+	body.varint(int64(len(rec.buf)))
+	body.raw(rec.buf)
+
+	var batch encoder
+	batch.int64(0) // base offset
+	batch.int32(int32(4 + 1 + 4 + len(body.buf)))
+	batch.int32(-1) // partition leader epoch
+	batch.int8(2)   // magic
+	batch.int32(int32(crc32.Checksum(body.buf, crc32c)))
+	batch.raw(body.buf)
+	return batch.buf
+}
+
+// murmur2 - the hash the Java client partitions keys with, so messages
+// of a host land on the same partition as with other producers.
+func murmur2(data []byte) uint32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+	h := uint32(seed) ^ uint32(len(data))
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+		data = data[4:]
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// brokerError - error code returned by a broker.
+type brokerError int16
+
+// Common brokerError codes
+const (
+	errUnknownTopic       brokerError = 3
+	errLeaderNotAvailable brokerError = 5
+	errNotLeader          brokerError = 6
+	errRequestTimedOut    brokerError = 7
+	errMessageTooLarge    brokerError = 10
+	errNotEnoughReplicas  brokerError = 19
+	errTopicAuthorization brokerError = 29
+	errSaslAuthentication brokerError = 58
+)
+
+func (e brokerError) Error() string {
+	switch e {
+	case errUnknownTopic:
+		return "unknown topic or partition"
+	case errLeaderNotAvailable:
+		return "leader not available"
+	case errNotLeader:
+		return "not leader for partition"
+	case errRequestTimedOut:
+		return "request timed out"
+	case errMessageTooLarge:
+		return "message too large"
+	case errNotEnoughReplicas:
+		return "not enough replicas"
+	case errTopicAuthorization:
+		return "topic authorization failed"
+	case errSaslAuthentication:
+		return "SASL authentication failed"
+	}
+	return "kafka error code " + strconv.Itoa(int(e))
+}
+
+// encoder - big-endian encoding of the Kafka protocol primitives.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int8(v int8)   { e.buf = append(e.buf, byte(v)) }
+func (e *encoder) int16(v int16) { e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(v)) }
+func (e *encoder) int32(v int32) { e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(v)) }
+func (e *encoder) int64(v int64) { e.buf = binary.BigEndian.AppendUint64(e.buf, uint64(v)) }
+func (e *encoder) raw(b []byte)  { e.buf = append(e.buf, b...) }
+
+// varint - zigzag encoded variable length integer used within records.
+func (e *encoder) varint(v int64) { e.buf = binary.AppendVarint(e.buf, v) }
+
+func (e *encoder) string(s string) {
+	e.int16(int16(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// decoder - decodes a response, the first error sticks and turns
+// all further reads into zero values.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf) < n {
+		d.err = errors.New("truncated kafka response")
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) int8() int8 {
+	if b := d.next(1); b != nil {
+		return int8(b[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.next(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.next(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if b := d.next(8); b != nil {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return 0
+}
+
+// string - reads a string, a null string reads as empty.
+func (d *decoder) string() string {
+	n := d.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+func (d *decoder) int32Array() {
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		d.int32()
+	}
+}
@@ -0,0 +1,332 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package kafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMurmur2(t *testing.T) {
+	// Vectors of the Java client, org.apache.kafka.common.utils.UtilsTest.
+	tests := []struct {
+		key  string
+		hash int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+	for _, test := range tests {
+		if got := int32(murmur2([]byte(test.key))); got != test.hash {
+			t.Errorf("murmur2(%q) = %d, want %d", test.key, got, test.hash)
+		}
+	}
+}
+
+func TestCRC32C(t *testing.T) {
+	if got := crc32.Checksum([]byte("123456789"), crc32c); got != 0xe3069283 {
+		t.Fatalf("crc32c check value = %#x, want 0xe3069283", got)
+	}
+}
+
+func TestKafkaRecordBatch(t *testing.T) {
+	ts := time.UnixMilli(1700000000000)
+	want := "0000000000000000" + // base offset
+		"0000003a" + // batch length
+		"ffffffff" + // partition leader epoch
+		"02" + // magic
+		"e99b8dd8" + // crc32c of the rest
+		"0000" + // attributes
+		"00000000" + // last offset delta
+		"0000018bcfe56800" + // first timestamp
+		"0000018bcfe56800" + // max timestamp
+		"ffffffffffffffff" + // producer id
+		"ffff" + // producer epoch
+		"ffffffff" + // base sequence
+		"00000001" + // records
+		"10" + // record length 8
+		"00" + // record attributes
+		"00" + // timestamp delta
+		"00" + // offset delta
+		"02" + "6b" + // key "k"
+		"02" + "76" + // value "v"
+		"00" // headers
+	if got := hex.EncodeToString(recordBatch([]byte("k"), []byte("v"), ts)); got != want {
+		t.Fatalf("record batch\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestKafkaSaslPlain(t *testing.T) {
+	want := []byte("\x00\x00\x00\x0a\x00user\x00pass")
+	if got := saslPlain("user", "pass"); !bytes.Equal(got, want) {
+		t.Fatalf("SASL/PLAIN token = %q, want %q", got, want)
+	}
+}
+
+// testPartition - a partition of a Metadata response.
+type testPartition struct {
+	index, leader int32
+}
+
+// metadataResponse - a Metadata v4 response body of brokers, by
+// id, and of the partitions of topic.
+func metadataResponse(brokers map[int32]string, topic string, code int16, partitions []testPartition) []byte {
+	var e encoder
+	e.int32(0) // throttle time
+	e.int32(int32(len(brokers)))
+	for id, addr := range brokers {
+		host, port, _ := net.SplitHostPort(addr)
+		p, _ := strconv.Atoi(port)
+		e.int32(id)
+		e.string(host)
+		e.int32(int32(p))
+		e.int16(-1) // rack
+	}
+	e.int16(-1) // cluster id
+	e.int32(0)  // controller id
+	e.int32(1)
+	e.int16(code)
+	e.string(topic)
+	e.int8(0)
+	e.int32(int32(len(partitions)))
+	for _, p := range partitions {
+		e.int16(0)
+		e.int32(p.index)
+		e.int32(p.leader)
+		e.int32(1) // replicas
+		e.int32(p.leader)
+		e.int32(1) // in-sync replicas
+		e.int32(p.leader)
+	}
+	return e.buf
+}
+
+func TestKafkaLeader(t *testing.T) {
+	brokers := map[int32]string{1: "kafka1:9092", 2: "kafka2:9093"}
+	// murmur2("foobar") & 0x7fffffff = 1357151166, partition 0 of 1 and 1 of 5.
+	tests := []struct {
+		name       string
+		resp       []byte
+		key        string
+		addr       string
+		partition  int32
+		err        error
+		truncated  bool
+		partitions []testPartition
+	}{
+		{
+			name:       "single partition",
+			key:        "foobar",
+			partitions: []testPartition{{0, 2}},
+			addr:       "kafka2:9093",
+		},
+		{
+			name:       "partitions out of order",
+			key:        "foobar",
+			partitions: []testPartition{{4, 2}, {1, 1}, {0, 2}, {2, 2}, {3, 2}},
+			addr:       "kafka1:9092",
+			partition:  1,
+		},
+		{
+			name:       "unknown leader",
+			key:        "foobar",
+			partitions: []testPartition{{0, 3}},
+			err:        errLeaderNotAvailable,
+		},
+		{
+			name: "no partitions",
+			key:  "foobar",
+			err:  errLeaderNotAvailable,
+		},
+		{
+			name: "topic error",
+			key:  "foobar",
+			resp: metadataResponse(brokers, "dperf", int16(errTopicAuthorization), nil),
+			err:  errTopicAuthorization,
+		},
+		{
+			name:      "truncated",
+			key:       "foobar",
+			resp:      metadataResponse(brokers, "dperf", 0, []testPartition{{0, 1}})[:40],
+			truncated: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resp := test.resp
+			if resp == nil {
+				resp = metadataResponse(brokers, "dperf", 0, test.partitions)
+			}
+			addr, partition, err := partitionLeader(resp, "dperf", []byte(test.key))
+			switch {
+			case test.truncated:
+				if err == nil {
+					t.Fatal("expected an error for a truncated response")
+				}
+			case test.err != nil:
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error = %v, want %v", err, test.err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case addr != test.addr || partition != test.partition:
+				t.Fatalf("leader = %s partition %d, want %s partition %d", addr, partition, test.addr, test.partition)
+			}
+		})
+	}
+}
+
+// fakeBroker - serves Metadata naming itself the leader of a
+// single partition, and answers the Produce requests with the codes
+// of produce in turn, then with success.
+type fakeBroker struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	produce  []int16
+	produced [][]byte
+	conns    int
+}
+
+func (b *fakeBroker) serve(t *testing.T) {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conns++
+		b.mu.Unlock()
+		go b.handle(t, conn)
+	}
+}
+
+func (b *fakeBroker) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		d := decoder{buf: req}
+		apiKey := d.int16()
+		d.int16()
+		id := d.int32()
+		d.string()
+
+		var resp encoder
+		resp.int32(0)
+		resp.int32(id)
+		switch apiKey {
+		case apiMetadata:
+			resp.raw(metadataResponse(map[int32]string{1: b.ln.Addr().String()}, "dperf", 0, []testPartition{{0, 1}}))
+		case apiProduce:
+			var code int16
+			b.mu.Lock()
+			if len(b.produce) > 0 {
+				code, b.produce = b.produce[0], b.produce[1:]
+			}
+			if code == 0 {
+				b.produced = append(b.produced, d.buf)
+			}
+			b.mu.Unlock()
+			resp.int32(1)
+			resp.string("dperf")
+			resp.int32(1)
+			resp.int32(0)
+			resp.int16(code)
+			resp.int64(0)
+			resp.int64(-1)
+			resp.int32(0) // throttle time
+		default:
+			t.Errorf("unexpected kafka API key %d", apiKey)
+			return
+		}
+		binary.BigEndian.PutUint32(resp.buf, uint32(len(resp.buf)-4))
+		if _, err := conn.Write(resp.buf); err != nil {
+			return
+		}
+	}
+}
+
+func TestKafkaPublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		produce []int16
+		// conns - connections to the broker, one for the Metadata and one
+		// to the leader per attempt.
+		conns    int
+		produced int
+		err      error
+	}{
+		{name: "success", conns: 2, produced: 2},
+		{name: "leader change", produce: []int16{int16(errNotLeader)}, conns: 4, produced: 2},
+		{name: "leader not available", produce: []int16{int16(errLeaderNotAvailable), int16(errNotLeader)}, conns: 6, produced: 2},
+		{name: "too many leader changes", produce: []int16{int16(errNotLeader), int16(errNotLeader), int16(errNotLeader)}, conns: 6, err: errNotLeader},
+		{name: "message too large", produce: []int16{int16(errMessageTooLarge)}, conns: 2, err: errMessageTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			b := &fakeBroker{ln: ln, produce: test.produce}
+			go b.serve(t)
+
+			k := NewProducer([]string{ln.Addr().String()}, "dperf", []byte("host"))
+			defer k.Close()
+			err = k.Publish([]byte(`{"n":1}`))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The connection to the leader is reused.
+			if err = k.Publish([]byte(`{"n":2}`)); err != nil {
+				t.Fatal(err)
+			}
+			k.Close()
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.conns != test.conns || len(b.produced) != test.produced {
+				t.Fatalf("%d connections and %d messages, want %d and %d", b.conns, len(b.produced), test.conns, test.produced)
+			}
+		})
+	}
+}
@@ -34,14 +34,14 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		s := <-sigs
-		fmt.Printf("Exiting on signal %s %#v\n", s.String(), s)
+		fmt.Fprintf(os.Stderr, "Exiting on signal %s %#v\n", s.String(), s)
 		cancel()
 		<-time.After(1 * time.Second)
 		os.Exit(1)
 	}()
 
 	if err := cmd.Execute(ctx); err != nil {
-		fmt.Println("ERROR", err)
+		fmt.Fprintln(os.Stderr, "ERROR", err)
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,201 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+)
+
+// Access patterns of the block operations
+const (
+	AccessSequential = "sequential"
+	AccessRandom     = "random"
+	// AccessBoth runs the sequential phases then the random ones.
+	AccessBoth = "both"
+)
+
+// random - true if the block operations are issued at random offsets.
+func (d *DrivePerf) random() bool {
+	return d.Access == AccessRandom
+}
+
+// runAccess - tests the drive at path with the Access pattern, with
+// AccessBoth the sequential phases run then the random ones back to back,
+// the result is that of the sequential phases with those of the random
+// phases as Random.
+func (d *DrivePerf) runAccess(ctx context.Context, path string, testUUID string) *DrivePerfResult {
+	if d.Access != AccessBoth {
+		return d.runRepeated(ctx, path, testUUID)
+	}
+	seq, rnd := *d, *d
+	seq.Access, rnd.Access = AccessSequential, AccessRandom
+	dr := seq.runRepeated(ctx, path, testUUID)
+	if dr.Error != nil {
+		return dr
+	}
+	dr.Random = rnd.runRepeated(ctx, path, testUUID)
+	if dr.Random.Error != nil {
+		dr.Error = fmt.Errorf("random: %w", dr.Random.Error)
+	}
+	return dr
+}
+
+// accessCells - sequential and random throughput and IOPS of every drive
+// tested with both access patterns, the first row is the header.
+func (r *Report) accessCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"SEQ WRITE",
+		"SEQ READ",
+		"RANDOM WRITE",
+		"RANDOM READ",
+		"RANDOM WRITE IOPS",
+		"RANDOM READ IOPS",
+	}}
+	for _, result := range r.Results {
+		rnd := result.Random
+		if rnd == nil {
+			continue
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			formatRate(result.WriteThroughput),
+			formatRate(result.ReadThroughput),
+			formatRate(rnd.WriteThroughput),
+			formatRate(rnd.ReadThroughput),
+			strconv.FormatUint(rnd.WriteIOPS, 10),
+			strconv.FormatUint(rnd.ReadIOPS, 10),
+		})
+	}
+	return cellText
+}
+
+// blockOffsets - offsets of the blocks of a file of size bytes in random
+// order, every block is covered once. The order is deterministic per I/O
+// worker when a Seed is set.
+func (d *DrivePerf) blockOffsets(size uint64, idx int) []int64 {
+	seed := d.Seed + int64(idx)
+	if d.Seed == 0 {
+		seed = rand.Int63()
+	}
+	n := (size + d.BlockSize - 1) / d.BlockSize
+	offsets := make([]int64, n)
+	for i, j := range rand.New(rand.NewSource(seed)).Perm(int(n)) {
+		offsets[i] = int64(uint64(j) * d.BlockSize)
+	}
+	return offsets
+}
+
+// positioned - implemented by the files wrapped to operate at arbitrary
+// offsets, position is the offset of the next operation.
+type positioned interface {
+	position() uint64
+}
+
+// blockCursor - walks a file block by block in the order of offsets.
+type blockCursor struct {
+	offsets   []int64
+	blockSize int64
+	size      int64
+	// next is the index of the current block in offsets, pos the
+	// position within it.
+	next int
+	pos  int64
+}
+
+func (c *blockCursor) position() uint64 {
+	if c.next >= len(c.offsets) {
+		return uint64(c.size)
+	}
+	return uint64(c.offsets[c.next] + c.pos)
+}
+
+// chunk - offset and length of the rest of the current block up to n
+// bytes, io.EOF once every block was walked.
+func (c *blockCursor) chunk(n int) (int64, int, error) {
+	if c.next >= len(c.offsets) {
+		return 0, 0, io.EOF
+	}
+	off := c.offsets[c.next] + c.pos
+	end := min(c.offsets[c.next]+c.blockSize, c.size)
+	return off, int(min(int64(n), end-off)), nil
+}
+
+// advance - moves past n bytes of the current block.
+func (c *blockCursor) advance(n int) {
+	c.pos += int64(n)
+	if c.offsets[c.next]+c.pos >= min(c.offsets[c.next]+c.blockSize, c.size) {
+		c.next++
+		c.pos = 0
+	}
+}
+
+// offsetWriter - writes to a file at the offsets of its cursor with
+// pwrite, a write never spans two blocks.
+type offsetWriter struct {
+	f io.WriterAt
+	blockCursor
+}
+
+func newOffsetWriter(f io.WriterAt, offsets []int64, blockSize, size uint64) *offsetWriter {
+	return &offsetWriter{f: f, blockCursor: blockCursor{offsets: offsets, blockSize: int64(blockSize), size: int64(size)}}
+}
+
+func (w *offsetWriter) Write(b []byte) (int, error) {
+	var written int
+	for written < len(b) {
+		off, n, err := w.chunk(len(b) - written)
+		if err != nil {
+			return written, io.ErrShortWrite
+		}
+		n, err = w.f.WriteAt(b[written:written+n], off)
+		written += n
+		w.advance(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// offsetReader - reads a file at the offsets of its cursor with pread, a
+// read never spans two blocks.
+type offsetReader struct {
+	f io.ReaderAt
+	blockCursor
+}
+
+func newOffsetReader(f io.ReaderAt, offsets []int64, blockSize, size uint64) *offsetReader {
+	return &offsetReader{f: f, blockCursor: blockCursor{offsets: offsets, blockSize: int64(blockSize), size: int64(size)}}
+}
+
+func (r *offsetReader) Read(b []byte) (int, error) {
+	off, n, err := r.chunk(len(b))
+	if err != nil {
+		return 0, err
+	}
+	n, err = r.f.ReadAt(b[:n], off)
+	r.advance(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
@@ -0,0 +1,229 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Commands of an iocb, see linux/aio_abi.h.
+const (
+	iocbCmdPread  = 0
+	iocbCmdPwrite = 1
+)
+
+// iocb - struct iocb of linux/aio_abi.h, aio_key and aio_rw_flags,
+// whose order depends on the endianness, are left zero.
+type iocb struct {
+	data      uint64
+	key       uint32
+	rwFlags   int32
+	opcode    uint16
+	reqprio   int16
+	fildes    uint32
+	buf       uint64
+	nbytes    uint64
+	offset    int64
+	reserved2 uint64
+	flags     uint32
+	resfd     uint32
+}
+
+// ioEvent - struct io_event of linux/aio_abi.h.
+type ioEvent struct {
+	data uint64
+	obj  uint64
+	res  int64
+	res2 int64
+}
+
+// aio - EngineLibaio, an AIO context of the I/O worker owning it with up
+// to depth operations in flight, those of the block being transferred.
+type aio struct {
+	syncEngine
+	ctx   uintptr
+	depth int
+	cbs   []iocb
+	ptrs  []*iocb
+	evs   []ioEvent
+	res   []int64
+	// timers of the reads and writes, indexed by opcode, nil unless
+	// set by timeSplit.
+	timers [2]*aioTimer
+}
+
+// aioTimer - the time io_submit takes and the time from its return to
+// the completion of the operations submitted.
+type aioTimer struct {
+	submit, complete *histogram
+}
+
+// aioChunk - the smallest part of a block submitted as an operation of
+// its own, aligned for O_DIRECT.
+const aioChunk = 4 << 10
+
+// newAIOEngine - the engine of a region transferring its blocks with
+// Linux AIO, split in up to depth operations in flight together.
+func newAIOEngine(depth int) (*aio, error) {
+	depth = max(depth, 1)
+	a := &aio{
+		depth: depth,
+		cbs:   make([]iocb, depth),
+		ptrs:  make([]*iocb, depth),
+		evs:   make([]ioEvent, depth),
+		res:   make([]int64, depth),
+	}
+	if _, _, e := unix.Syscall(unix.SYS_IO_SETUP, uintptr(depth), uintptr(unsafe.Pointer(&a.ctx)), 0); e != 0 {
+		return nil, fmt.Errorf("io_setup: %w", e)
+	}
+	return a, nil
+}
+
+// timeSplit - records the time io_submit takes in submit and the time
+// to the completions in complete, for the writes if write is set, else
+// for the reads.
+func (a *aio) timeSplit(write bool, submit, complete *histogram) {
+	opcode := iocbCmdPread
+	if write {
+		opcode = iocbCmdPwrite
+	}
+	a.timers[opcode] = &aioTimer{submit: submit, complete: complete}
+}
+
+func (a *aio) ReadBlock(f *os.File, b []byte, off int64) (int, error) {
+	return a.full(iocbCmdPread, f, b, off)
+}
+
+func (a *aio) WriteBlock(f *os.File, b []byte, off int64) (int, error) {
+	return a.full(iocbCmdPwrite, f, b, off)
+}
+
+// full - transfers all of b at off as ReadAt and WriteAt do, short reads
+// end with io.EOF.
+func (a *aio) full(opcode uint16, f *os.File, b []byte, off int64) (int, error) {
+	var done int
+	for done < len(b) {
+		n, err := a.do(opcode, f, b[done:], off+int64(done))
+		done += n
+		if err != nil {
+			return done, err
+		}
+		if n == 0 {
+			if opcode == iocbCmdPread {
+				return done, io.EOF
+			}
+			return done, io.ErrShortWrite
+		}
+	}
+	return done, nil
+}
+
+// do - submits the operations of opcode on b at off, b split in up to
+// depth parts of whole aioChunk, and waits for all of them. It returns
+// the bytes transferred up to the first part that fell short.
+func (a *aio) do(opcode uint16, f *os.File, b []byte, off int64) (int, error) {
+	chunk := len(b)
+	if a.depth > 1 && len(b) > aioChunk {
+		chunk = (len(b)/a.depth + aioChunk - 1) / aioChunk * aioChunk
+	}
+	var n int
+	for done := 0; done < len(b); done += chunk {
+		part := b[done:min(done+chunk, len(b))]
+		a.cbs[n] = iocb{
+			data:   uint64(n),
+			opcode: opcode,
+			fildes: uint32(f.Fd()),
+			buf:    uint64(uintptr(unsafe.Pointer(&part[0]))),
+			nbytes: uint64(len(part)),
+			offset: off + int64(done),
+		}
+		a.ptrs[n] = &a.cbs[n]
+		n++
+	}
+
+	start := time.Now()
+	for sent := 0; sent < n; {
+		m, _, e := unix.Syscall(unix.SYS_IO_SUBMIT, a.ctx, uintptr(n-sent), uintptr(unsafe.Pointer(&a.ptrs[sent])))
+		if e == unix.EINTR || e == unix.EAGAIN {
+			continue
+		}
+		if e != 0 {
+			// Wait for the operations in flight before b is reused.
+			a.wait(sent)
+			return 0, &os.PathError{Op: "io_submit", Path: f.Name(), Err: e}
+		}
+		sent += int(m)
+	}
+	submitted := time.Now()
+	err := a.wait(n)
+	runtime.KeepAlive(b)
+	if t := a.timers[opcode]; t != nil {
+		t.submit.record(submitted.Sub(start))
+		t.complete.record(time.Since(submitted))
+	}
+	if err != nil {
+		return 0, &os.PathError{Op: "io_getevents", Path: f.Name(), Err: err}
+	}
+
+	var done int
+	for i := 0; i < n; i++ {
+		res := a.res[i]
+		if res < 0 {
+			return done, &os.PathError{Op: "aio", Path: f.Name(), Err: syscall.Errno(-res)}
+		}
+		done += int(res)
+		if uint64(res) < a.cbs[i].nbytes {
+			break
+		}
+	}
+	return done, nil
+}
+
+// wait - reaps the completions of the first n operations submitted into
+// res, indexed by their data.
+func (a *aio) wait(n int) error {
+	for reaped := 0; reaped < n; {
+		m, _, e := unix.Syscall6(unix.SYS_IO_GETEVENTS, a.ctx, uintptr(n-reaped), uintptr(n-reaped), uintptr(unsafe.Pointer(&a.evs[0])), 0, 0)
+		if e == unix.EINTR {
+			continue
+		}
+		if e != 0 {
+			return e
+		}
+		for _, ev := range a.evs[:m] {
+			a.res[ev.data] = ev.res
+		}
+		reaped += int(m)
+	}
+	return nil
+}
+
+// Close - destroys the AIO context.
+func (a *aio) Close() error {
+	if _, _, e := unix.Syscall(unix.SYS_IO_DESTROY, a.ctx, 0, 0); e != 0 {
+		return fmt.Errorf("io_destroy: %w", e)
+	}
+	return nil
+}
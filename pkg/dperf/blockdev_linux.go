@@ -0,0 +1,243 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"golang.org/x/sys/unix"
+)
+
+// errNoBlockDevice returned for paths not backed by a block device
+// such as tmpfs or overlay mounts.
+var errNoBlockDevice = errors.New("path is not backed by a block device")
+
+// blockDev - block device backing a path.
+type blockDev struct {
+	// name of the device, partition name if the path is on a partition.
+	name string
+	// disk is the whole-disk device name, same as name for non-partitions.
+	disk string
+	// major:minor of the whole-disk device.
+	diskMajor, diskMinor uint32
+}
+
+// pathBlockDev - resolves the block device backing path via sysfs.
+func pathBlockDev(path string) (*blockDev, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return nil, err
+	}
+	devNum := st.Dev
+	if st.Mode&unix.S_IFMT == unix.S_IFBLK {
+		// A block device passed in place of a directory.
+		devNum = st.Rdev
+	}
+	major, minor := unix.Major(devNum), unix.Minor(devNum)
+	if major == 0 {
+		return nil, errNoBlockDevice
+	}
+
+	sysPath, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errNoBlockDevice
+		}
+		return nil, err
+	}
+
+	dev := &blockDev{
+		name:      filepath.Base(sysPath),
+		disk:      filepath.Base(sysPath),
+		diskMajor: major,
+		diskMinor: minor,
+	}
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err == nil {
+		diskPath := filepath.Dir(sysPath)
+		dev.disk = filepath.Base(diskPath)
+		dev.diskMajor, dev.diskMinor, err = readDevNumber(filepath.Join(diskPath, "dev"))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dev, nil
+}
+
+// readDevNumber - parses a sysfs 'dev' file of the form "major:minor".
+func readDevNumber(path string) (uint32, uint32, error) {
+	s, err := readSysfsString(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	majorStr, minorStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid device number %q in %s", s, path)
+	}
+	major, err := strconv.ParseUint(majorStr, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	minor, err := strconv.ParseUint(minorStr, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uint32(major), uint32(minor), nil
+}
+
+// queueAttr - reads an attribute from the request queue of the whole disk.
+func (b *blockDev) queueAttr(attr string) (string, error) {
+	return readSysfsString(filepath.Join("/sys/block", b.disk, "queue", attr))
+}
+
+// queueAttrUint - reads a numeric attribute from the request queue of the whole disk.
+func (b *blockDev) queueAttrUint(attr string) (uint64, error) {
+	s, err := b.queueAttr(attr)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+// rotational - reports if the whole disk is a spinning drive.
+func (b *blockDev) rotational() bool {
+	v, err := b.queueAttrUint("rotational")
+	return err == nil && v == 1
+}
+
+// scheduler - returns the active I/O scheduler of the whole disk.
+func (b *blockDev) scheduler() (string, error) {
+	s, err := b.queueAttr("scheduler")
+	if err != nil {
+		return "", err
+	}
+	for _, f := range strings.Fields(s) {
+		if strings.HasPrefix(f, "[") && strings.HasSuffix(f, "]") {
+			return strings.Trim(f, "[]"), nil
+		}
+	}
+	return s, nil
+}
+
+// blockStat - subset of /sys/block/<dev>/stat, see Documentation/block/stat.rst
+type blockStat struct {
+	readIOs      uint64
+	readMerges   uint64
+	readSectors  uint64
+	readTicks    uint64
+	writeIOs     uint64
+	writeMerges  uint64
+	writeSectors uint64
+	writeTicks   uint64
+	inFlight     uint64
+	ioTicks      uint64
+}
+
+// stat - reads the I/O statistics of the device.
+func (b *blockDev) stat() (*blockStat, error) {
+	s, err := readSysfsString(filepath.Join("/sys/class/block", b.name, "stat"))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(s)
+	if len(fields) < 10 {
+		return nil, fmt.Errorf("unexpected stat format for %s: %q", b.name, s)
+	}
+	v := make([]uint64, 10)
+	for i := range v {
+		v[i], err = strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &blockStat{
+		readIOs:      v[0],
+		readMerges:   v[1],
+		readSectors:  v[2],
+		readTicks:    v[3],
+		writeIOs:     v[4],
+		writeMerges:  v[5],
+		writeSectors: v[6],
+		writeTicks:   v[7],
+		inFlight:     v[8],
+		ioTicks:      v[9],
+	}, nil
+}
+
+// assumedThroughput - a conservative guess of the sequential throughput
+// of the drive backing path, used for duration estimates only.
+func assumedThroughput(path string) uint64 {
+	dev, err := pathBlockDev(path)
+	switch {
+	case err != nil:
+		return 200 * humanize.MiByte
+	case strings.HasPrefix(dev.disk, "nvme"):
+		return 2 * humanize.GiByte
+	case dev.rotational():
+		return 150 * humanize.MiByte
+	}
+	return 500 * humanize.MiByte
+}
+
+// model - model of the whole disk as reported by its driver, empty if the
+// driver does not report one.
+func (b *blockDev) model() string {
+	s, _ := readSysfsString(filepath.Join("/sys/block", b.disk, "device", "model"))
+	return s
+}
+
+// pollMode - how the block device backing path polls for the
+// completions of polled I/O, one of the PollMode* constants, empty if
+// unknown.
+func pollMode(path string) string {
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return ""
+	}
+	if v, err := dev.queueAttrUint("io_poll"); err != nil {
+		return ""
+	} else if v == 0 {
+		return PollModeOff
+	}
+	delay, err := dev.queueAttr("io_poll_delay")
+	if err != nil || delay == "-1" {
+		return PollModeClassic
+	}
+	return PollModeHybrid
+}
+
+// driveModel - model of the drive backing path, empty if unknown.
+func driveModel(path string) string {
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return ""
+	}
+	return dev.model()
+}
+
+func readSysfsString(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
@@ -0,0 +1,69 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"io"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborEncMode - deterministic encoding, the schema is that of the JSON
+// report with the same field names.
+var cborEncMode = func() cbor.EncMode {
+	opts := cbor.CoreDetEncOptions()
+	opts.Time = cbor.TimeRFC3339Nano
+	em, err := opts.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return em
+}()
+
+// renderCBOR - writes the report as a single CBOR data item, reports
+// appended to the same file form a CBOR sequence.
+func (r *Report) renderCBOR(w io.Writer) error {
+	return cborEncMode.NewEncoder(w).Encode(r)
+}
+
+// MarshalCBOR encodes the result with Error as a string.
+func (r DrivePerfResult) MarshalCBOR() ([]byte, error) {
+	v := driveResultJSON{driveResult: driveResult(r)}
+	if r.Error != nil {
+		v.Error = r.Error.Error()
+	}
+	return cborEncMode.Marshal(v)
+}
+
+// UnmarshalCBOR decodes a result encoded by MarshalCBOR.
+func (r *DrivePerfResult) UnmarshalCBOR(b []byte) error {
+	var v driveResultJSON
+	if err := cbor.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = DrivePerfResult(v.driveResult)
+	if v.Error != "" {
+		r.Error = errors.New(v.Error)
+	}
+	return nil
+}
+
+// isCBOR - true if b starts with a CBOR map, which a JSON document can't.
+func isCBOR(b []byte) bool {
+	return len(b) > 0 && b[0]>>5 == 5
+}
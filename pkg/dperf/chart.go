@@ -0,0 +1,480 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// ErrUnknownChartFormat returned for chart files that are neither .svg nor .png
+var ErrUnknownChartFormat = errors.New("chart file must end in .svg or .png")
+
+// ThroughputPoint throughput of a drive over a sampling interval
+type ThroughputPoint struct {
+	// Elapsed since the start of the run at the end of the interval.
+	Elapsed    time.Duration `json:"elapsed"`
+	Phase      Phase         `json:"phase"`
+	Throughput uint64        `json:"throughput"`
+}
+
+// Timeline records the throughput of every drive over time from periodic
+// progress snapshots, it is safe for concurrent use. Set as
+// DrivePerf.Timeline it samples the bytes moved by the I/O workers every
+// second while the drives are tested.
+type Timeline struct {
+	mu     sync.Mutex
+	last   map[string]DriveProgress
+	lastAt time.Duration
+	points map[string][]ThroughputPoint
+	// start is that of the first sampling, later runs carry on from it.
+	start time.Time
+	// drives are the byte counters of the workers of every drive in its
+	// current phase.
+	drives map[string]*timelineDrive
+}
+
+// timelineDrive - the byte counters of the I/O workers of a drive.
+type timelineDrive struct {
+	phase   Phase
+	workers map[int]*workerBytes
+}
+
+// timelineInterval - sampling interval of the throughput over time.
+const timelineInterval = time.Second
+
+// Record adds the throughput of every drive since the previous snapshot.
+func (tl *Timeline) Record(elapsed time.Duration, drives []DriveProgress) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	if tl.points == nil {
+		tl.last = make(map[string]DriveProgress)
+		tl.points = make(map[string][]ThroughputPoint)
+	}
+	dt := (elapsed - tl.lastAt).Seconds()
+	if dt <= 0 {
+		return
+	}
+	for _, p := range drives {
+		bytes := p.Bytes
+		if prev, ok := tl.last[p.Path]; ok && prev.Phase == p.Phase {
+			if prev.Bytes >= prev.Total {
+				// The drive is done, waiting on the others.
+				continue
+			}
+			bytes -= prev.Bytes
+		}
+		tl.points[p.Path] = append(tl.points[p.Path], ThroughputPoint{
+			Elapsed:    elapsed,
+			Phase:      p.Phase,
+			Throughput: uint64(float64(bytes) / dt),
+		})
+		tl.last[p.Path] = p
+	}
+	tl.lastAt = elapsed
+}
+
+// worker - the byte counter of the I/O worker idx of the drive at path
+// in phase, the counters of the previous phase of the drive are dropped.
+func (tl *Timeline) worker(path string, phase Phase, idx int, total uint64, timed bool) *workerBytes {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	if tl.drives == nil {
+		tl.drives = make(map[string]*timelineDrive)
+	}
+	td, ok := tl.drives[path]
+	if !ok || td.phase != phase {
+		td = &timelineDrive{phase: phase, workers: make(map[int]*workerBytes)}
+		tl.drives[path] = td
+	}
+	w := &workerBytes{total: total, timed: timed}
+	td.workers[idx] = w
+	return w
+}
+
+// snapshot - the progress of every drive from the counters of its
+// workers, a drive is done once all of them are.
+func (tl *Timeline) snapshot() []DriveProgress {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	drives := make([]DriveProgress, 0, len(tl.drives))
+	for path, td := range tl.drives {
+		p := DriveProgress{Path: path, Phase: td.phase}
+		for _, w := range td.workers {
+			bytes, total := w.bytes.Load(), w.total
+			if w.done.Load() {
+				total = bytes
+			} else if w.timed {
+				total = bytes + 1
+			}
+			p.Bytes += bytes
+			p.Total += total
+		}
+		drives = append(drives, p)
+	}
+	return drives
+}
+
+// sample - records the throughput of every drive every timelineInterval
+// until the returned function is called.
+func (tl *Timeline) sample() func() {
+	tl.mu.Lock()
+	if tl.start.IsZero() {
+		tl.start = time.Now()
+	}
+	start := tl.start
+	tl.mu.Unlock()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(timelineInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				tl.Record(time.Since(start), tl.snapshot())
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// Points returns the throughput of every drive over time.
+func (tl *Timeline) Points() map[string][]ThroughputPoint {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	points := make(map[string][]ThroughputPoint, len(tl.points))
+	for path, p := range tl.points {
+		points[path] = append([]ThroughputPoint(nil), p...)
+	}
+	return points
+}
+
+// minTimelinePoints - runs with fewer samples are too short for a line chart.
+const minTimelinePoints = 3
+
+var (
+	chartWhite = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartBlack = color.RGBA{0x22, 0x22, 0x22, 0xff}
+	chartGrey  = color.RGBA{0xcc, 0xcc, 0xcc, 0xff}
+	chartRed   = color.RGBA{0xdc, 0x26, 0x26, 0xff}
+	chartWrite = color.RGBA{0x3b, 0x82, 0xf6, 0xff}
+	chartRead  = color.RGBA{0x22, 0xc5, 0x5e, 0xff}
+
+	// chartPalette - line colors of the drives, reused when exhausted.
+	chartPalette = []color.RGBA{
+		{0x3b, 0x82, 0xf6, 0xff},
+		{0xf5, 0x9e, 0x0b, 0xff},
+		{0x22, 0xc5, 0x5e, 0xff},
+		{0xef, 0x44, 0x44, 0xff},
+		{0x8b, 0x5c, 0xf6, 0xff},
+		{0x06, 0xb6, 0xd4, 0xff},
+		{0xec, 0x48, 0x99, 0xff},
+		{0x84, 0xcc, 0x16, 0xff},
+	}
+)
+
+const (
+	chartWidth      = 900
+	chartMargin     = 20
+	chartCharWidth  = 7
+	chartBarHeight  = 12
+	chartRowHeight  = 2*chartBarHeight + 12
+	chartLineHeight = 260
+)
+
+// textAnchor - horizontal alignment of text relative to its position.
+type textAnchor int
+
+const (
+	anchorStart textAnchor = iota
+	anchorMiddle
+	anchorEnd
+)
+
+// canvas - drawing primitives shared by the SVG and PNG charts, y of
+// text is its baseline.
+type canvas interface {
+	rect(x, y, w, h int, c color.RGBA)
+	line(x1, y1, x2, y2 int, c color.RGBA)
+	text(x, y int, s string, anchor textAnchor, c color.RGBA)
+}
+
+// WriteChart draws a bar chart of the throughput of every drive to file,
+// followed by a line chart of their throughput over time for runs long
+// enough to have a timeline. The format is picked from the extension of
+// file, .svg or .png, timeline may be nil.
+func WriteChart(file string, report *Report, timeline *Timeline) error {
+	ext := strings.ToLower(filepath.Ext(file))
+	if ext != ".svg" && ext != ".png" {
+		return ErrUnknownChartFormat
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = writeChart(f, ext, report, timeline); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeChart(w io.Writer, ext string, report *Report, timeline *Timeline) error {
+	var points map[string][]ThroughputPoint
+	if timeline != nil {
+		points = timeline.Points()
+	}
+	lines := false
+	for _, p := range points {
+		if len(p) >= minTimelinePoints {
+			lines = true
+		}
+	}
+
+	labelWidth := 0
+	for _, result := range report.Results {
+		labelWidth = max(labelWidth, len(result.Path)*chartCharWidth)
+	}
+	labelWidth = min(labelWidth+chartMargin, chartWidth/3)
+
+	barsHeight := 50 + len(report.Results)*chartRowHeight + 30
+	height := barsHeight
+	if lines {
+		height += chartLineHeight + 50 + 20*((len(points)+3)/4)
+	}
+
+	switch ext {
+	case ".svg":
+		c := &svgCanvas{}
+		drawBars(c, report, labelWidth)
+		if lines {
+			drawLines(c, points, barsHeight)
+		}
+		return c.encode(w, chartWidth, height)
+	case ".png":
+		c := &pngCanvas{img: image.NewRGBA(image.Rect(0, 0, chartWidth, height))}
+		draw.Draw(c.img, c.img.Bounds(), image.NewUniform(chartWhite), image.Point{}, draw.Src)
+		drawBars(c, report, labelWidth)
+		if lines {
+			drawLines(c, points, barsHeight)
+		}
+		return png.Encode(w, c.img)
+	}
+	return ErrUnknownChartFormat
+}
+
+// drawBars - horizontal write and read bars for every drive.
+func drawBars(c canvas, report *Report, labelWidth int) {
+	c.text(chartMargin, 24, "Throughput per drive", anchorStart, chartBlack)
+	c.rect(chartWidth-170, 14, 10, 10, chartWrite)
+	c.text(chartWidth-155, 24, "write", anchorStart, chartBlack)
+	c.rect(chartWidth-100, 14, 10, 10, chartRead)
+	c.text(chartWidth-85, 24, "read", anchorStart, chartBlack)
+
+	var maxThroughput uint64
+	for _, result := range report.Results {
+		maxThroughput = max(maxThroughput, result.WriteThroughput, result.ReadThroughput)
+	}
+	x := labelWidth + chartMargin
+	plotWidth := chartWidth - x - 100
+
+	bar := func(y int, v uint64, col color.RGBA) {
+		w := 0
+		if maxThroughput > 0 {
+			w = int(float64(v) / float64(maxThroughput) * float64(plotWidth))
+		}
+		c.rect(x, y, max(w, 1), chartBarHeight, col)
+		c.text(x+w+6, y+chartBarHeight-2, formatRate(v), anchorStart, chartBlack)
+	}
+	for i, result := range report.Results {
+		y := 50 + i*chartRowHeight
+		c.text(labelWidth, y+chartBarHeight+4, result.Path, anchorEnd, chartBlack)
+		if result.Error != nil {
+			c.text(x, y+chartBarHeight+4, "error: "+result.Error.Error(), anchorStart, chartRed)
+			continue
+		}
+		bar(y, result.WriteThroughput, chartWrite)
+		bar(y+chartBarHeight+2, result.ReadThroughput, chartRead)
+	}
+}
+
+// drawLines - throughput of every drive over time, below the bars at top.
+func drawLines(c canvas, points map[string][]ThroughputPoint, top int) {
+	c.text(chartMargin, top+24, "Throughput over time", anchorStart, chartBlack)
+
+	var maxThroughput uint64
+	var maxElapsed time.Duration
+	for _, p := range points {
+		for _, pt := range p {
+			maxThroughput = max(maxThroughput, pt.Throughput)
+			maxElapsed = max(maxElapsed, pt.Elapsed)
+		}
+	}
+	if maxThroughput == 0 || maxElapsed == 0 {
+		return
+	}
+
+	left, right := 90, chartWidth-chartMargin
+	plotTop, plotBottom := top+40, top+40+chartLineHeight-30
+	xOf := func(d time.Duration) int {
+		return left + int(float64(d)/float64(maxElapsed)*float64(right-left))
+	}
+	yOf := func(v uint64) int {
+		return plotBottom - int(float64(v)/float64(maxThroughput)*float64(plotBottom-plotTop))
+	}
+
+	const ticks = 4
+	for i := 0; i <= ticks; i++ {
+		v := maxThroughput * uint64(i) / ticks
+		y := yOf(v)
+		c.line(left, y, right, y, chartGrey)
+		c.text(left-6, y+4, formatRate(v), anchorEnd, chartBlack)
+
+		d := maxElapsed * time.Duration(i) / ticks
+		c.text(xOf(d), plotBottom+16, d.Round(time.Second).String(), anchorMiddle, chartBlack)
+	}
+	c.line(left, plotTop, left, plotBottom, chartBlack)
+	c.line(left, plotBottom, right, plotBottom, chartBlack)
+
+	legendTop := plotBottom + 40
+	for i, path := range sortedKeys(points) {
+		col := chartPalette[i%len(chartPalette)]
+		p := points[path]
+		for j := 1; j < len(p); j++ {
+			c.line(xOf(p[j-1].Elapsed), yOf(p[j-1].Throughput), xOf(p[j].Elapsed), yOf(p[j].Throughput), col)
+		}
+		lx := chartMargin + (i%4)*(chartWidth-2*chartMargin)/4
+		ly := legendTop + (i/4)*20
+		c.rect(lx, ly-9, 10, 10, col)
+		c.text(lx+15, ly, path, anchorStart, chartBlack)
+	}
+}
+
+// svgCanvas - collects the chart as SVG elements.
+type svgCanvas struct {
+	sb strings.Builder
+}
+
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func (s *svgCanvas) rect(x, y, w, h int, c color.RGBA) {
+	fmt.Fprintf(&s.sb, "<rect x=\"%d\" y=\"%d\" width=\"%d\" height=\"%d\" fill=\"%s\"/>\n", x, y, w, h, svgColor(c))
+}
+
+func (s *svgCanvas) line(x1, y1, x2, y2 int, c color.RGBA) {
+	fmt.Fprintf(&s.sb, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"%s\"/>\n", x1, y1, x2, y2, svgColor(c))
+}
+
+func (s *svgCanvas) text(x, y int, str string, anchor textAnchor, c color.RGBA) {
+	a := [...]string{"start", "middle", "end"}[anchor]
+	fmt.Fprintf(&s.sb, "<text x=\"%d\" y=\"%d\" text-anchor=\"%s\" fill=\"%s\">%s</text>\n", x, y, a, svgColor(c), html.EscapeString(str))
+}
+
+func (s *svgCanvas) encode(w io.Writer, width, height int) error {
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">
+<rect width="100%%" height="100%%" fill="white"/>
+%s</svg>
+`, width, height, s.sb.String())
+	return err
+}
+
+// pngCanvas - draws the chart on an image using a fixed 7x13 font.
+type pngCanvas struct {
+	img *image.RGBA
+}
+
+func (p *pngCanvas) rect(x, y, w, h int, c color.RGBA) {
+	draw.Draw(p.img, image.Rect(x, y, x+w, y+h), image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// line - Bresenham's line algorithm.
+func (p *pngCanvas) line(x1, y1, x2, y2 int, c color.RGBA) {
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+	e := dx + dy
+	for {
+		p.img.SetRGBA(x1, y1, c)
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+}
+
+func (p *pngCanvas) text(x, y int, s string, anchor textAnchor, c color.RGBA) {
+	d := &font.Drawer{Dst: p.img, Src: image.NewUniform(c), Face: basicfont.Face7x13}
+	w := d.MeasureString(s).Round()
+	switch anchor {
+	case anchorMiddle:
+		x -= w / 2
+	case anchorEnd:
+		x -= w
+	}
+	d.Dot = fixed.P(x, y)
+	d.DrawString(s)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,166 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DriveComparison change in throughput of a drive from a baseline run
+type DriveComparison struct {
+	Path string
+	// Baseline or Current is nil if the drive is missing from that run.
+	Baseline *DrivePerfResult
+	Current  *DrivePerfResult
+	// Regressions lists what got slower by more than the tolerance.
+	Regressions []string
+}
+
+// ReadReport reads a report written with --output json or cbor.
+func ReadReport(file string) (*Report, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	report, err := decodeReport(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return report, nil
+}
+
+// Compare matches the drives of two runs by path, a drive regresses when
+// its write or read throughput dropped by more than tolerance percent, or
+// when it failed in current only.
+func Compare(baseline, current *Report, tolerance float64) []*DriveComparison {
+	byPath := make(map[string]*DriveComparison)
+	for _, result := range baseline.Results {
+		byPath[result.Path] = &DriveComparison{Path: result.Path, Baseline: result}
+	}
+	for _, result := range current.Results {
+		c, ok := byPath[result.Path]
+		if !ok {
+			c = &DriveComparison{Path: result.Path}
+			byPath[result.Path] = c
+		}
+		c.Current = result
+	}
+
+	comparisons := make([]*DriveComparison, 0, len(byPath))
+	for _, c := range byPath {
+		if c.Baseline != nil && c.Current != nil {
+			switch {
+			case c.Current.Error != nil && c.Baseline.Error == nil:
+				c.Regressions = append(c.Regressions, "failed")
+			case c.Current.Error == nil && c.Baseline.Error == nil:
+				if change(c.Baseline.WriteThroughput, c.Current.WriteThroughput) < -tolerance {
+					c.Regressions = append(c.Regressions, "write")
+				}
+				if change(c.Baseline.ReadThroughput, c.Current.ReadThroughput) < -tolerance {
+					c.Regressions = append(c.Regressions, "read")
+				}
+			}
+		}
+		comparisons = append(comparisons, c)
+	}
+	sort.Slice(comparisons, func(i, j int) bool {
+		return comparisons[i].Path < comparisons[j].Path
+	})
+	return comparisons
+}
+
+// CompareAndRender prints the change of every drive from baseline to
+// current, it fails if any drive regressed by more than tolerance percent.
+func CompareAndRender(baseline, current *Report, tolerance float64) error {
+	comparisons := Compare(baseline, current, tolerance)
+
+	cellText := [][]string{{
+		"PATH",
+		"WRITE",
+		"CHANGE",
+		"READ",
+		"CHANGE",
+		"",
+	}}
+	var regressed int
+	for _, c := range comparisons {
+		status := "✓"
+		switch {
+		case c.Baseline == nil:
+			status = "not in baseline"
+		case c.Current == nil:
+			status = "not in current"
+		case len(c.Regressions) > 0:
+			regressed++
+			status = "regressed: " + strings.Join(c.Regressions, ", ")
+		}
+		cellText = append(cellText, []string{
+			c.Path,
+			throughputChange(c.Baseline, c.Current, func(r *DrivePerfResult) uint64 { return r.WriteThroughput }),
+			percentChange(c.Baseline, c.Current, func(r *DrivePerfResult) uint64 { return r.WriteThroughput }),
+			throughputChange(c.Baseline, c.Current, func(r *DrivePerfResult) uint64 { return r.ReadThroughput }),
+			percentChange(c.Baseline, c.Current, func(r *DrivePerfResult) uint64 { return r.ReadThroughput }),
+			status,
+		})
+	}
+	displayTable(os.Stdout, cellText)
+
+	if regressed > 0 {
+		return fmt.Errorf("%d of %d drives regressed by more than %g%%", regressed, len(comparisons), tolerance)
+	}
+	return nil
+}
+
+// RunAndCompare runs the drive performance tests and compares the
+// results to baseline.
+func (d *DrivePerf) RunAndCompare(ctx context.Context, baseline *Report, tolerance float64, paths ...string) error {
+	results, err := d.Run(ctx, paths...)
+	if err != nil {
+		return err
+	}
+	return CompareAndRender(baseline, d.newReport(results), tolerance)
+}
+
+// throughputChange - "baseline -> current" throughput of a drive.
+func throughputChange(baseline, current *DrivePerfResult, v func(*DrivePerfResult) uint64) string {
+	format := func(r *DrivePerfResult) string {
+		if r == nil || r.Error != nil {
+			return "-"
+		}
+		return formatRate(v(r))
+	}
+	return format(baseline) + " -> " + format(current)
+}
+
+func percentChange(baseline, current *DrivePerfResult, v func(*DrivePerfResult) uint64) string {
+	if baseline == nil || current == nil || baseline.Error != nil || current.Error != nil {
+		return "-"
+	}
+	return trend(v(baseline), v(current))
+}
+
+// change - change from prev to cur in percent, 0 if prev is 0.
+func change(prev, cur uint64) float64 {
+	if prev == 0 {
+		return 0
+	}
+	return (float64(cur) - float64(prev)) / float64(prev) * 100
+}
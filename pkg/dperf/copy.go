@@ -0,0 +1,157 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Methods of the copies, see CopyStats.
+const (
+	CopyMethodCopyFileRange = "copy_file_range"
+	CopyMethodSendfile      = "sendfile"
+)
+
+// CopyStats throughput of the copies of the test files of a drive made
+// by the kernel, as rebalancing and healing move data between drives
+type CopyStats struct {
+	// Destination is the directory the files were copied to.
+	Destination string `json:"destination"`
+	// Method is copy_file_range, or sendfile where the files could not be
+	// copied with it, e.g. between filesystems of older kernels.
+	Method string `json:"method"`
+	Bytes  uint64 `json:"bytes"`
+	// Throughput in bytes/sec, the fdatasync of the copies included.
+	Throughput uint64        `json:"throughput"`
+	Elapsed    time.Duration `json:"elapsed"`
+}
+
+// copyDestination - the directory the test files of the drive at path
+// are copied to.
+func (d *DrivePerf) copyDestination(path string) string {
+	if d.CopyTo != "" {
+		return d.CopyTo
+	}
+	return path
+}
+
+// runCopy - copies the files of the I/O workers of the drive at path to
+// its copy destination concurrently, in the kernel, and syncs the copies.
+func (d *DrivePerf) runCopy(ctx context.Context, path string, files []string) (*CopyStats, error) {
+	dir := d.copyDestination(path)
+	copied := make([]int64, d.IOPerDrive)
+	methods := make([]string, d.IOPerDrive)
+	errs := make([]error, d.IOPerDrive)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(d.IOPerDrive)
+	for i := 0; i < d.IOPerDrive; i++ {
+		go func(idx int) {
+			defer wg.Done()
+			for _, name := range d.workerFiles(files[idx]) {
+				if err := ctx.Err(); err != nil {
+					errs[idx] = err
+					return
+				}
+				n, method, err := copyTestFile(dir, name)
+				copied[idx] += n
+				methods[idx] = method
+				if err != nil {
+					errs[idx] = err
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("copy failed: %w", err)
+	}
+
+	st := &CopyStats{Destination: dir, Method: CopyMethodCopyFileRange, Elapsed: elapsed}
+	for idx, n := range copied {
+		st.Bytes += uint64(n)
+		if methods[idx] == CopyMethodSendfile {
+			st.Method = CopyMethodSendfile
+		}
+	}
+	st.Throughput = uint64(float64(st.Bytes) / elapsed.Seconds())
+	return st, nil
+}
+
+// copyTestFile - copies the file at name to a new file in dir, unnamed
+// where the filesystem supports it, and syncs it. The copy is removed
+// once closed.
+func copyTestFile(dir, name string) (int64, string, error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return 0, "", err
+	}
+	defer src.Close()
+
+	dst, _, err := openTmpFile(dir)
+	if err != nil {
+		dst, err = os.OpenFile(filepath.Join(dir, ".dperf-copy-"+mustGetUUID()), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return 0, "", err
+		}
+		defer os.Remove(dst.Name())
+	}
+	defer dst.Close()
+
+	n, method, err := copyFile(dst, src)
+	if err != nil {
+		return n, method, err
+	}
+	return n, method, fdatasync(int(dst.Fd()))
+}
+
+// copyCells - copy throughput of every drive next to its read throughput,
+// the first row is the header.
+func (r *Report) copyCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"COPY TO",
+		"METHOD",
+		"COPIED",
+		"READ",
+		"COPY",
+	}}
+	for _, result := range r.Results {
+		cp := result.Copy
+		if cp == nil {
+			continue
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			cp.Destination,
+			cp.Method,
+			FormatBytes(cp.Bytes),
+			formatRate(result.ReadThroughput),
+			formatRate(cp.Throughput),
+		})
+	}
+	return cellText
+}
@@ -0,0 +1,57 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyChunk - the most bytes copied by a single call.
+const copyChunk = 1 << 30
+
+// copyFile - copies src from its start to dst in the kernel with
+// copy_file_range, with sendfile if it cannot copy them, and returns
+// the bytes copied and how.
+func copyFile(dst, src *os.File) (int64, string, error) {
+	method := CopyMethodCopyFileRange
+	var copied int64
+	for {
+		var n int
+		var err error
+		if method == CopyMethodCopyFileRange {
+			n, err = unix.CopyFileRange(int(src.Fd()), nil, int(dst.Fd()), nil, copyChunk, 0)
+			if copied == 0 && (err == unix.EXDEV || err == unix.EOPNOTSUPP || err == unix.ENOSYS || err == unix.EINVAL) {
+				method = CopyMethodSendfile
+				continue
+			}
+		} else {
+			n, err = unix.Sendfile(int(dst.Fd()), int(src.Fd()), nil, copyChunk)
+		}
+		if err == unix.EINTR || err == unix.EAGAIN {
+			continue
+		}
+		if err != nil {
+			return copied, method, &os.PathError{Op: method, Path: src.Name(), Err: err}
+		}
+		if n == 0 {
+			return copied, method, nil
+		}
+		copied += int64(n)
+	}
+}
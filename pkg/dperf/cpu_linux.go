@@ -0,0 +1,80 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cpuTimes - aggregate CPU time of the host from /proc/stat, in ticks.
+type cpuTimes struct {
+	total  uint64
+	idle   uint64
+	iowait uint64
+}
+
+// cpuSnapshot - current CPU times, nil if /proc/stat is unreadable.
+func cpuSnapshot() *cpuTimes {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return nil
+	}
+	// cpu user nice system idle iowait irq softirq steal guest guest_nice
+	fields := strings.Fields(scanner.Text())
+	if len(fields) < 9 || fields[0] != "cpu" {
+		return nil
+	}
+	var t cpuTimes
+	// guest and guest_nice are already accounted in user and nice.
+	for i, field := range fields[1:9] {
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil
+		}
+		t.total += v
+		switch i {
+		case 3:
+			t.idle = v
+		case 4:
+			t.iowait = v
+		}
+	}
+	return &t
+}
+
+// cpuUsage - average CPU utilization between two snapshots.
+func cpuUsage(before, after *cpuTimes) *CPUUsage {
+	if before == nil || after == nil || after.total <= before.total {
+		return nil
+	}
+	total := float64(after.total - before.total)
+	idle := float64(after.idle - before.idle)
+	iowait := float64(after.iowait - before.iowait)
+	return &CPUUsage{
+		Busy:   (total - idle - iowait) / total * 100,
+		IOWait: iowait / total * 100,
+	}
+}
@@ -0,0 +1,224 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ErrBlockDevice returned for block devices tested without ReadOnly or
+// Destructive, the tests would overwrite whatever is on the device.
+var ErrBlockDevice = errors.New("path is a block device, its data is overwritten unless tested read-only or destructive")
+
+// isBlockDevice - reports if path is a block device, tested in place of
+// files on a filesystem.
+func isBlockDevice(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode()&os.ModeDevice != 0 && fi.Mode()&os.ModeCharDevice == 0
+}
+
+// deviceSize - the capacity of the block device at path.
+func deviceSize(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(size), nil
+}
+
+// checkDevice - returns d for the block device at path, laid out in the
+// zones of a zoned device, or an error if it cannot be tested with the
+// options of the run.
+func (d *DrivePerf) checkDevice(path string) (*DrivePerf, error) {
+	if !d.ReadOnly && !d.Destructive {
+		return nil, ErrBlockDevice
+	}
+	if d.MetadataFiles > 0 || d.Objects > 0 || d.Fill > 0 || d.Ramp > 0 || d.FilesPerIO > 1 || d.KeepFiles || d.Reuse || d.Copy {
+		return nil, errors.New("metadata, object, fill, ramp, multiple file tests, kept files and copies need a filesystem, not a block device")
+	}
+	if d.Engine == EngineNVMe && !d.ReadOnly {
+		return nil, errNVMeEngine
+	}
+	if !d.ReadOnly {
+		if err := checkDeviceUnused(path); err != nil {
+			return nil, err
+		}
+	}
+	zones, err := deviceZones(path, d.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if zones != nil {
+		dd := *d
+		dd.zones = zones
+		d = &dd
+		if err := d.checkZones(); err != nil {
+			return nil, err
+		}
+	}
+	size, err := deviceSize(path)
+	if err != nil {
+		return nil, err
+	}
+	if need := d.workerSpan() * uint64(d.IOPerDrive); d.Offset+need > size {
+		if d.Offset > 0 {
+			return nil, fmt.Errorf("the regions of the I/O workers, %s from %s, do not fit the %s device", FormatBytes(need), FormatBytes(d.Offset), FormatBytes(size))
+		}
+		return nil, fmt.Errorf("the regions of the I/O workers, %s, do not fit the %s device", FormatBytes(need), FormatBytes(size))
+	}
+	return d, nil
+}
+
+// deviceFiles - the block device at path once per I/O worker, which
+// read and write their own region of it.
+func (d *DrivePerf) deviceFiles(path string) []string {
+	files := make([]string, d.IOPerDrive)
+	for i := range files {
+		files[i] = path
+	}
+	return files
+}
+
+// region - the part of a file from base on, read and written at offsets
+// relative to base. Over several files the region is striped in blocks
+// of stripe bytes, the first to the first file, the next to the second
+// and so on.
+type region struct {
+	files  []*os.File
+	base   int64
+	stripe int64
+	engine IOEngine
+	// zones is set on a host-managed zoned device, the region then fills
+	// the capacity of its zones one after the other.
+	zones *zoneLayout
+}
+
+// workerFiles - the files an I/O worker operates on, path itself or
+// FilesPerIO test files next to it.
+func (d *DrivePerf) workerFiles(path string) []string {
+	if d.FilesPerIO <= 1 || d.ReadOnly {
+		return []string{path}
+	}
+	files := make([]string, d.FilesPerIO)
+	for i := range files {
+		files[i] = path + "." + strconv.Itoa(i)
+	}
+	return files
+}
+
+// openRegion - opens the files of the I/O worker idx at path with flag,
+// bypassing the page cache if direct, and returns the region it
+// operates on: its own FileSize bytes of a block device at path, the
+// whole files otherwise, from Offset on.
+func (d *DrivePerf) openRegion(path string, flag int, perm os.FileMode, direct bool, idx int) (region, error) {
+	rg := region{base: int64(d.Offset), stripe: int64(d.BlockSize)}
+	if isBlockDevice(path) {
+		rg.base += int64(idx) * int64(d.workerSpan())
+		if d.zones != nil && d.zones.managed() {
+			rg.zones = d.zones
+		}
+	}
+	names := d.workerFiles(path)
+	// The bytes of the region in every file, whole stripes over several.
+	extent := int64(d.FileSize)
+	if n := uint64(len(names)); n > 1 {
+		stripes := (d.FileSize + d.BlockSize - 1) / d.BlockSize
+		extent = int64((stripes + n - 1) / n * d.BlockSize)
+	}
+	engine, err := d.newEngine(rg.base + extent)
+	if err != nil {
+		return region{}, err
+	}
+	rg.engine = engine
+	for _, name := range names {
+		f, err := engine.Open(name, flag, perm, direct)
+		if err != nil {
+			rg.Close()
+			return region{}, err
+		}
+		rg.files = append(rg.files, f)
+	}
+	return rg, nil
+}
+
+func (r region) ReadAt(b []byte, off int64) (int, error) {
+	return r.at(b, off, r.engine.ReadBlock)
+}
+
+func (r region) WriteAt(b []byte, off int64) (int, error) {
+	return r.at(b, off, r.engine.WriteBlock)
+}
+
+// at - applies op to the files b spans from off.
+func (r region) at(b []byte, off int64, op func(*os.File, []byte, int64) (int, error)) (int, error) {
+	if r.zones != nil {
+		return r.zonedAt(b, off, op)
+	}
+	if len(r.files) == 1 {
+		return op(r.files[0], b, r.base+off)
+	}
+	n := int64(len(r.files))
+	var done int
+	for len(b) > 0 {
+		stripe, within := off/r.stripe, off%r.stripe
+		chunk := min(int64(len(b)), r.stripe-within)
+		m, err := op(r.files[stripe%n], b[:chunk], r.base+stripe/n*r.stripe+within)
+		done += m
+		if err != nil {
+			return done, err
+		}
+		b = b[m:]
+		off += int64(m)
+	}
+	return done, nil
+}
+
+// Fd - the descriptor of the first file of the region.
+func (r region) Fd() uintptr {
+	return r.files[0].Fd()
+}
+
+// sync - makes the blocks written to every file of the region durable.
+func (r region) sync() error {
+	for _, f := range r.files {
+		if err := r.engine.Sync(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close - closes the files of the region and its engine.
+func (r region) Close() error {
+	var errs []error
+	for _, f := range r.files {
+		errs = append(errs, f.Close())
+	}
+	if r.engine != nil {
+		errs = append(errs, closeEngine(r.engine))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,140 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DiskStats I/O the block device of a drive accounted for while it was
+// tested, the difference of its /proc/diskstats counters before and after
+type DiskStats struct {
+	// Device is the name of the block device, e.g. "nvme0n1".
+	Device       string `json:"device"`
+	BytesRead    uint64 `json:"bytesRead"`
+	BytesWritten uint64 `json:"bytesWritten"`
+	ReadIOs      uint64 `json:"readIOs"`
+	WriteIOs     uint64 `json:"writeIOs"`
+	// ReadMerges and WriteMerges are the requests merged by the block
+	// layer with adjacent ones before reaching the device.
+	ReadMerges  uint64 `json:"readMerges"`
+	WriteMerges uint64 `json:"writeMerges"`
+	// Busy is the time the device had I/O in flight, Utilization is
+	// Busy in percent of the time the drive was tested.
+	Busy        time.Duration `json:"busy"`
+	Utilization float64       `json:"utilization"`
+	// Shared is set when other paths of the run are on the same device,
+	// the counters are then those of all of them, counted once in the
+	// total.
+	Shared bool `json:"shared,omitempty"`
+}
+
+// markSharedDevices - sets Shared for the results on the same device.
+func markSharedDevices(results []*DrivePerfResult) {
+	paths := make(map[string]int)
+	for _, result := range results {
+		if result.DiskStats != nil {
+			paths[result.DiskStats.Device]++
+		}
+	}
+	for _, result := range results {
+		if ds := result.DiskStats; ds != nil && paths[ds.Device] > 1 {
+			ds.Shared = true
+		}
+	}
+}
+
+// diskStatsCells - I/O accounted for by the block device of every drive,
+// to cross-check dperf's own numbers, the first row is the header. Paths
+// sharing a device are marked, the total counts every device once.
+func (r *Report) diskStatsCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"DEVICE",
+		"DEV READ",
+		"DEV WRITTEN",
+		"READ IOs",
+		"WRITE IOs",
+		"READ MERGES",
+		"WRITE MERGES",
+		"BUSY",
+	}}
+	// The windows of paths sharing a device overlap, the largest counters
+	// of a device are the closest to its I/O over the whole run.
+	devices := make(map[string]*DiskStats)
+	for _, result := range r.Results {
+		ds := result.DiskStats
+		if ds == nil {
+			continue
+		}
+		device := ds.Device
+		if ds.Shared {
+			device += " (shared)"
+		}
+		dev, ok := devices[ds.Device]
+		if !ok {
+			dev = &DiskStats{}
+			devices[ds.Device] = dev
+		}
+		dev.BytesRead = max(dev.BytesRead, ds.BytesRead)
+		dev.BytesWritten = max(dev.BytesWritten, ds.BytesWritten)
+		dev.ReadIOs = max(dev.ReadIOs, ds.ReadIOs)
+		dev.WriteIOs = max(dev.WriteIOs, ds.WriteIOs)
+		dev.ReadMerges = max(dev.ReadMerges, ds.ReadMerges)
+		dev.WriteMerges = max(dev.WriteMerges, ds.WriteMerges)
+		cellText = append(cellText, []string{
+			result.Path,
+			device,
+			FormatBytes(ds.BytesRead),
+			FormatBytes(ds.BytesWritten),
+			strconv.FormatUint(ds.ReadIOs, 10),
+			strconv.FormatUint(ds.WriteIOs, 10),
+			strconv.FormatUint(ds.ReadMerges, 10),
+			strconv.FormatUint(ds.WriteMerges, 10),
+			fmt.Sprintf("%s (%.0f%%)", formatLatency(ds.Busy), ds.Utilization),
+		})
+	}
+	if len(cellText) > 2 {
+		var total DiskStats
+		for _, dev := range devices {
+			total.BytesRead += dev.BytesRead
+			total.BytesWritten += dev.BytesWritten
+			total.ReadIOs += dev.ReadIOs
+			total.WriteIOs += dev.WriteIOs
+			total.ReadMerges += dev.ReadMerges
+			total.WriteMerges += dev.WriteMerges
+		}
+		count := strconv.Itoa(len(devices)) + " device"
+		if len(devices) > 1 {
+			count += "s"
+		}
+		cellText = append(cellText, []string{
+			"total",
+			count,
+			FormatBytes(total.BytesRead),
+			FormatBytes(total.BytesWritten),
+			strconv.FormatUint(total.ReadIOs, 10),
+			strconv.FormatUint(total.WriteIOs, 10),
+			strconv.FormatUint(total.ReadMerges, 10),
+			strconv.FormatUint(total.WriteMerges, 10),
+			"-",
+		})
+	}
+	return cellText
+}
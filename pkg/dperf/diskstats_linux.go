@@ -0,0 +1,66 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import "time"
+
+// sectorSize - unit of the sector counters of the block layer, whatever
+// the logical block size of the device.
+const sectorSize = 512
+
+// diskStatsSnapshot - counters of a block device at a point in time.
+type diskStatsSnapshot struct {
+	dev  *blockDev
+	stat *blockStat
+	time time.Time
+}
+
+// snapshotDiskStats - captures the I/O counters of the block device
+// backing path, returns nil if there is none.
+func snapshotDiskStats(path string) *diskStatsSnapshot {
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return nil
+	}
+	stat, err := dev.stat()
+	if err != nil {
+		return nil
+	}
+	return &diskStatsSnapshot{dev: dev, stat: stat, time: time.Now()}
+}
+
+// diskStats - computes the I/O of the device between two snapshots.
+func diskStats(before, after *diskStatsSnapshot) *DiskStats {
+	if before == nil || after == nil {
+		return nil
+	}
+	b, a := before.stat, after.stat
+	ds := &DiskStats{
+		Device:       before.dev.name,
+		BytesRead:    (a.readSectors - b.readSectors) * sectorSize,
+		BytesWritten: (a.writeSectors - b.writeSectors) * sectorSize,
+		ReadIOs:      a.readIOs - b.readIOs,
+		WriteIOs:     a.writeIOs - b.writeIOs,
+		ReadMerges:   a.readMerges - b.readMerges,
+		WriteMerges:  a.writeMerges - b.writeMerges,
+		Busy:         time.Duration(a.ioTicks-b.ioTicks) * time.Millisecond,
+	}
+	if elapsed := after.time.Sub(before.time); elapsed > 0 {
+		ds.Utilization = min(float64(ds.Busy)/float64(elapsed)*100, 100)
+	}
+	return ds
+}
@@ -0,0 +1,134 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CheckStatus outcome of a single preflight check
+type CheckStatus string
+
+// Preflight check outcomes
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+	CheckSkip CheckStatus = "skip"
+)
+
+// Check result of a single preflight check
+type Check struct {
+	Name   string
+	Status CheckStatus
+	Detail string
+	// Hint describes how to remediate a warning or failure.
+	Hint string
+}
+
+// DoctorReport preflight checks run against a path
+type DoctorReport struct {
+	Path   string
+	Checks []Check
+}
+
+// Ready returns true if none of the checks failed.
+func (r *DoctorReport) Ready() bool {
+	for _, c := range r.Checks {
+		if c.Status == CheckFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Doctor checks if everything a successful run needs is in place for
+// each of the paths, the only write is a 4KiB probe file checking
+// O_DIRECT, removed right after.
+func (d *DrivePerf) Doctor(ctx context.Context, paths ...string) []*DoctorReport {
+	reports := make([]*DoctorReport, len(paths))
+
+	var wg sync.WaitGroup
+	wg.Add(len(paths))
+	for i, path := range paths {
+		go func(idx int, path string) {
+			defer wg.Done()
+			dd, err := d.forDrive(path)
+			if err != nil {
+				reports[idx] = &DoctorReport{
+					Path:   path,
+					Checks: []Check{{Name: "free space", Status: CheckFail, Detail: err.Error()}},
+				}
+				return
+			}
+			reports[idx] = &DoctorReport{
+				Path:   path,
+				Checks: dd.doctorChecks(ctx, path),
+			}
+		}(i, path)
+	}
+	wg.Wait()
+
+	return reports
+}
+
+// DoctorAndRender runs preflight checks and renders the readiness report.
+func (d *DrivePerf) DoctorAndRender(ctx context.Context, paths ...string) error {
+	reports := d.Doctor(ctx, paths...)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var notReady int
+	for _, report := range reports {
+		d.renderDoctor(report)
+		if !report.Ready() {
+			notReady++
+		}
+	}
+	if notReady > 0 {
+		return fmt.Errorf("%d of %d paths are not ready", notReady, len(reports))
+	}
+	return nil
+}
+
+func (d *DrivePerf) renderDoctor(report *DoctorReport) {
+	w := d.out()
+	getPrintColFor(w, colGreen).Fprintln(w, report.Path)
+	for _, c := range report.Checks {
+		var mark string
+		var markCol col
+		switch c.Status {
+		case CheckOK:
+			mark, markCol = "✓", colGreen
+		case CheckWarn:
+			mark, markCol = "!", colYellow
+		case CheckFail:
+			mark, markCol = "✗", colRed
+		default:
+			mark, markCol = "-", colGrey
+		}
+		fmt.Fprint(w, "  ")
+		getPrintColFor(w, markCol).Fprint(w, mark)
+		fmt.Fprintf(w, " %-14s %s\n", c.Name, c.Detail)
+		if c.Hint != "" && (c.Status == CheckWarn || c.Status == CheckFail) {
+			fmt.Fprintf(w, "    %-14s %s\n", "", "hint: "+c.Hint)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// busySampleInterval - how long the device is observed for competing I/O.
+const busySampleInterval = time.Second
+
+func (d *DrivePerf) doctorChecks(ctx context.Context, path string) []Check {
+	var checks []Check
+
+	readOnly, err := isReadOnlyFS(path)
+	switch {
+	case err != nil:
+		checks = append(checks, Check{Name: "filesystem", Status: CheckFail, Detail: err.Error()})
+	case readOnly && !d.ReadOnly:
+		checks = append(checks, Check{
+			Name:   "filesystem",
+			Status: CheckFail,
+			Detail: ErrReadOnlyFS.Error(),
+			Hint:   "remount the filesystem read-write or run with --read-only",
+		})
+	default:
+		checks = append(checks, Check{Name: "filesystem", Status: CheckOK, Detail: "writable"})
+		if readOnly {
+			checks[len(checks)-1].Detail = "read-only"
+		}
+	}
+
+	mode, modeName := uint32(unix.R_OK|unix.W_OK|unix.X_OK), "read/write"
+	if d.ReadOnly {
+		mode, modeName = unix.R_OK|unix.X_OK, "read"
+	}
+	writable := !d.ReadOnly && !readOnly
+	if err := unix.Access(path, mode); err != nil {
+		writable = false
+		checks = append(checks, Check{
+			Name:   "permissions",
+			Status: CheckFail,
+			Detail: fmt.Sprintf("no %s access: %v", modeName, err),
+			Hint:   fmt.Sprintf("run as a user with %s access to '%s'", modeName, path),
+		})
+	} else {
+		checks = append(checks, Check{Name: "permissions", Status: CheckOK, Detail: modeName + " access"})
+	}
+
+	checks = append(checks, d.checkFreeSpace(path))
+	if writable {
+		checks = append(checks, checkDirectIO(path))
+	} else {
+		checks = append(checks, Check{Name: "O_DIRECT", Status: CheckSkip, Detail: "path is not writable"})
+	}
+
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		for _, name := range []string{"alignment", "scheduler", "cgroup limits", "competing I/O"} {
+			checks = append(checks, Check{Name: name, Status: CheckSkip, Detail: err.Error()})
+		}
+		return checks
+	}
+	checks = append(checks,
+		d.checkAlignment(dev),
+		checkScheduler(dev),
+		checkCgroupLimits(dev),
+		checkCompetingIO(ctx, dev),
+	)
+	return checks
+}
+
+func (d *DrivePerf) checkFreeSpace(path string) Check {
+	if d.ReadOnly {
+		return Check{Name: "free space", Status: CheckSkip, Detail: "nothing is written in read-only mode"}
+	}
+	avail, err := freeSpace(path)
+	if err != nil {
+		return Check{Name: "free space", Status: CheckFail, Detail: err.Error()}
+	}
+	need := d.FileSize * uint64(d.IOPerDrive)
+	if avail < need {
+		return Check{
+			Name:   "free space",
+			Status: CheckFail,
+			Detail: fmt.Sprintf("need %s, only %s available", FormatBytes(need), FormatBytes(avail)),
+			Hint:   "reduce --filesize or --ioperdrive, or free up space on the drive",
+		}
+	}
+	return Check{
+		Name:   "free space",
+		Status: CheckOK,
+		Detail: fmt.Sprintf("need %s, %s available", FormatBytes(need), FormatBytes(avail)),
+	}
+}
+
+// checkDirectIO - writes a single aligned block with O_DIRECT to a
+// probe file that is removed right after.
+func checkDirectIO(path string) Check {
+	probe := filepath.Join(path, ".dperf-doctor-"+mustGetUUID())
+	defer os.Remove(probe)
+
+	f, err := os.OpenFile(probe, syscall.O_DIRECT|os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err == nil {
+		_, err = f.Write(alignedBlock(DirectioAlignSize))
+		f.Close()
+	}
+	if err != nil {
+		c := Check{Name: "O_DIRECT", Status: CheckFail, Detail: err.Error()}
+		if errors.Is(err, syscall.EINVAL) {
+			c.Detail = "filesystem does not support O_DIRECT"
+			c.Hint = "use a filesystem with O_DIRECT support such as XFS or ext4"
+		}
+		return c
+	}
+	return Check{Name: "O_DIRECT", Status: CheckOK, Detail: "supported"}
+}
+
+func (d *DrivePerf) checkAlignment(dev *blockDev) Check {
+	lbs, err := dev.queueAttrUint("logical_block_size")
+	if err != nil {
+		return Check{Name: "alignment", Status: CheckSkip, Detail: err.Error()}
+	}
+	if d.BlockSize%lbs != 0 || d.FileSize%lbs != 0 {
+		return Check{
+			Name:   "alignment",
+			Status: CheckFail,
+			Detail: fmt.Sprintf("blocksize/filesize not a multiple of %s logical block size %d", dev.disk, lbs),
+			Hint:   fmt.Sprintf("use a --blocksize and --filesize that are multiples of %d", lbs),
+		}
+	}
+	pbs, err := dev.queueAttrUint("physical_block_size")
+	if err == nil && d.BlockSize%pbs != 0 {
+		return Check{
+			Name:   "alignment",
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("blocksize not a multiple of %s physical block size %d", dev.disk, pbs),
+			Hint:   "unaligned writes cause read-modify-write cycles on the drive",
+		}
+	}
+	return Check{Name: "alignment", Status: CheckOK, Detail: fmt.Sprintf("%s logical block size %d", dev.disk, lbs)}
+}
+
+func checkScheduler(dev *blockDev) Check {
+	sched, err := dev.scheduler()
+	if err != nil {
+		return Check{Name: "scheduler", Status: CheckSkip, Detail: err.Error()}
+	}
+	detail := fmt.Sprintf("%s uses %s", dev.disk, sched)
+	hintFor := func(want string) string {
+		return fmt.Sprintf("echo %s > /sys/block/%s/queue/scheduler", want, dev.disk)
+	}
+	switch {
+	case dev.rotational() && sched == "none":
+		return Check{Name: "scheduler", Status: CheckWarn, Detail: detail + " on a rotational drive", Hint: hintFor("mq-deadline")}
+	case !dev.rotational() && (sched == "bfq" || sched == "cfq"):
+		return Check{Name: "scheduler", Status: CheckWarn, Detail: detail + " on a non-rotational drive", Hint: hintFor("none")}
+	}
+	return Check{Name: "scheduler", Status: CheckOK, Detail: detail}
+}
+
+func checkCgroupLimits(dev *blockDev) Check {
+	limits, err := cgroupIOLimits(dev.diskMajor, dev.diskMinor)
+	if err != nil {
+		return Check{Name: "cgroup limits", Status: CheckSkip, Detail: err.Error()}
+	}
+	if len(limits) > 0 {
+		return Check{
+			Name:   "cgroup limits",
+			Status: CheckWarn,
+			Detail: fmt.Sprintf("I/O to %s is throttled: %s", dev.disk, strings.Join(limits, " ")),
+			Hint:   "results will reflect the cgroup limits, not the drive",
+		}
+	}
+	return Check{Name: "cgroup limits", Status: CheckOK, Detail: "none"}
+}
+
+// cgroupIOLimits - returns the blkio/io.max limits that apply to the
+// device for the cgroup of the current process and its ancestors.
+func cgroupIOLimits(major, minor uint32) ([]string, error) {
+	f, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devNum := fmt.Sprintf("%d:%d", major, minor)
+	var limits []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			for dir := filepath.Join("/sys/fs/cgroup", parts[2]); strings.HasPrefix(dir, "/sys/fs/cgroup"); dir = filepath.Dir(dir) {
+				limits = append(limits, readCgroupLimits(filepath.Join(dir, "io.max"), devNum)...)
+			}
+		case strings.Contains(parts[1], "blkio"):
+			dir := filepath.Join("/sys/fs/cgroup/blkio", parts[2])
+			for _, name := range []string{"read_bps", "write_bps", "read_iops", "write_iops"} {
+				for _, l := range readCgroupLimits(filepath.Join(dir, "blkio.throttle."+name+"_device"), devNum) {
+					limits = append(limits, name+"="+l)
+				}
+			}
+		}
+	}
+	return limits, scanner.Err()
+}
+
+// readCgroupLimits - returns the non-default limits for devNum found in
+// an io.max or blkio.throttle.* file, missing files have no limits.
+func readCgroupLimits(file, devNum string) (limits []string) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != devNum {
+			continue
+		}
+		for _, f := range fields[1:] {
+			if !strings.HasSuffix(f, "=max") {
+				limits = append(limits, f)
+			}
+		}
+	}
+	return limits
+}
+
+func checkCompetingIO(ctx context.Context, dev *blockDev) Check {
+	before, err := dev.stat()
+	if err != nil {
+		return Check{Name: "competing I/O", Status: CheckSkip, Detail: err.Error()}
+	}
+	select {
+	case <-ctx.Done():
+		return Check{Name: "competing I/O", Status: CheckSkip, Detail: ctx.Err().Error()}
+	case <-time.After(busySampleInterval):
+	}
+	after, err := dev.stat()
+	if err != nil {
+		return Check{Name: "competing I/O", Status: CheckSkip, Detail: err.Error()}
+	}
+
+	busy := float64(after.ioTicks-before.ioTicks) / float64(busySampleInterval.Milliseconds()) * 100
+	ios := (after.readIOs - before.readIOs) + (after.writeIOs - before.writeIOs)
+	detail := fmt.Sprintf("%s %.0f%% busy, %d I/Os in %s", dev.name, busy, ios, busySampleInterval)
+	if busy > 5 {
+		return Check{
+			Name:   "competing I/O",
+			Status: CheckWarn,
+			Detail: detail,
+			Hint:   "stop other workloads on the drive, they skew the results",
+		}
+	}
+	return Check{Name: "competing I/O", Status: CheckOK, Detail: detail}
+}
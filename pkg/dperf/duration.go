@@ -0,0 +1,63 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// errDeadline - returned by the readers of a timed phase once its
+// Duration elapsed.
+var errDeadline = errors.New("phase duration elapsed")
+
+// deadline - the end of a phase started at start, zero unless Duration
+// is set.
+func (d *DrivePerf) deadline(start time.Time) time.Time {
+	if d.Duration <= 0 {
+		return time.Time{}
+	}
+	return start.Add(d.Duration)
+}
+
+// untilDeadline - wraps r to fail with errDeadline once deadline passed,
+// r is returned as is for a zero deadline.
+func untilDeadline(r io.Reader, deadline time.Time) io.Reader {
+	if deadline.IsZero() {
+		return r
+	}
+	return &deadlineReader{r: r, deadline: deadline}
+}
+
+type deadlineReader struct {
+	r        io.Reader
+	deadline time.Time
+}
+
+func (dr *deadlineReader) Read(b []byte) (int, error) {
+	if !time.Now().Before(dr.deadline) {
+		return 0, errDeadline
+	}
+	return dr.r.Read(b)
+}
+
+// another - reports if a timed phase should make another pass over its
+// files.
+func another(deadline time.Time) bool {
+	return !deadline.IsZero() && time.Now().Before(deadline)
+}
@@ -0,0 +1,125 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// I/O engines, how the blocks are transferred
+const (
+	// EngineSync reads and writes every block with pread and pwrite.
+	EngineSync = "sync"
+	// EngineLibaio submits every block with io_submit and waits for it
+	// with io_getevents, split in up to DrivePerf.AIODepth operations in
+	// flight together, Linux only, for where io_uring is disabled.
+	EngineLibaio = "libaio"
+	// EngineMmap maps the test files and copies the blocks from and to
+	// the mappings, the writes are flushed with msync, Linux only.
+	EngineMmap = "mmap"
+	// EngineNVMe reads every block with an NVMe Read command passed
+	// through the ioctl interface of the namespace, bypassing the
+	// filesystem, the page cache and the bio path of the block layer,
+	// Linux only, for read-only tests of NVMe namespaces.
+	EngineNVMe = "nvme"
+)
+
+// Flags of the blocks transferred by EngineSync, see DrivePerf.RWFlags.
+const (
+	// RWFlagDsync makes every block written durable before it completes,
+	// as O_DSYNC does, without opening the files with it (RWF_DSYNC).
+	RWFlagDsync = "dsync"
+	// RWFlagHipri polls for the completion of every block instead of
+	// waiting for its interrupt, on devices supporting it (RWF_HIPRI).
+	RWFlagHipri = "hipri"
+)
+
+// errNVMeEngine returned for runs writing with EngineNVMe, or reading
+// anything but a block device with it.
+var errNVMeEngine = errors.New("the nvme engine only reads NVMe namespaces passed as block devices")
+
+// IOEngine opens the test files of an I/O worker and transfers their
+// blocks in the read, write and mixed phases, see DrivePerf.NewEngine.
+// Every worker creates its own engine for every phase and uses it from
+// a single goroutine. An engine implementing io.Closer is closed once
+// the files it opened are.
+type IOEngine interface {
+	// Open opens the test file at path with the os.OpenFile flag and
+	// perm, bypassing the page cache if direct.
+	Open(path string, flag int, perm os.FileMode, direct bool) (*os.File, error)
+	// ReadBlock reads len(b) bytes of f at off as (*os.File).ReadAt does,
+	// reading less returns an error, io.EOF at the end of f.
+	ReadBlock(f *os.File, b []byte, off int64) (int, error)
+	// WriteBlock writes b to f at off as (*os.File).WriteAt does.
+	WriteBlock(f *os.File, b []byte, off int64) (int, error)
+	// Sync makes the blocks written to f durable.
+	Sync(f *os.File) error
+}
+
+// newEngine - the engine of a region ending at extent in its files,
+// NewEngine if set, else that of Engine.
+func (d *DrivePerf) newEngine(extent int64) (IOEngine, error) {
+	if d.NewEngine != nil {
+		return d.NewEngine(extent)
+	}
+	return d.builtinEngine(extent)
+}
+
+// SyncEngine returns the engine of EngineSync, for engines wrapping it.
+func SyncEngine() IOEngine {
+	return syncEngine{}
+}
+
+// syncEngine - EngineSync, pread and pwrite.
+type syncEngine struct{}
+
+func (syncEngine) Open(path string, flag int, perm os.FileMode, direct bool) (*os.File, error) {
+	if direct {
+		return openDirect(path, flag, perm)
+	}
+	return os.OpenFile(path, flag, perm)
+}
+
+func (syncEngine) ReadBlock(f *os.File, b []byte, off int64) (int, error) {
+	return f.ReadAt(b, off)
+}
+
+func (syncEngine) WriteBlock(f *os.File, b []byte, off int64) (int, error) {
+	return f.WriteAt(b, off)
+}
+
+func (syncEngine) Sync(f *os.File) error {
+	return fdatasync(int(f.Fd()))
+}
+
+// splitEngine - an engine timing the submission of the blocks apart
+// from their completion, EngineLibaio.
+type splitEngine interface {
+	// timeSplit records the submissions of the writes if write is set,
+	// else of the reads, in submit and their completions in complete.
+	timeSplit(write bool, submit, complete *histogram)
+}
+
+// closeEngine - closes e if it is an io.Closer.
+func closeEngine(e IOEngine) error {
+	if c, ok := e.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
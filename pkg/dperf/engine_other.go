@@ -0,0 +1,29 @@
+//go:build !linux
+// +build !linux
+
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+// builtinEngine - only the sync engine without RWFlags is available off
+// Linux.
+func (d *DrivePerf) builtinEngine(extent int64) (IOEngine, error) {
+	if d.Engine == EngineLibaio || d.Engine == EngineMmap || d.Engine == EngineNVMe || len(d.RWFlags) > 0 {
+		return nil, ErrNotImplemented
+	}
+	return syncEngine{}, nil
+}
@@ -0,0 +1,160 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Erasure coding parameters of MinIO the objects are laid out with.
+const (
+	// erasureBlockSize is the size of the blocks of an object that are
+	// erasure coded, every one adds a shard to every drive.
+	erasureBlockSize = 1 << 20
+	// bitrotHashSize is the size of the hash preceding every shard.
+	bitrotHashSize = 32
+	// inlineThreshold is the size of the shards up to which they are
+	// stored in xl.meta instead of a part file.
+	inlineThreshold = 128 << 10
+	// xlMetaSize is about the size of the xl.meta of a version, and
+	// xlMetaPartSize what every part adds to it.
+	xlMetaSize     = 512
+	xlMetaPartSize = 64
+	// erasureBufSize is the size of the aligned buffer parts are written
+	// and read through.
+	erasureBufSize = 4 << 20
+)
+
+// erasure - reports if the objects are laid out as MinIO does with
+// ErasureData data shards.
+func (d *DrivePerf) erasure() bool {
+	return d.ErasureData > 0
+}
+
+// shardFileSize - the bytes a part of size bytes takes on every drive,
+// its shards and their hashes.
+func (d *DrivePerf) shardFileSize(size uint64) uint64 {
+	shard := func(n uint64) uint64 {
+		return (n+uint64(d.ErasureData)-1)/uint64(d.ErasureData) + bitrotHashSize
+	}
+	total := size / erasureBlockSize * shard(erasureBlockSize)
+	if rem := size % erasureBlockSize; rem > 0 {
+		total += shard(rem)
+	}
+	return total
+}
+
+// objectParts - the sizes of the parts of an object of size bytes, of
+// PartSize but the last, all of it if PartSize is not set.
+func (d *DrivePerf) objectParts(size uint64) []uint64 {
+	if d.PartSize == 0 || size <= d.PartSize {
+		return []uint64{size}
+	}
+	parts := make([]uint64, 0, (size+d.PartSize-1)/d.PartSize)
+	for size > d.PartSize {
+		parts = append(parts, d.PartSize)
+		size -= d.PartSize
+	}
+	return append(parts, size)
+}
+
+// erasureFiles - the sizes of the xl.meta and of the part files of an
+// object of size bytes on a drive, without part files if its shards are
+// inlined in xl.meta.
+func (d *DrivePerf) erasureFiles(size uint64) (uint64, []uint64) {
+	parts := d.objectParts(size)
+	meta := uint64(xlMetaSize + xlMetaPartSize*len(parts))
+	files := make([]uint64, len(parts))
+	var total uint64
+	for i, part := range parts {
+		files[i] = d.shardFileSize(part)
+		total += files[i]
+	}
+	if len(parts) == 1 && total <= inlineThreshold {
+		return meta + total, nil
+	}
+	return meta, files
+}
+
+// erasureSizes - the bytes the objects of sizes take on a drive.
+func (d *DrivePerf) erasureSizes(sizes [][]uint64) [][]uint64 {
+	onDrive := make([][]uint64, len(sizes))
+	for idx := range sizes {
+		onDrive[idx] = make([]uint64, len(sizes[idx]))
+		for i, size := range sizes[idx] {
+			meta, parts := d.erasureFiles(size)
+			onDrive[idx][i] = meta
+			for _, part := range parts {
+				onDrive[idx][i] += part
+			}
+		}
+	}
+	return onDrive
+}
+
+// writeErasureObject - writes the shards of the object i of size bytes
+// of the worker dir as MinIO does: the part files in dataDir and xl.meta
+// to a temporary directory, synced, then renamed into the directory of
+// the object.
+func (d *DrivePerf) writeErasureObject(dir string, i int, dataDir string, size uint64, buf []byte, src io.Reader) error {
+	meta, parts := d.erasureFiles(size)
+	tmp := filepath.Join(dir, "tmp", strconv.Itoa(i))
+	if err := os.MkdirAll(filepath.Join(tmp, dataDir), 0o755); err != nil {
+		return err
+	}
+	for n, part := range parts {
+		if err := writePart(filepath.Join(tmp, dataDir, "part."+strconv.Itoa(n+1)), buf, src, int64(part)); err != nil {
+			return err
+		}
+	}
+	if err := writePart(filepath.Join(tmp, "xl.meta"), buf, src, int64(meta)); err != nil {
+		return err
+	}
+
+	object := objectFile(dir, i)
+	if err := os.MkdirAll(object, 0o755); err != nil {
+		return err
+	}
+	if len(parts) > 0 {
+		if err := os.Rename(filepath.Join(tmp, dataDir), filepath.Join(object, dataDir)); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(filepath.Join(tmp, "xl.meta"), filepath.Join(object, "xl.meta")); err != nil {
+		return err
+	}
+	return os.RemoveAll(tmp)
+}
+
+// readErasureObject - reads the xl.meta of the object i of size bytes
+// of the worker dir, then its part files in dataDir.
+func (d *DrivePerf) readErasureObject(dir string, i int, dataDir string, size uint64, buf []byte) error {
+	object := objectFile(dir, i)
+	if err := readPart(filepath.Join(object, "xl.meta"), buf); err != nil {
+		return err
+	}
+	_, parts := d.erasureFiles(size)
+	for n := range parts {
+		if err := readPart(filepath.Join(object, dataDir, "part."+strconv.Itoa(n+1)), buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import "fmt"
+
+// forDrive - the options of the tests of the drive at path, with
+// FileSize sized from FilePercent of its free space if set.
+func (d *DrivePerf) forDrive(path string) (*DrivePerf, error) {
+	if d.FilePercent <= 0 {
+		return d, nil
+	}
+	free, err := freeSpace(path)
+	if isBlockDevice(path) {
+		// All of a block device is free for the test.
+		free, err = deviceSize(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(float64(free)*d.FilePercent/100) / uint64(max(d.IOPerDrive, 1))
+	if d.MaxFileSize > 0 {
+		size = min(size, d.MaxFileSize)
+	}
+	size -= size % DirectioAlignSize
+	if size == 0 {
+		return nil, fmt.Errorf("%g%% of the %s free leaves no room for the test files", d.FilePercent, FormatBytes(free))
+	}
+	dd := *d
+	dd.FileSize = size
+	return &dd, nil
+}
+
+// plannedWrite - the total bytes a run against paths will write.
+func (d *DrivePerf) plannedWrite(paths []string) (uint64, error) {
+	if d.Fill > 0 {
+		var total uint64
+		for _, path := range paths {
+			target, _, _, err := d.fillBytes(path)
+			if err != nil {
+				return 0, err
+			}
+			total += target
+		}
+		return total, nil
+	}
+	if d.FilePercent <= 0 {
+		return d.PlannedWrite(len(paths)), nil
+	}
+	var total uint64
+	for _, path := range paths {
+		dd, err := d.forDrive(path)
+		if err != nil {
+			return 0, err
+		}
+		total += dd.PlannedWrite(1)
+	}
+	return total, nil
+}
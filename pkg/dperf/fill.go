@@ -0,0 +1,228 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FillPoint write throughput of a drive while it was filled up to Fill
+// percent
+type FillPoint struct {
+	// Fill is the percent of the capacity of the filesystem in use at the
+	// end of the step.
+	Fill       float64 `json:"fill"`
+	Throughput uint64  `json:"throughput"`
+}
+
+// fillTracker - counts the bytes written by the workers of a drive in
+// fill mode and samples the throughput every percent of the capacity.
+type fillTracker struct {
+	mu       sync.Mutex
+	capacity uint64
+	// used is the bytes in use at the start, target the bytes to write.
+	used, target uint64
+	written      uint64
+	stepStart    time.Time
+	stepWritten  uint64
+	points       []FillPoint
+}
+
+// add - counts n more bytes, returns false once the target is reached.
+func (t *fillTracker) add(n int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.written += uint64(n)
+	t.stepWritten += uint64(n)
+	if t.stepWritten >= t.capacity/100 {
+		t.point()
+	}
+	return t.written < t.target
+}
+
+// full - true once the target is reached.
+func (t *fillTracker) full() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.written >= t.target
+}
+
+// point - records the throughput of the current step and starts another,
+// called with mu held.
+func (t *fillTracker) point() {
+	if t.stepWritten == 0 {
+		return
+	}
+	t.points = append(t.points, FillPoint{
+		Fill:       float64(t.used+t.written) / float64(t.capacity) * 100,
+		Throughput: uint64(float64(t.stepWritten) / time.Since(t.stepStart).Seconds()),
+	})
+	t.stepStart = time.Now()
+	t.stepWritten = 0
+}
+
+// fillReader - the data written by a worker in fill mode, it ends once
+// the drive is filled as a deadline ends a timed phase.
+type fillReader struct {
+	r        io.Reader
+	t        *fillTracker
+	progress *ioProgress
+	n        uint64
+}
+
+func (f *fillReader) Read(b []byte) (int, error) {
+	if f.t.full() {
+		return 0, errDeadline
+	}
+	n, err := f.r.Read(b)
+	f.n += uint64(n)
+	f.t.add(n)
+	if f.progress != nil && n > 0 {
+		f.progress.add(n)
+	}
+	return n, err
+}
+
+// fillBytes - the bytes to write to fill the filesystem at path to Fill
+// percent, along with its capacity and the bytes in use.
+func (d *DrivePerf) fillBytes(path string) (target, capacity, used uint64, err error) {
+	capacity, free, err := diskUsage(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	used = capacity - free
+	fill := uint64(float64(capacity) * d.Fill / 100)
+	if fill <= used {
+		return 0, 0, 0, fmt.Errorf("already %.1f%% full, nothing to fill up to %g%%", float64(used)/float64(capacity)*100, d.Fill)
+	}
+	return fill - used, capacity, used, nil
+}
+
+// runFillTest - fills the filesystem at path to Fill percent of its
+// capacity, or until it is full, with test files of FileSize written by
+// every I/O worker one after the other.
+func (d *DrivePerf) runFillTest(ctx context.Context, path, testUUID string) *DrivePerfResult {
+	target, capacity, used, err := d.fillBytes(path)
+	if err != nil {
+		return &DrivePerfResult{Path: path, Error: err}
+	}
+	defer os.RemoveAll(filepath.Join(path, testUUID))
+
+	t := &fillTracker{capacity: capacity, used: used, target: target, stepStart: time.Now()}
+	results := make([]ioResult, d.IOPerDrive)
+	errs := make([]error, d.IOPerDrive)
+
+	wearBefore := wearSnapshot(path)
+	statsBefore := snapshotDiskStats(path)
+	temp := sampleTemperature(path)
+
+	var wg sync.WaitGroup
+	wg.Add(d.IOPerDrive)
+	for i := 0; i < d.IOPerDrive; i++ {
+		go func(idx int) {
+			defer wg.Done()
+			start := time.Now()
+			data := alignedBlock(int(d.BlockSize))
+			stats := d.newIOStats(path, PhaseWrite, idx, d.FileSize)
+			progress := d.newProgress(path, PhaseWrite, idx, t.target/uint64(d.IOPerDrive))
+			src := &fillReader{r: d.newDataReader(idx), t: t, progress: progress}
+			for n := 0; !t.full(); n++ {
+				iopath := testFilePath(path, testUUID, idx) + "." + strconv.Itoa(n)
+				if _, err := d.runWriteTest(ctx, iopath, data, src, stats, nil); err != nil {
+					if !errors.Is(err, syscall.ENOSPC) {
+						errs[idx] = err
+					}
+					// A full filesystem ends the test of all workers.
+					t.mu.Lock()
+					t.target = min(t.target, t.written)
+					t.mu.Unlock()
+					break
+				}
+			}
+			progress.finish()
+			results[idx] = stats.result(src.n, time.Since(start))
+		}(i)
+	}
+	wg.Wait()
+	temperature := temp.result()
+
+	for _, err := range errs {
+		if err != nil {
+			return &DrivePerfResult{Path: path, Error: err}
+		}
+	}
+	t.mu.Lock()
+	t.point()
+	t.mu.Unlock()
+
+	write := phaseResults(results)
+	return &DrivePerfResult{
+		Path:              path,
+		WriteThroughput:   write.throughput,
+		WriteIOPS:         write.iops,
+		WriteOps:          write.ops,
+		WriteElapsed:      write.elapsed,
+		WriteWorkers:      write.workers,
+		WriteLatency:      write.latencyStats(),
+		SyncLatency:       newLatencyStats(write.syncLatency),
+		Outliers:          d.latencyOutliers(write),
+		SlowestOps:        d.slowestOps(write),
+		writeHist:         write.latency,
+		TotalBytesWritten: write.bytes,
+		Fill:              t.points,
+		Wear:              driveWear(wearBefore, wearSnapshot(path), write.bytes),
+		Temperature:       temperature,
+		DiskStats:         diskStats(statsBefore, snapshotDiskStats(path)),
+	}
+}
+
+// fillCells - write throughput of every drive by fill level, with the
+// percent of the fastest step before it, the first row is the header.
+func (r *Report) fillCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"FILL",
+		"WRITE",
+		"OF PEAK",
+	}}
+	for _, result := range r.Results {
+		var peak uint64
+		for _, p := range result.Fill {
+			peak = max(peak, p.Throughput)
+			ofPeak := "-"
+			if peak > 0 {
+				ofPeak = fmt.Sprintf("%.0f%%", float64(p.Throughput)/float64(peak)*100)
+			}
+			cellText = append(cellText, []string{
+				result.Path,
+				fmt.Sprintf("%.0f%%", p.Fill),
+				formatRate(p.Throughput),
+				ofPeak,
+			})
+		}
+	}
+	return cellText
+}
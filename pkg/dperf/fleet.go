@@ -0,0 +1,309 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FleetReport aggregate of the results of many runs, usually from many hosts
+type FleetReport struct {
+	Runs   int `json:"runs"`
+	Hosts  int `json:"hosts"`
+	Drives int `json:"drives"`
+	Failed int `json:"failed"`
+	// Write and Read distribution of the per-drive throughput.
+	Write  Distribution   `json:"write"`
+	Read   Distribution   `json:"read"`
+	Models []ModelSummary `json:"models"`
+	// Slowest drives relative to the fleet median, slowest first.
+	Slowest []FleetDrive `json:"slowest"`
+}
+
+// Distribution summary of throughputs in bytes/sec
+type Distribution struct {
+	Count int    `json:"count"`
+	Min   uint64 `json:"min"`
+	P10   uint64 `json:"p10"`
+	P50   uint64 `json:"p50"`
+	P90   uint64 `json:"p90"`
+	Max   uint64 `json:"max"`
+	Mean  uint64 `json:"mean"`
+}
+
+// ModelSummary average throughput of the drives of a model
+type ModelSummary struct {
+	Model    string `json:"model"`
+	Drives   int    `json:"drives"`
+	Failed   int    `json:"failed"`
+	AvgWrite uint64 `json:"avgWrite"`
+	AvgRead  uint64 `json:"avgRead"`
+}
+
+// FleetDrive result of a drive of the fleet
+type FleetDrive struct {
+	Host  string `json:"host"`
+	Path  string `json:"path"`
+	Model string `json:"model,omitempty"`
+	// Time of the run the result is from.
+	Time            time.Time `json:"time"`
+	WriteThroughput uint64    `json:"writeThroughput"`
+	ReadThroughput  uint64    `json:"readThroughput"`
+	// OfMedian is the worse of write and read in percent of the fleet median.
+	OfMedian float64 `json:"ofMedian"`
+}
+
+// ReadReports reads the reports in the given files and directories,
+// directories are searched recursively for .json, .cbor and .jsonl
+// (history) files. Files that are not reports are skipped with a warning.
+func ReadReports(paths ...string) ([]*Report, error) {
+	var reports []*Report
+	read := func(file string) {
+		var rs []*Report
+		var err error
+		if strings.EqualFold(filepath.Ext(file), ".jsonl") {
+			rs, err = ReadHistory(file)
+		} else {
+			var r *Report
+			if r, err = ReadReport(file); err == nil {
+				rs = []*Report{r}
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[warn] skipping %s: %v\n", file, err)
+			return
+		}
+		reports = append(reports, rs...)
+	}
+	for _, path := range paths {
+		st, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			read(path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(file)) {
+			case ".json", ".cbor", ".jsonl":
+				if d.Type().IsRegular() {
+					read(file)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return reports, nil
+}
+
+// AggregateReports summarizes the drives of all reports and lists the
+// slowest n of them.
+func AggregateReports(reports []*Report, n int) *FleetReport {
+	fr := &FleetReport{Runs: len(reports)}
+	hosts := make(map[string]struct{})
+	type model struct {
+		ModelSummary
+		writes, reads []uint64
+	}
+	models := make(map[string]*model)
+	var drives []FleetDrive
+	var writes, reads []uint64
+	for _, report := range reports {
+		if report.Environment.Hostname != "" {
+			hosts[report.Environment.Hostname] = struct{}{}
+		}
+		for _, result := range report.Results {
+			fr.Drives++
+			name := result.Model
+			if name == "" {
+				name = "unknown"
+			}
+			m, ok := models[name]
+			if !ok {
+				m = &model{ModelSummary: ModelSummary{Model: name}}
+				models[name] = m
+			}
+			m.Drives++
+			if result.Error != nil {
+				fr.Failed++
+				m.Failed++
+				continue
+			}
+			if result.WriteThroughput > 0 {
+				writes = append(writes, result.WriteThroughput)
+				m.writes = append(m.writes, result.WriteThroughput)
+			}
+			if result.ReadThroughput > 0 {
+				reads = append(reads, result.ReadThroughput)
+				m.reads = append(m.reads, result.ReadThroughput)
+			}
+			drives = append(drives, FleetDrive{
+				Host:            report.Environment.Hostname,
+				Path:            result.Path,
+				Model:           result.Model,
+				Time:            report.Time,
+				WriteThroughput: result.WriteThroughput,
+				ReadThroughput:  result.ReadThroughput,
+			})
+		}
+	}
+	fr.Hosts = len(hosts)
+	fr.Write = newDistribution(writes)
+	fr.Read = newDistribution(reads)
+
+	for _, k := range sortedKeys(models) {
+		m := models[k]
+		m.AvgWrite = newDistribution(m.writes).Mean
+		m.AvgRead = newDistribution(m.reads).Mean
+		fr.Models = append(fr.Models, m.ModelSummary)
+	}
+
+	for i := range drives {
+		d := &drives[i]
+		d.OfMedian = 100
+		if d.WriteThroughput > 0 && fr.Write.P50 > 0 {
+			d.OfMedian = min(d.OfMedian, percentOf(d.WriteThroughput, fr.Write.P50))
+		}
+		if d.ReadThroughput > 0 && fr.Read.P50 > 0 {
+			d.OfMedian = min(d.OfMedian, percentOf(d.ReadThroughput, fr.Read.P50))
+		}
+	}
+	sort.SliceStable(drives, func(i, j int) bool {
+		return drives[i].OfMedian < drives[j].OfMedian
+	})
+	fr.Slowest = drives[:min(n, len(drives))]
+	return fr
+}
+
+// newDistribution - nearest-rank percentiles of the throughputs.
+func newDistribution(v []uint64) Distribution {
+	if len(v) == 0 {
+		return Distribution{}
+	}
+	sorted := append([]uint64(nil), v...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p int) uint64 {
+		i := (p*len(sorted)+99)/100 - 1
+		return sorted[max(i, 0)]
+	}
+	var sum uint64
+	for _, t := range sorted {
+		sum += t
+	}
+	return Distribution{
+		Count: len(sorted),
+		Min:   sorted[0],
+		P10:   rank(10),
+		P50:   rank(50),
+		P90:   rank(90),
+		Max:   sorted[len(sorted)-1],
+		Mean:  sum / uint64(len(sorted)),
+	}
+}
+
+// Render prints the fleet report as tables, or as JSON if output is OutputJSON.
+func (fr *FleetReport) Render(w io.Writer, output string) error {
+	if output == OutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fr)
+	}
+
+	if err := displayTable(w, [][]string{
+		{"RUNS", "HOSTS", "DRIVES", "FAILED"},
+		{strconv.Itoa(fr.Runs), strconv.Itoa(fr.Hosts), strconv.Itoa(fr.Drives), strconv.Itoa(fr.Failed)},
+	}); err != nil {
+		return err
+	}
+
+	dist := [][]string{{"", "DRIVES", "MIN", "P10", "P50", "P90", "MAX", "MEAN"}}
+	for _, d := range []struct {
+		name string
+		Distribution
+	}{{"WRITE", fr.Write}, {"READ", fr.Read}} {
+		if d.Count == 0 {
+			continue
+		}
+		row := []string{d.name, strconv.Itoa(d.Count)}
+		for _, v := range []uint64{d.Min, d.P10, d.P50, d.P90, d.Max, d.Mean} {
+			row = append(row, formatRate(v))
+		}
+		dist = append(dist, row)
+	}
+	if len(dist) > 1 {
+		if err := displayTable(w, dist); err != nil {
+			return err
+		}
+	}
+
+	if len(fr.Models) > 0 {
+		models := [][]string{{"MODEL", "DRIVES", "FAILED", "AVG WRITE", "AVG READ"}}
+		for _, m := range fr.Models {
+			models = append(models, []string{
+				m.Model,
+				strconv.Itoa(m.Drives),
+				strconv.Itoa(m.Failed),
+				formatRate(m.AvgWrite),
+				formatRate(m.AvgRead),
+			})
+		}
+		if err := displayTable(w, models); err != nil {
+			return err
+		}
+	}
+
+	if len(fr.Slowest) > 0 {
+		slowest := [][]string{{"HOST", "PATH", "MODEL", "TIME", "WRITE", "READ", "OF MEDIAN"}}
+		for _, d := range fr.Slowest {
+			// Reports predating versioning carry no host and time.
+			host, at := "-", "-"
+			if d.Host != "" {
+				host = d.Host
+			}
+			if !d.Time.IsZero() {
+				at = d.Time.Local().Format(time.DateTime)
+			}
+			slowest = append(slowest, []string{
+				host,
+				d.Path,
+				d.Model,
+				at,
+				formatRate(d.WriteThroughput),
+				formatRate(d.ReadThroughput),
+				fmt.Sprintf("%.0f%%", d.OfMedian),
+			})
+		}
+		return displayTable(w, slowest)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// heatmapBounds - upper bounds of the latency rows of the heatmap, the
+// last row holds everything slower.
+var heatmapBounds = []time.Duration{
+	100 * time.Microsecond,
+	200 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+}
+
+// heatmapShades - cells from empty to holding most operations of their column.
+var heatmapShades = []rune(" ·░▒▓█")
+
+// heatColumn - operations of a drive per latency row over one interval.
+type heatColumn []uint64
+
+// LatencyHeatmap counts the block operations of every drive per time
+// interval and latency range, it is safe for concurrent use and its
+// Record method can be used as DrivePerf.Latency.
+type LatencyHeatmap struct {
+	// Width is the number of intervals kept per drive.
+	Width int
+
+	mu     sync.Mutex
+	drives map[string]*heatDrive
+}
+
+type heatDrive struct {
+	phase   Phase
+	columns []heatColumn
+}
+
+// Record counts an operation in the current interval of its drive.
+func (hm *LatencyHeatmap) Record(path string, phase Phase, latency time.Duration) {
+	row := sort.Search(len(heatmapBounds), func(i int) bool {
+		return latency < heatmapBounds[i]
+	})
+
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	if hm.drives == nil {
+		hm.drives = make(map[string]*heatDrive)
+	}
+	hd, ok := hm.drives[path]
+	if !ok {
+		hd = &heatDrive{columns: []heatColumn{make(heatColumn, len(heatmapBounds)+1)}}
+		hm.drives[path] = hd
+	}
+	hd.phase = phase
+	hd.columns[len(hd.columns)-1][row]++
+}
+
+// Tick starts a new interval for every drive, older intervals are dropped
+// beyond Width.
+func (hm *LatencyHeatmap) Tick() {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	for _, hd := range hm.drives {
+		hd.columns = append(hd.columns, make(heatColumn, len(heatmapBounds)+1))
+		if hm.Width > 0 && len(hd.columns) > hm.Width {
+			hd.columns = hd.columns[len(hd.columns)-hm.Width:]
+		}
+	}
+}
+
+// Render draws the heatmap of every drive sorted by path, the newest
+// interval on the right and the slowest operations on top. When timeline
+// is set the current throughput of every drive is added along with a
+// sparkline of its throughput over the same intervals. It returns the
+// number of lines written.
+func (hm *LatencyHeatmap) Render(w io.Writer, timeline *Timeline) (int, error) {
+	var points map[string][]ThroughputPoint
+	if timeline != nil {
+		points = timeline.Points()
+	}
+
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+
+	var sb strings.Builder
+	lines := 0
+	for _, path := range sortedKeys(hm.drives) {
+		hd := hm.drives[path]
+		p := points[path]
+		if len(p) > 0 {
+			fmt.Fprintf(&sb, "%s (%s) %s\n", path, hd.phase, formatRate(p[len(p)-1].Throughput))
+			p = p[max(0, len(p)-len(hd.columns)):]
+			sb.WriteString("   rate │")
+			sb.WriteString(strings.Repeat(" ", len(hd.columns)-len(p)))
+			sb.WriteString(sparkline(p))
+			sb.WriteByte('\n')
+			lines += 2
+		} else {
+			fmt.Fprintf(&sb, "%s (%s)\n", path, hd.phase)
+			lines++
+		}
+		for row := len(heatmapBounds); row >= 0; row-- {
+			sb.WriteString(heatmapLabel(row))
+			sb.WriteString(" │")
+			for _, col := range hd.columns {
+				sb.WriteRune(heatmapShade(col, row))
+			}
+			sb.WriteByte('\n')
+			lines++
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return lines, err
+}
+
+// sparkBars - bars of a sparkline from lowest to highest.
+var sparkBars = []rune("▁▂▃▄▅▆▇█")
+
+// sparkline - one bar per point scaled to the highest throughput.
+func sparkline(points []ThroughputPoint) string {
+	var highest uint64
+	for _, p := range points {
+		highest = max(highest, p.Throughput)
+	}
+	bars := make([]rune, len(points))
+	for i, p := range points {
+		bars[i] = sparkBars[0]
+		if highest > 0 {
+			bars[i] = sparkBars[int(p.Throughput*uint64(len(sparkBars)-1)/highest)]
+		}
+	}
+	return string(bars)
+}
+
+// heatmapLabel - label of a latency row, right aligned.
+func heatmapLabel(row int) string {
+	label := "<" + heatmapBounds[min(row, len(heatmapBounds)-1)].String()
+	if row == len(heatmapBounds) {
+		label = "≥" + heatmapBounds[row-1].String()
+	}
+	return strings.Repeat(" ", max(0, 7-utf8.RuneCountInString(label))) + label
+}
+
+// heatmapShade - shade of the cell by the share of the operations of its
+// column, any operation at all is visible.
+func heatmapShade(col heatColumn, row int) rune {
+	var total uint64
+	for _, n := range col {
+		total += n
+	}
+	if col[row] == 0 {
+		return heatmapShades[0]
+	}
+	share := float64(col[row]) / float64(total)
+	switch {
+	case share < 0.01:
+		return heatmapShades[1]
+	case share < 0.1:
+		return heatmapShades[2]
+	case share < 0.3:
+		return heatmapShades[3]
+	case share < 0.6:
+		return heatmapShades[4]
+	}
+	return heatmapShades[5]
+}
@@ -0,0 +1,241 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Layout of the latency histograms, the same as an HdrHistogram tracking
+// 1µs to 1h with 2 significant digits: values below 256µs are exact,
+// larger ones are within 1% of the recorded value.
+const (
+	histUnit               = time.Microsecond
+	histHighest            = int64(time.Hour / histUnit)
+	histSubBucketCount     = 256
+	histSubBucketHalfCount = histSubBucketCount / 2
+	histSubBucketHalfMagn  = 7
+	histSubBucketMask      = histSubBucketCount - 1
+	// histBucketCount is the number of buckets needed to reach histHighest.
+	histBucketCount = 25
+	histCountsLen   = (histBucketCount + 1) * histSubBucketHalfCount
+)
+
+// histogram - HDR histogram of operation latencies, not safe for
+// concurrent use.
+type histogram struct {
+	counts [histCountsLen]uint64
+	total  uint64
+	min    int64
+	max    int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{min: math.MaxInt64}
+}
+
+// record - records the latency of a single operation.
+func (h *histogram) record(d time.Duration) {
+	v := int64(d / histUnit)
+	v = max(v, 0)
+	v = min(v, histHighest)
+	h.counts[histCountsIndex(v)]++
+	h.total++
+	h.min = min(h.min, v)
+	h.max = max(h.max, v)
+}
+
+// merge - adds the operations recorded by o.
+func (h *histogram) merge(o *histogram) {
+	if o == nil || o.total == 0 {
+		return
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+	h.min = min(h.min, o.min)
+	h.max = max(h.max, o.max)
+}
+
+// quantile - the latency q (0-100) percent of the operations completed
+// within, 0 if nothing was recorded.
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	target := uint64(math.Ceil(q / 100 * float64(h.total)))
+	target = max(target, 1)
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= target {
+			v := min(histHighestEquivalent(histValueFromIndex(i)), h.max)
+			return time.Duration(v) * histUnit
+		}
+	}
+	return time.Duration(h.max) * histUnit
+}
+
+// hgrmTicksPerHalfDistance - percentile steps printed per halving of the
+// distance to 100%, as in HdrHistogram's outputPercentileDistribution.
+const hgrmTicksPerHalfDistance = 5
+
+// writeHGRM - writes the percentile distribution in the HdrHistogram
+// .hgrm text format with values in milliseconds, as read by the
+// HdrHistogram plotter.
+func (h *histogram) writeHGRM(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	const scale = float64(time.Millisecond / histUnit)
+
+	fmt.Fprintf(bw, "%12s %14s %10s %14s\n\n", "Value", "Percentile", "TotalCount", "1/(1-Percentile)")
+	var seen uint64
+	next := 0.0
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		seen += c
+		value := float64(min(histHighestEquivalent(histValueFromIndex(i)), h.max)) / scale
+		for next <= 100*float64(seen)/float64(h.total) {
+			fmt.Fprintf(bw, "%12.3f %2.12f %10d %14.2f\n", value, next/100, seen, 1/(1-next/100))
+			halfDistance := math.Pow(2, math.Trunc(math.Log2(100/(100-next)))+1)
+			next += 100 / (hgrmTicksPerHalfDistance * halfDistance)
+			if seen == h.total {
+				break
+			}
+		}
+		if seen == h.total {
+			fmt.Fprintf(bw, "%12.3f %2.12f %10d\n", value, 1.0, seen)
+		}
+	}
+
+	mean, stddev := h.meanStddev()
+	fmt.Fprintf(bw, "#[Mean    = %12.3f, StdDeviation   = %12.3f]\n", mean/scale, stddev/scale)
+	fmt.Fprintf(bw, "#[Max     = %12.3f, Total count    = %12d]\n", float64(h.max)/scale, h.total)
+	fmt.Fprintf(bw, "#[Buckets = %12d, SubBuckets     = %12d]\n", histBucketCount, histSubBucketCount)
+	return bw.Flush()
+}
+
+// meanStddev - mean and standard deviation of the recorded values, every
+// value is counted as the middle of its slot.
+func (h *histogram) meanStddev() (mean, stddev float64) {
+	if h.total == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for i, c := range h.counts {
+		if c > 0 {
+			sum += float64(c) * histMedianEquivalent(i)
+		}
+	}
+	mean = sum / float64(h.total)
+	var sq float64
+	for i, c := range h.counts {
+		if c > 0 {
+			d := histMedianEquivalent(i) - mean
+			sq += float64(c) * d * d
+		}
+	}
+	return mean, math.Sqrt(sq / float64(h.total))
+}
+
+// WriteHistograms writes the latency histogram of every phase of every
+// drive to dir in the .hgrm format, as <drive>-<phase>.hgrm where drive
+// is the path with '/' replaced by '_'.
+func WriteHistograms(dir string, report *Report) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, result := range report.Results {
+		for phase, h := range map[Phase]*histogram{
+			PhaseWrite: result.writeHist,
+			PhaseRead:  result.readHist,
+		} {
+			if result.Error != nil || h == nil || h.total == 0 {
+				continue
+			}
+			if err := writeHistogram(filepath.Join(dir, histogramFile(result.Path, phase)), h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeHistogram(file string, h *histogram) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = h.writeHGRM(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// histogramFile - name of the .hgrm file of a drive and phase.
+func histogramFile(path string, phase Phase) string {
+	name := strings.ReplaceAll(strings.Trim(filepath.ToSlash(path), "/"), "/", "_")
+	if name == "" {
+		name = "root"
+	}
+	return name + "-" + string(phase) + ".hgrm"
+}
+
+func histCountsIndex(v int64) int {
+	bucket := 64 - bits.LeadingZeros64(uint64(v|histSubBucketMask)) - (histSubBucketHalfMagn + 1)
+	subBucket := int(v >> uint(bucket))
+	return (bucket+1)<<histSubBucketHalfMagn + (subBucket - histSubBucketHalfCount)
+}
+
+func histValueFromIndex(i int) int64 {
+	bucket := (i >> histSubBucketHalfMagn) - 1
+	subBucket := (i & (histSubBucketHalfCount - 1)) + histSubBucketHalfCount
+	if bucket < 0 {
+		subBucket -= histSubBucketHalfCount
+		bucket = 0
+	}
+	return int64(subBucket) << uint(bucket)
+}
+
+// histMedianEquivalent - middle of the slot at index i.
+func histMedianEquivalent(i int) float64 {
+	lowest := histValueFromIndex(i)
+	return float64(lowest+histHighestEquivalent(lowest)) / 2
+}
+
+// histHighestEquivalent - largest value counted in the same slot as v.
+func histHighestEquivalent(v int64) int64 {
+	bucket := 64 - bits.LeadingZeros64(uint64(v|histSubBucketMask)) - (histSubBucketHalfMagn + 1)
+	subBucket := int(v >> uint(bucket))
+	if subBucket >= histSubBucketCount {
+		bucket++
+	}
+	size := int64(1) << uint(bucket)
+	lowest := int64(subBucket) << uint(bucket)
+	return lowest + size - 1
+}
@@ -0,0 +1,168 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AppendHistory records a report in the history file, which holds one
+// JSON encoded Report per line.
+func AppendHistory(file string, report *Report) error {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ReadHistory returns the runs recorded in the history file, oldest first.
+func ReadHistory(file string) ([]*Report, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var reports []*Report
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 16<<20)
+	for n := 1; scanner.Scan(); n++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		report, err := decodeReport(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", file, n, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, scanner.Err()
+}
+
+// ErrNoRuns returned by RenderHistory when there is nothing to show.
+var ErrNoRuns = errors.New("no runs recorded")
+
+// RenderHistory renders the runs recorded in the history to w, when path
+// is set only the results of that drive are shown along with their trend.
+func RenderHistory(w io.Writer, reports []*Report, path string) error {
+	if path == "" {
+		return renderRuns(w, reports)
+	}
+
+	cellText := [][]string{{
+		"TIME",
+		"WRITE",
+		"READ",
+		"WRITE TREND",
+		"READ TREND",
+		"CONFIG",
+		"",
+	}}
+	var prev *DrivePerfResult
+	for _, report := range reports {
+		for _, result := range report.Results {
+			if result.Path != path {
+				continue
+			}
+			row := []string{
+				report.Time.Local().Format(time.DateTime),
+				formatRate(result.WriteThroughput),
+				formatRate(result.ReadThroughput),
+				"-",
+				"-",
+				report.Config.String(),
+				"✓",
+			}
+			if result.Error != nil {
+				row[1], row[2], row[6] = "-", "-", result.Error.Error()
+			} else {
+				if prev != nil {
+					row[3] = trend(prev.WriteThroughput, result.WriteThroughput)
+					row[4] = trend(prev.ReadThroughput, result.ReadThroughput)
+				}
+				prev = result
+			}
+			cellText = append(cellText, row)
+		}
+	}
+	if len(cellText) == 1 {
+		return fmt.Errorf("%w for %s", ErrNoRuns, path)
+	}
+	return displayTable(w, cellText)
+}
+
+// renderRuns - one row per run recorded in the history.
+func renderRuns(w io.Writer, reports []*Report) error {
+	if len(reports) == 0 {
+		return ErrNoRuns
+	}
+	cellText := [][]string{{
+		"TIME",
+		"DRIVES",
+		"TotalWRITE",
+		"TotalREAD",
+		"CONFIG",
+		"TAGS",
+	}}
+	for _, report := range reports {
+		var tags []string
+		for _, k := range sortedKeys(report.Tags) {
+			tags = append(tags, k+"="+report.Tags[k])
+		}
+		cellText = append(cellText, []string{
+			report.Time.Local().Format(time.DateTime),
+			strconv.Itoa(len(report.Results)),
+			formatRate(report.TotalWriteThroughput),
+			formatRate(report.TotalReadThroughput),
+			report.Config.String(),
+			strings.Join(tags, " "),
+		})
+	}
+	return displayTable(w, cellText)
+}
+
+// trend - change from prev to cur as a signed percentage.
+func trend(prev, cur uint64) string {
+	if prev == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", change(prev, cur))
+}
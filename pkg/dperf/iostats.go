@@ -0,0 +1,391 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"io"
+	"slices"
+	"time"
+)
+
+// ioResult - outcome of the test of a single I/O worker.
+type ioResult struct {
+	// throughput in bytes/sec.
+	throughput uint64
+	// ops is the number of completed block operations of bytes in total.
+	ops     uint64
+	bytes   uint64
+	elapsed time.Duration
+	// latency of the block operations.
+	latency *histogram
+	// outliers are the operations slower than DrivePerf.LatencyThreshold.
+	outliers outliers
+	// slowest are the DrivePerf.SlowestOps slowest operations.
+	slowest []SlowOp
+	regions fileRegions
+	zones   fileZones
+	// firstBlock is the time from the start of the worker to the
+	// completion of its first block operation.
+	firstBlock time.Duration
+	// syncLatency of the fdatasync calls issued every DrivePerf.SyncEvery
+	// blocks, nil if there were none.
+	syncLatency *histogram
+	// submitLatency and completeLatency split the latency of the block
+	// operations of a splitEngine, nil for other engines.
+	submitLatency, completeLatency *histogram
+	// verify is the check of the data read, nil unless DrivePerf.Verify
+	// is set.
+	verify *Verification
+}
+
+// iops - block operations per second.
+func (r ioResult) iops() uint64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return uint64(float64(r.ops) / r.elapsed.Seconds())
+}
+
+// ioStats - counts and times the block operations of an I/O worker
+// against its file, not those against the data source or sink.
+type ioStats struct {
+	ops         uint64
+	latency     *histogram
+	syncLatency *histogram
+	phase       Phase
+	outliers    outliers
+	slowest     slowOps
+	start       time.Time
+	firstBlock  time.Duration
+	worker      int
+	// offset is the position in the file of the next operation, out of
+	// size bytes, taken from the file if it is positioned.
+	offset  uint64
+	size    uint64
+	regions fileRegions
+	// zones if set records the operations by zone of a zoned block
+	// device, laid out as zoned from zoneBase on.
+	zones    fileZones
+	zoned    *zoneLayout
+	zoneBase uint64
+	// threshold above which operations are recorded as outliers, 0
+	// disables them.
+	threshold time.Duration
+	// observe if set is called with the latency of every operation.
+	observe func(time.Duration)
+	// verify if set stamps the data written and checks the data read.
+	verify *Verification
+	// limit if set paces the operations to DrivePerf.Rate, outside of
+	// their latency.
+	limit *rateLimiter
+	// submitLatency and completeLatency are set by engine.
+	submitLatency, completeLatency *histogram
+}
+
+// newIOStats - returns the stats of the I/O worker idx of the drive at
+// path, operating on size bytes of its file.
+func (d *DrivePerf) newIOStats(path string, phase Phase, idx int, size uint64) *ioStats {
+	s := &ioStats{
+		latency:   newHistogram(),
+		phase:     phase,
+		threshold: d.LatencyThreshold,
+		slowest:   slowOps{n: d.SlowestOps},
+		start:     time.Now(),
+		worker:    idx,
+		size:      size,
+		limit:     d.newRateLimiter(),
+	}
+	if d.zones != nil {
+		s.zones, s.zoned, s.zoneBase = fileZones{}, d.zones, d.Offset+uint64(idx)*d.workerSpan()
+	}
+	if d.Latency != nil {
+		s.observe = func(latency time.Duration) {
+			d.Latency(path, phase, latency)
+		}
+	}
+	if d.Verify {
+		s.verify = &Verification{tag: verifyTag}
+		if d.reusedTag != 0 {
+			// The files were written by an earlier run.
+			s.verify.tag = d.reusedTag
+		}
+	}
+	return s
+}
+
+// record - records a block operation of n bytes started at start.
+func (s *ioStats) record(start time.Time, latency time.Duration, n int) {
+	s.ops++
+	if s.ops == 1 {
+		s.firstBlock = start.Add(latency).Sub(s.start)
+	}
+	s.latency.record(latency)
+	if s.threshold > 0 && latency > s.threshold {
+		s.outliers.add(LatencyOutlier{Time: start, Phase: s.phase, Latency: latency})
+	}
+	s.slowest.add(SlowOp{Time: start, Phase: s.phase, Worker: s.worker, Offset: s.offset, Latency: latency})
+	s.regions.record(s.offset, s.size, n, latency)
+	if s.zones != nil {
+		s.zones.record(s.zoned.zone(s.zoneBase, s.offset), n, latency)
+	}
+	s.offset += uint64(n)
+	if s.observe != nil {
+		s.observe(latency)
+	}
+}
+
+// result - the result of a test that transferred size bytes in elapsed.
+func (s *ioStats) result(size uint64, elapsed time.Duration) ioResult {
+	s.regions.done()
+	return ioResult{
+		throughput:      uint64(float64(size) / elapsed.Seconds()),
+		ops:             s.ops,
+		bytes:           size,
+		elapsed:         elapsed,
+		latency:         s.latency,
+		outliers:        s.outliers,
+		slowest:         s.slowest.ops,
+		regions:         s.regions,
+		zones:           s.zones,
+		syncLatency:     s.syncLatency,
+		firstBlock:      s.firstBlock,
+		verify:          s.verify,
+		submitLatency:   s.submitLatency,
+		completeLatency: s.completeLatency,
+	}
+}
+
+// engine - times the submissions and completions of the blocks apart if
+// e is a splitEngine.
+func (s *ioStats) engine(e IOEngine) {
+	if se, ok := e.(splitEngine); ok {
+		s.submitLatency, s.completeLatency = newHistogram(), newHistogram()
+		se.timeSplit(s.phase == PhaseWrite, s.submitLatency, s.completeLatency)
+	}
+}
+
+// reader - wraps the file read from, positioned if the file is so that
+// the data read can be verified outside of the timed reads.
+func (s *ioStats) reader(r io.Reader) io.Reader {
+	sr := &statsReader{r: r, s: s}
+	if p, ok := r.(positioned); ok {
+		return positionedStatsReader{sr, p}
+	}
+	return sr
+}
+
+// writer - wraps the file written to, positioned if the file is so that
+// the data can be stamped outside of the timed writes.
+func (s *ioStats) writer(w io.Writer) io.Writer {
+	sw := &statsWriter{w: w, s: s}
+	if p, ok := w.(positioned); ok {
+		return positionedStatsWriter{sw, p}
+	}
+	return sw
+}
+
+type positionedStatsReader struct {
+	*statsReader
+	positioned
+}
+
+type positionedStatsWriter struct {
+	*statsWriter
+	positioned
+}
+
+type statsReader struct {
+	r io.Reader
+	s *ioStats
+}
+
+func (sr *statsReader) Read(b []byte) (int, error) {
+	if p, ok := sr.r.(positioned); ok {
+		sr.s.offset = p.position()
+	}
+	if sr.s.limit != nil {
+		sr.s.limit.wait(len(b))
+	}
+	start := time.Now()
+	n, err := sr.r.Read(b)
+	if n > 0 {
+		sr.s.record(start, time.Since(start), n)
+	}
+	return n, err
+}
+
+type statsWriter struct {
+	w io.Writer
+	s *ioStats
+}
+
+func (sw *statsWriter) Write(b []byte) (int, error) {
+	if p, ok := sw.w.(positioned); ok {
+		sw.s.offset = p.position()
+	}
+	if sw.s.limit != nil {
+		sw.s.limit.wait(len(b))
+	}
+	start := time.Now()
+	n, err := sw.w.Write(b)
+	if n > 0 {
+		sw.s.record(start, time.Since(start), n)
+	}
+	return n, err
+}
+
+// syncer - wraps the file written to so that every block written is
+// followed by sync once every blocks, the latency of sync is recorded
+// apart from that of the writes.
+func (s *ioStats) syncer(w io.Writer, every int, sync func() error) io.Writer {
+	if every <= 0 {
+		return w
+	}
+	if s.syncLatency == nil {
+		s.syncLatency = newHistogram()
+	}
+	return &syncWriter{w: w, s: s, every: every, sync: sync}
+}
+
+type syncWriter struct {
+	w       io.Writer
+	s       *ioStats
+	every   int
+	pending int
+	sync    func() error
+}
+
+func (sw *syncWriter) Write(b []byte) (int, error) {
+	n, err := sw.w.Write(b)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	if sw.pending++; sw.pending < sw.every {
+		return n, nil
+	}
+	sw.pending = 0
+	start := time.Now()
+	if err = sw.sync(); err != nil {
+		return n, err
+	}
+	sw.s.syncLatency.record(time.Since(start))
+	return n, nil
+}
+
+// phaseResult - results of the I/O workers of a drive in one phase.
+type phaseResult struct {
+	throughput uint64
+	iops       uint64
+	ops        uint64
+	bytes      uint64
+	// elapsed is the time of the slowest worker.
+	elapsed     time.Duration
+	workers     WorkerStats
+	latency     *histogram
+	syncLatency *histogram
+	outliers    outliers
+	slowest     [][]SlowOp
+	regions     fileRegions
+	zones       fileZones
+	firstBlocks []time.Duration
+	verify      *Verification
+	// submitLatency and completeLatency are nil unless the engine times
+	// them.
+	submitLatency, completeLatency *histogram
+}
+
+// phaseResults - aggregates the results of the I/O workers of a drive.
+func phaseResults(results []ioResult) phaseResult {
+	pr := phaseResult{
+		latency:     newHistogram(),
+		firstBlocks: make([]time.Duration, len(results)),
+	}
+	throughputs := make([]uint64, len(results))
+	for i, r := range results {
+		throughputs[i] = r.throughput
+		pr.firstBlocks[i] = r.firstBlock
+		pr.throughput += r.throughput
+		pr.iops += r.iops()
+		pr.ops += r.ops
+		pr.bytes += r.bytes
+		pr.elapsed = max(pr.elapsed, r.elapsed)
+		pr.latency.merge(r.latency)
+		if r.syncLatency != nil {
+			if pr.syncLatency == nil {
+				pr.syncLatency = newHistogram()
+			}
+			pr.syncLatency.merge(r.syncLatency)
+		}
+		pr.submitLatency = mergeHistogram(pr.submitLatency, r.submitLatency)
+		pr.completeLatency = mergeHistogram(pr.completeLatency, r.completeLatency)
+		pr.outliers.merge(r.outliers)
+		pr.slowest = append(pr.slowest, r.slowest)
+		pr.regions.merge(r.regions)
+		if r.zones != nil {
+			if pr.zones == nil {
+				pr.zones = fileZones{}
+			}
+			pr.zones.merge(r.zones)
+		}
+		if r.verify != nil {
+			if pr.verify == nil {
+				pr.verify = &Verification{}
+			}
+			pr.verify.merge(r.verify)
+		}
+	}
+	pr.workers = newWorkerStats(throughputs)
+	return pr
+}
+
+// mergeHistogram - merges h into into, allocated on the first h set.
+func mergeHistogram(into, h *histogram) *histogram {
+	if h == nil {
+		return into
+	}
+	if into == nil {
+		into = newHistogram()
+	}
+	into.merge(h)
+	return into
+}
+
+// latencyStats - latency percentiles of the phase, nil if no operation
+// was recorded.
+func (pr phaseResult) latencyStats() *LatencyStats {
+	l := newLatencyStats(pr.latency)
+	if l != nil {
+		l.FirstBlocks = pr.firstBlocks
+		l.FirstBlock = slices.Max(pr.firstBlocks)
+	}
+	return l
+}
+
+// newLatencyStats - percentiles of the latencies recorded in h, nil if
+// there are none.
+func newLatencyStats(h *histogram) *LatencyStats {
+	if h == nil || h.total == 0 {
+		return nil
+	}
+	return &LatencyStats{
+		P50:  h.quantile(50),
+		P90:  h.quantile(90),
+		P99:  h.quantile(99),
+		P999: h.quantile(99.9),
+		Max:  time.Duration(h.max) * histUnit,
+	}
+}
@@ -0,0 +1,88 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// keepDir - directory the test files are kept in on every drive.
+const keepDir = ".dperf"
+
+// keptFilesName - name of the description of the kept files, next to
+// them.
+const keptFilesName = "files.json"
+
+// keptFiles - describes the test files kept on a drive, as much as a
+// later run needs to read them back.
+type keptFiles struct {
+	Time       time.Time `json:"time"`
+	FileSize   uint64    `json:"fileSize"`
+	IOPerDrive int       `json:"ioPerDrive"`
+	FilesPerIO int       `json:"filesPerIO,omitempty"`
+	// Tag is the verify tag of the data, 0 unless written with Verify.
+	Tag uint32 `json:"tag,omitempty"`
+}
+
+// keepFiles - describes the test files just written to the drive at path.
+func (d *DrivePerf) keepFiles(path string) error {
+	kept := keptFiles{
+		Time:       time.Now().UTC(),
+		FileSize:   d.FileSize,
+		IOPerDrive: d.IOPerDrive,
+		FilesPerIO: d.FilesPerIO,
+	}
+	if d.Verify {
+		kept.Tag = verifyTag
+	}
+	b, err := json.Marshal(kept)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(path, keepDir, keptFilesName), b, 0o600)
+}
+
+// reuseFiles - a copy of d reading the test files kept on the drive at
+// path as they were written.
+func (d *DrivePerf) reuseFiles(path string) (*DrivePerf, error) {
+	b, err := os.ReadFile(filepath.Join(path, keepDir, keptFilesName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no test files kept under '%s'", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var kept keptFiles
+	if err = json.Unmarshal(b, &kept); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", keptFilesName, err)
+	}
+	if d.Verify && kept.Tag == 0 {
+		return nil, fmt.Errorf("test files kept under '%s' on %s were written without verify", path, kept.Time.Format(time.RFC3339))
+	}
+	dd := *d
+	dd.FileSize = kept.FileSize
+	dd.IOPerDrive = kept.IOPerDrive
+	dd.FilesPerIO = kept.FilesPerIO
+	dd.reusedTag = kept.Tag
+	return &dd, nil
+}
@@ -0,0 +1,197 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metadata operations, in the order they are benchmarked
+const (
+	MetadataCreate = "create"
+	MetadataStat   = "stat"
+	MetadataRename = "rename"
+	MetadataUnlink = "unlink"
+)
+
+// MetadataStats rate and latency of a metadata operation on a drive
+type MetadataStats struct {
+	Op        string        `json:"op"`
+	OpsPerSec uint64        `json:"opsPerSec"`
+	Latency   *LatencyStats `json:"latency,omitempty"`
+}
+
+// metadataOp - runs a metadata operation against the file i of a worker.
+type metadataOp struct {
+	name string
+	run  func(dir string, i int) error
+}
+
+var metadataOps = []metadataOp{
+	{MetadataCreate, func(dir string, i int) error {
+		f, err := os.OpenFile(metadataFile(dir, i), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}},
+	{MetadataStat, func(dir string, i int) error {
+		_, err := os.Stat(metadataFile(dir, i))
+		return err
+	}},
+	{MetadataRename, func(dir string, i int) error {
+		return os.Rename(metadataFile(dir, i), metadataFile(dir, i)+".renamed")
+	}},
+	{MetadataUnlink, func(dir string, i int) error {
+		return os.Remove(metadataFile(dir, i) + ".renamed")
+	}},
+}
+
+// metadataFile - path of the file i of a worker.
+func metadataFile(dir string, i int) string {
+	return filepath.Join(dir, "file-"+strconv.Itoa(i))
+}
+
+// runMetadataTests - creates, stats, renames and unlinks MetadataFiles
+// empty files per I/O worker, one operation at a time across all workers
+// so that each is measured on its own.
+func (d *DrivePerf) runMetadataTests(ctx context.Context, path, testUUID string) *DrivePerfResult {
+	defer os.RemoveAll(filepath.Join(path, testUUID))
+
+	dirs := make([]string, d.IOPerDrive)
+	for i := range dirs {
+		dirs[i] = filepath.Join(path, testUUID, "metadata-"+strconv.Itoa(i))
+		if err := os.MkdirAll(dirs[i], 0o755); err != nil {
+			return &DrivePerfResult{Path: path, Error: err}
+		}
+	}
+
+	stats := make([]MetadataStats, 0, len(metadataOps))
+	for _, op := range metadataOps {
+		s, err := d.runMetadataOp(ctx, op, dirs)
+		if err != nil {
+			return &DrivePerfResult{Path: path, Error: fmt.Errorf("%s: %w", op.name, err)}
+		}
+		stats = append(stats, s)
+	}
+	return &DrivePerfResult{
+		Path:     path,
+		Metadata: stats,
+	}
+}
+
+// runMetadataOp - runs op against the files of every worker concurrently.
+func (d *DrivePerf) runMetadataOp(ctx context.Context, op metadataOp, dirs []string) (MetadataStats, error) {
+	hists := make([]*histogram, len(dirs))
+	errs := make([]error, len(dirs))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(len(dirs))
+	for idx, dir := range dirs {
+		go func(idx int, dir string) {
+			defer wg.Done()
+			h := newHistogram()
+			hists[idx] = h
+			for i := 0; i < d.MetadataFiles; i++ {
+				if err := ctx.Err(); err != nil {
+					errs[idx] = err
+					return
+				}
+				opStart := time.Now()
+				if err := op.run(dir, i); err != nil {
+					errs[idx] = err
+					return
+				}
+				h.record(time.Since(opStart))
+			}
+		}(idx, dir)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	for _, err := range errs {
+		if err != nil {
+			return MetadataStats{}, err
+		}
+	}
+	latency := newHistogram()
+	for _, h := range hists {
+		latency.merge(h)
+	}
+	return MetadataStats{
+		Op:        op.name,
+		OpsPerSec: uint64(float64(latency.total) / elapsed.Seconds()),
+		Latency:   newLatencyStats(latency),
+	}, nil
+}
+
+// metadataCells - rate and latency of the metadata operations of every
+// drive, the first row is the header.
+func (r *Report) metadataCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"OP",
+		"OPS/S",
+		"P50",
+		"P99",
+		"MAX",
+	}}
+	for _, result := range r.Results {
+		for _, m := range result.Metadata {
+			row := []string{result.Path, m.Op, strconv.FormatUint(m.OpsPerSec, 10), "-", "-", "-"}
+			if l := m.Latency; l != nil {
+				row[3], row[4], row[5] = formatLatency(l.P50), formatLatency(l.P99), formatLatency(l.Max)
+			}
+			cellText = append(cellText, row)
+		}
+	}
+	return cellText
+}
+
+// metadataTotalCells - the rate of every metadata operation over all the
+// drives, nil if the report is not of a metadata test.
+func (r *Report) metadataTotalCells() [][]string {
+	var ops []string
+	rates := make(map[string]uint64)
+	for _, result := range r.Results {
+		for _, m := range result.Metadata {
+			if _, ok := rates[m.Op]; !ok {
+				ops = append(ops, m.Op)
+			}
+			rates[m.Op] += m.OpsPerSec
+		}
+	}
+	if len(ops) == 0 {
+		return nil
+	}
+	cellText := [][]string{{}, {}}
+	for _, op := range ops {
+		cellText[0] = append(cellText[0], "Total"+strings.ToUpper(op)+"/S")
+		cellText[1] = append(cellText[1], strconv.FormatUint(rates[op], 10))
+	}
+	cellText[0] = append(cellText[0], "ELAPSED")
+	cellText[1] = append(cellText[1], formatLatency(r.Elapsed))
+	return cellText
+}
@@ -0,0 +1,73 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import "math/rand"
+
+// mixedOp - a block operation of the mixed phase.
+type mixedOp struct {
+	offset int64
+	read   bool
+}
+
+// mixedOps - the block operations of an I/O worker in the mixed phase
+// over a file of size bytes, ReadMix percent of them reads. Every block
+// is visited once, in the order of Access. The operations are
+// deterministic per I/O worker when a Seed is set.
+func (d *DrivePerf) mixedOps(size uint64, idx int) []mixedOp {
+	seed := d.Seed + int64(idx)
+	if d.Seed == 0 {
+		seed = rand.Int63()
+	}
+	var offsets []int64
+	if d.random() {
+		offsets = d.blockOffsets(size, idx)
+	} else {
+		for off := uint64(0); off < size; off += d.BlockSize {
+			offsets = append(offsets, int64(off))
+		}
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	ops := make([]mixedOp, len(offsets))
+	for i, off := range offsets {
+		ops[i] = mixedOp{offset: off, read: rnd.Intn(100) < d.ReadMix}
+	}
+	return ops
+}
+
+// fileAt - reads and writes a file, or a region of it, at off with pread
+// and pwrite, advancing off.
+type fileAt struct {
+	f   region
+	off int64
+}
+
+func (f *fileAt) position() uint64 {
+	return uint64(f.off)
+}
+
+func (f *fileAt) Read(b []byte) (int, error) {
+	n, err := f.f.ReadAt(b, f.off)
+	f.off += int64(n)
+	return n, err
+}
+
+func (f *fileAt) Write(b []byte) (int, error) {
+	n, err := f.f.WriteAt(b, f.off)
+	f.off += int64(n)
+	return n, err
+}
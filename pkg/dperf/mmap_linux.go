@@ -0,0 +1,144 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mmapEngine - EngineMmap, maps the files of a region up to extent bytes
+// and copies the blocks from and to the mappings. Regular files written
+// are extended to extent first.
+type mmapEngine struct {
+	syncEngine
+	extent int64
+	maps   map[*os.File]mapping
+}
+
+type mapping struct {
+	b        []byte
+	writable bool
+}
+
+// newMmapEngine - the engine of a region ending at extent in its files,
+// transferring its blocks through mappings of them.
+func newMmapEngine(extent int64) *mmapEngine {
+	return &mmapEngine{extent: extent, maps: make(map[*os.File]mapping)}
+}
+
+func (m *mmapEngine) ReadBlock(f *os.File, b []byte, off int64) (int, error) {
+	mb, err := m.mapping(f, false)
+	if err != nil {
+		return 0, err
+	}
+	if off >= int64(len(mb)) {
+		return 0, io.EOF
+	}
+	n := copy(b, mb[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *mmapEngine) WriteBlock(f *os.File, b []byte, off int64) (int, error) {
+	mb, err := m.mapping(f, true)
+	if err != nil {
+		return 0, err
+	}
+	if off >= int64(len(mb)) {
+		return 0, io.ErrShortWrite
+	}
+	n := copy(mb[off:], b)
+	if n < len(b) {
+		return n, io.ErrShortWrite
+	}
+	return n, nil
+}
+
+// Sync - flushes the mapping of f with msync, then f with fdatasync.
+func (m *mmapEngine) Sync(f *os.File) error {
+	if mp, ok := m.maps[f]; ok && mp.writable {
+		if err := unix.Msync(mp.b, unix.MS_SYNC); err != nil {
+			return fmt.Errorf("msync %s: %w", f.Name(), err)
+		}
+	}
+	return m.syncEngine.Sync(f)
+}
+
+// mapping - the mapping of f, writable if write is set, mapped at the
+// first access.
+func (m *mmapEngine) mapping(f *os.File, write bool) ([]byte, error) {
+	mp, ok := m.maps[f]
+	if ok && (mp.writable || !write) {
+		return mp.b, nil
+	}
+	if ok {
+		// Read first, written now.
+		if err := unix.Munmap(mp.b); err != nil {
+			return nil, err
+		}
+		delete(m.maps, f)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := m.extent
+	if fi.Mode().IsRegular() {
+		if write && fi.Size() < size {
+			if err = f.Truncate(size); err != nil {
+				return nil, err
+			}
+		} else {
+			size = min(size, fi.Size())
+		}
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	prot := unix.PROT_READ
+	if write {
+		prot |= unix.PROT_WRITE
+	}
+	b, err := unix.Mmap(int(f.Fd()), 0, int(size), prot, unix.MAP_SHARED)
+	if err != nil {
+		return nil, &os.PathError{Op: "mmap", Path: f.Name(), Err: err}
+	}
+	m.maps[f] = mapping{b: b, writable: write}
+	return b, nil
+}
+
+// Close - flushes the writable mappings with msync and unmaps them all.
+func (m *mmapEngine) Close() error {
+	var errs []error
+	for f, mp := range m.maps {
+		if mp.writable {
+			if err := unix.Msync(mp.b, unix.MS_SYNC); err != nil {
+				errs = append(errs, fmt.Errorf("msync %s: %w", f.Name(), err))
+			}
+		}
+		errs = append(errs, unix.Munmap(mp.b))
+	}
+	m.maps = nil
+	return errors.Join(errs...)
+}
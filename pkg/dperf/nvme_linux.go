@@ -0,0 +1,152 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// errNotNVMe returned for drives that do not speak NVMe.
+var errNotNVMe = errors.New("not an NVMe drive")
+
+const (
+	// _IOWR('N', 0x41, struct nvme_passthru_cmd)
+	nvmeIoctlAdminCmd = 0xC0484E41
+
+	nvmeAdminGetLogPage = 0x02
+	nvmeLogSmart        = 0x02
+	nvmeNSIDAll         = 0xFFFFFFFF
+
+	// SMART "Data Units" are thousands of 512 byte units.
+	nvmeDataUnitSize = 512 * 1000
+)
+
+// nvmePassthruCmd - struct nvme_passthru_cmd from linux/nvme_ioctl.h
+type nvmePassthruCmd struct {
+	opcode      uint8
+	flags       uint8
+	rsvd1       uint16
+	nsid        uint32
+	cdw2        uint32
+	cdw3        uint32
+	metadata    uint64
+	addr        uint64
+	metadataLen uint32
+	dataLen     uint32
+	cdw10       uint32
+	cdw11       uint32
+	cdw12       uint32
+	cdw13       uint32
+	cdw14       uint32
+	cdw15       uint32
+	timeoutMS   uint32
+	result      uint32
+}
+
+// nvmeSmartLog - fields of the SMART / Health Information log page.
+type nvmeSmartLog struct {
+	// temperature is the composite temperature in Kelvin.
+	temperature      uint16
+	percentageUsed   uint8
+	dataUnitsRead    uint64
+	dataUnitsWritten uint64
+	// thermalTransitions counts the times the drive entered a thermal
+	// management (throttling) state.
+	thermalTransitions uint64
+}
+
+// nvmeGetLogPage - reads a log page from an NVMe device.
+func nvmeGetLogPage(dev string, lid uint8, size int) ([]byte, error) {
+	f, err := os.Open("/dev/" + dev)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, size)
+	numd := uint32(size/4 - 1)
+	cmd := nvmePassthruCmd{
+		opcode:  nvmeAdminGetLogPage,
+		nsid:    nvmeNSIDAll,
+		addr:    uint64(uintptr(unsafe.Pointer(&buf[0]))),
+		dataLen: uint32(size),
+		cdw10:   uint32(lid) | (numd&0xffff)<<16,
+		cdw11:   numd >> 16,
+	}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlAdminCmd, uintptr(unsafe.Pointer(&cmd)))
+	if errno != 0 {
+		return nil, errno
+	}
+	return buf, nil
+}
+
+// readNVMeSmartLog - reads the SMART / Health Information log of an NVMe drive.
+func readNVMeSmartLog(dev *blockDev) (*nvmeSmartLog, error) {
+	if !strings.HasPrefix(dev.disk, "nvme") {
+		return nil, errNotNVMe
+	}
+	buf, err := nvmeGetLogPage(dev.disk, nvmeLogSmart, 512)
+	if err != nil {
+		return nil, err
+	}
+	// The 128 bit data unit counters will not exceed 64 bits in practice.
+	return &nvmeSmartLog{
+		temperature:      binary.LittleEndian.Uint16(buf[1:3]),
+		percentageUsed:   buf[5],
+		dataUnitsRead:    binary.LittleEndian.Uint64(buf[32:40]),
+		dataUnitsWritten: binary.LittleEndian.Uint64(buf[48:56]),
+		thermalTransitions: uint64(binary.LittleEndian.Uint32(buf[216:220])) +
+			uint64(binary.LittleEndian.Uint32(buf[220:224])),
+	}, nil
+}
+
+// wearSnapshot - captures the SMART endurance counters of the drive
+// backing path, returns nil if they are not available.
+func wearSnapshot(path string) *nvmeSmartLog {
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return nil
+	}
+	log, err := readNVMeSmartLog(dev)
+	if err != nil {
+		return nil
+	}
+	return log
+}
+
+// driveWear - computes the endurance cost between two snapshots of a run
+// that wrote written bytes.
+func driveWear(before, after *nvmeSmartLog, written uint64) *DriveWear {
+	if before == nil || after == nil {
+		return nil
+	}
+	wear := &DriveWear{
+		PercentUsedBefore: before.percentageUsed,
+		PercentUsedAfter:  after.percentageUsed,
+		BytesWritten:      (after.dataUnitsWritten - before.dataUnitsWritten) * nvmeDataUnitSize,
+	}
+	if written > 0 {
+		wear.HostWriteRatio = float64(wear.BytesWritten) / float64(written)
+	}
+	return wear
+}
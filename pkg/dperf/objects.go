@@ -0,0 +1,240 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Default object sizes of the small-object workload.
+const (
+	DefaultObjectMinSize = 4 << 10
+	DefaultObjectMaxSize = 1 << 20
+)
+
+// ObjectStats rate, throughput and latency of the small objects written
+// or read on a drive
+type ObjectStats struct {
+	Phase         Phase  `json:"phase"`
+	ObjectsPerSec uint64 `json:"objectsPerSec"`
+	// Throughput in bytes/sec.
+	Throughput uint64 `json:"throughput"`
+	// Latency of an object from its open to its close, the fdatasync of
+	// written objects included.
+	Latency *LatencyStats `json:"latency,omitempty"`
+}
+
+// objectSizes - the sizes of the Objects objects of an I/O worker, log
+// uniformly distributed from ObjectMinSize to ObjectMaxSize so that
+// small objects are the most common, aligned for O_DIRECT.
+func (d *DrivePerf) objectSizes(idx int) []uint64 {
+	seed := d.Seed + int64(idx)
+	if d.Seed == 0 {
+		seed = rand.Int63()
+	}
+	rnd := rand.New(rand.NewSource(seed))
+	lo, hi := math.Log(float64(d.ObjectMinSize)), math.Log(float64(d.ObjectMaxSize))
+	sizes := make([]uint64, d.Objects)
+	for i := range sizes {
+		size := uint64(math.Exp(lo + rnd.Float64()*(hi-lo)))
+		size += (DirectioAlignSize - size%DirectioAlignSize) % DirectioAlignSize
+		sizes[i] = min(max(size, d.ObjectMinSize), d.ObjectMaxSize)
+	}
+	return sizes
+}
+
+// objectFile - path of the object i of a worker, the objects are spread
+// over 256 directories as object names are over prefixes.
+func objectFile(dir string, i int) string {
+	return filepath.Join(dir, fmt.Sprintf("%02x", i%256), "object-"+strconv.Itoa(i))
+}
+
+// runObjectTests - writes Objects small objects per I/O worker, then
+// reads them back unless WriteOnly is set.
+func (d *DrivePerf) runObjectTests(ctx context.Context, path, testUUID string) *DrivePerfResult {
+	defer os.RemoveAll(filepath.Join(path, testUUID))
+
+	dirs := make([]string, d.IOPerDrive)
+	sizes := make([][]uint64, d.IOPerDrive)
+	bufs := make([][]byte, d.IOPerDrive)
+	srcs := make([]io.Reader, d.IOPerDrive)
+	dataDirs := make([][]string, d.IOPerDrive)
+	for i := range dirs {
+		dirs[i] = filepath.Join(path, testUUID, "objects-"+strconv.Itoa(i))
+		sizes[i] = d.objectSizes(i)
+		srcs[i] = d.newDataReader(i)
+		if d.erasure() {
+			bufs[i] = alignedBlock(erasureBufSize)
+			dataDirs[i] = make([]string, d.Objects)
+			for n := range dataDirs[i] {
+				dataDirs[i][n] = mustGetUUID()
+			}
+		} else {
+			bufs[i] = alignedBlock(int(d.ObjectMaxSize))
+		}
+	}
+	// The bytes written to and read from the drive, the shards of the
+	// objects when erasure coded.
+	driveSizes := sizes
+	if d.erasure() {
+		driveSizes = d.erasureSizes(sizes)
+	}
+
+	dr := &DrivePerfResult{Path: path}
+	write, err := d.runObjectPhase(ctx, PhaseWrite, driveSizes, func(idx, i int) error {
+		if d.erasure() {
+			return d.writeErasureObject(dirs[idx], i, dataDirs[idx][i], sizes[idx][i], bufs[idx], srcs[idx])
+		}
+		buf := bufs[idx][:sizes[idx][i]]
+		if _, err := io.ReadFull(srcs[idx], buf); err != nil {
+			return err
+		}
+		f := objectFile(dirs[idx], i)
+		if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+			return err
+		}
+		return writeObject(f, buf)
+	})
+	if err != nil {
+		dr.Error = err
+		return dr
+	}
+	dr.Objects = append(dr.Objects, write.stats)
+	// An object is an operation, its IOPS are its objects per second.
+	dr.WriteThroughput, dr.WriteIOPS = write.stats.Throughput, write.stats.ObjectsPerSec
+	dr.WriteOps, dr.WriteElapsed = write.objects, write.elapsed
+	dr.TotalBytesWritten = write.bytes
+	if d.WriteOnly {
+		return dr
+	}
+
+	read, err := d.runObjectPhase(ctx, PhaseRead, driveSizes, func(idx, i int) error {
+		if d.erasure() {
+			return d.readErasureObject(dirs[idx], i, dataDirs[idx][i], sizes[idx][i], bufs[idx])
+		}
+		return readObject(objectFile(dirs[idx], i), bufs[idx][:sizes[idx][i]])
+	})
+	if err != nil {
+		dr.Error = err
+		return dr
+	}
+	dr.Objects = append(dr.Objects, read.stats)
+	dr.ReadThroughput, dr.ReadIOPS = read.stats.Throughput, read.stats.ObjectsPerSec
+	dr.ReadOps, dr.ReadElapsed = read.objects, read.elapsed
+	dr.TotalBytesRead = read.bytes
+	return dr
+}
+
+// objectPhase - outcome of the workers of a drive in an object phase.
+type objectPhase struct {
+	stats   ObjectStats
+	objects uint64
+	bytes   uint64
+	elapsed time.Duration
+}
+
+// runObjectPhase - runs op against every object of every worker, the
+// workers run concurrently and their objects one after the other.
+func (d *DrivePerf) runObjectPhase(ctx context.Context, phase Phase, sizes [][]uint64, op func(idx, i int) error) (objectPhase, error) {
+	hists := make([]*histogram, len(sizes))
+	errs := make([]error, len(sizes))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(len(sizes))
+	for idx := range sizes {
+		go func(idx int) {
+			defer wg.Done()
+			h := newHistogram()
+			hists[idx] = h
+			for i := range sizes[idx] {
+				if err := ctx.Err(); err != nil {
+					errs[idx] = err
+					return
+				}
+				opStart := time.Now()
+				if err := op(idx, i); err != nil {
+					errs[idx] = err
+					return
+				}
+				h.record(time.Since(opStart))
+			}
+		}(idx)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	for _, err := range errs {
+		if err != nil {
+			return objectPhase{}, fmt.Errorf("%s: %w", phase, err)
+		}
+	}
+	latency := newHistogram()
+	for _, h := range hists {
+		latency.merge(h)
+	}
+	var bytes uint64
+	for _, s := range sizes {
+		for _, size := range s {
+			bytes += size
+		}
+	}
+	return objectPhase{
+		stats: ObjectStats{
+			Phase:         phase,
+			ObjectsPerSec: uint64(float64(latency.total) / elapsed.Seconds()),
+			Throughput:    uint64(float64(bytes) / elapsed.Seconds()),
+			Latency:       newLatencyStats(latency),
+		},
+		objects: latency.total,
+		bytes:   bytes,
+		elapsed: elapsed,
+	}, nil
+}
+
+// objectCells - rate, throughput and latency of the small objects of
+// every drive, the first row is the header.
+func (r *Report) objectCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"PHASE",
+		"OBJECTS/S",
+		"THROUGHPUT",
+		"P50",
+		"P99",
+		"MAX",
+	}}
+	for _, result := range r.Results {
+		for _, o := range result.Objects {
+			row := []string{result.Path, string(o.Phase), strconv.FormatUint(o.ObjectsPerSec, 10), formatRate(o.Throughput), "-", "-", "-"}
+			if l := o.Latency; l != nil {
+				row[4], row[5], row[6] = formatLatency(l.P50), formatLatency(l.P99), formatLatency(l.Max)
+			}
+			cellText = append(cellText, row)
+		}
+	}
+	return cellText
+}
@@ -0,0 +1,127 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// maxLatencyOutliers - outliers kept per drive with their timestamp, the
+// others are only counted.
+const maxLatencyOutliers = 100
+
+// LatencyOutlier a block operation slower than DrivePerf.LatencyThreshold
+type LatencyOutlier struct {
+	// Time the operation started.
+	Time    time.Time     `json:"time"`
+	Phase   Phase         `json:"phase"`
+	Latency time.Duration `json:"latency"`
+}
+
+// LatencyOutliers operations of a drive slower than the threshold
+type LatencyOutliers struct {
+	Threshold time.Duration `json:"threshold"`
+	// Count of all outliers, Ops holds the first of them by time.
+	Count uint64           `json:"count"`
+	Ops   []LatencyOutlier `json:"ops,omitempty"`
+}
+
+// outliers - outliers of an I/O worker or a drive.
+type outliers struct {
+	count uint64
+	ops   []LatencyOutlier
+}
+
+func (o *outliers) add(op LatencyOutlier) {
+	o.count++
+	if len(o.ops) < maxLatencyOutliers {
+		o.ops = append(o.ops, op)
+	}
+}
+
+// merge - adds the outliers of another worker, keeping the earliest.
+func (o *outliers) merge(other outliers) {
+	o.count += other.count
+	o.ops = append(o.ops, other.ops...)
+	sort.Slice(o.ops, func(i, j int) bool {
+		return o.ops[i].Time.Before(o.ops[j].Time)
+	})
+	if len(o.ops) > maxLatencyOutliers {
+		o.ops = o.ops[:maxLatencyOutliers]
+	}
+}
+
+// latencyOutliers - the outliers of the phases of a drive, nil when
+// outliers are not tracked.
+func (d *DrivePerf) latencyOutliers(phases ...phaseResult) *LatencyOutliers {
+	if d.LatencyThreshold <= 0 {
+		return nil
+	}
+	var o outliers
+	for _, pr := range phases {
+		o.merge(pr.outliers)
+	}
+	return &LatencyOutliers{
+		Threshold: d.LatencyThreshold,
+		Count:     o.count,
+		Ops:       o.ops,
+	}
+}
+
+// outlierCells - every outlier kept per drive, the first row is the header.
+func (r *Report) outlierCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"PHASE",
+		"TIME",
+		"LATENCY",
+	}}
+	for _, result := range r.Results {
+		o := result.Outliers
+		if o == nil {
+			continue
+		}
+		for _, op := range o.Ops {
+			cellText = append(cellText, []string{
+				result.Path,
+				string(op.Phase),
+				op.Time.Local().Format("15:04:05.000"),
+				formatLatency(op.Latency),
+			})
+		}
+		if more := o.Count - uint64(len(o.Ops)); more > 0 {
+			cellText = append(cellText, []string{
+				result.Path,
+				"",
+				fmt.Sprintf("%d more", more),
+				"",
+			})
+		}
+	}
+	return cellText
+}
+
+// outlierCount - number of outliers of a drive, "-" if not tracked.
+func outlierCount(o *LatencyOutliers) string {
+	if o == nil {
+		return "-"
+	}
+	return strconv.FormatUint(o.Count, 10)
+}
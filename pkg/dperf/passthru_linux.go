@@ -0,0 +1,119 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// _IO('N', 0x40)
+	nvmeIoctlID = 0x4E40
+	// _IOWR('N', 0x43, struct nvme_passthru_cmd)
+	nvmeIoctlIOCmd = 0xC0484E43
+
+	nvmeCmdRead = 0x02
+)
+
+// nvmeEngine - EngineNVMe, reads the blocks of the namespace it opened
+// with NVMe Read commands passed through to it.
+type nvmeEngine struct {
+	syncEngine
+	nsid uint32
+	// lbaSize is the logical block size of the namespace, maxTransfer the
+	// most bytes read by a single command.
+	lbaSize, maxTransfer int
+}
+
+// Open - opens the namespace at path, without O_DIRECT, the commands
+// do not go through the page cache.
+func (e *nvmeEngine) Open(path string, flag int, perm os.FileMode, _ bool) (*os.File, error) {
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	nsid, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlID, 0)
+	if errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, errNotNVMe)
+	}
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if dev.name != dev.disk {
+		// The commands address the blocks of the whole namespace.
+		f.Close()
+		return nil, fmt.Errorf("%s is a partition, the nvme engine reads whole namespaces", path)
+	}
+	lbaSize, err := dev.queueAttrUint("logical_block_size")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	maxKB, err := dev.queueAttrUint("max_hw_sectors_kb")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	e.nsid, e.lbaSize = uint32(nsid), int(lbaSize)
+	e.maxTransfer = max(int(maxKB<<10)/e.lbaSize, 1) * e.lbaSize
+	return f, nil
+}
+
+// ReadBlock - reads b from the namespace at off, in commands of at most
+// maxTransfer bytes, off and len(b) are multiples of its logical blocks.
+func (e *nvmeEngine) ReadBlock(f *os.File, b []byte, off int64) (int, error) {
+	if off%int64(e.lbaSize) != 0 || len(b)%e.lbaSize != 0 {
+		return 0, fmt.Errorf("nvme read of %d bytes at %d is not aligned to the %d byte logical blocks", len(b), off, e.lbaSize)
+	}
+	var done int
+	for done < len(b) {
+		chunk := b[done:min(len(b), done+e.maxTransfer)]
+		slba := uint64(off+int64(done)) / uint64(e.lbaSize)
+		cmd := nvmePassthruCmd{
+			opcode:  nvmeCmdRead,
+			nsid:    e.nsid,
+			addr:    uint64(uintptr(unsafe.Pointer(&chunk[0]))),
+			dataLen: uint32(len(chunk)),
+			cdw10:   uint32(slba),
+			cdw11:   uint32(slba >> 32),
+			// The number of logical blocks is 0 based.
+			cdw12: uint32(len(chunk)/e.lbaSize - 1),
+		}
+		status, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), nvmeIoctlIOCmd, uintptr(unsafe.Pointer(&cmd)))
+		runtime.KeepAlive(chunk)
+		if errno != 0 {
+			return done, fmt.Errorf("nvme read at %d: %w", off+int64(done), errno)
+		}
+		if status != 0 {
+			return done, fmt.Errorf("nvme read at %d: status %#x", off+int64(done), status)
+		}
+		done += len(chunk)
+	}
+	return done, nil
+}
+
+func (e *nvmeEngine) WriteBlock(f *os.File, b []byte, off int64) (int, error) {
+	return 0, errNVMeEngine
+}
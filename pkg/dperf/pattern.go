@@ -0,0 +1,75 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import "io"
+
+// Data patterns of the data written
+const (
+	PatternRandom       = "random"
+	PatternZero         = "zero"
+	PatternCompressible = "compressible"
+)
+
+// newDataReader - returns the source of the data written by an I/O
+// worker in DataPattern, random data if empty.
+func (d *DrivePerf) newDataReader(idx int) io.Reader {
+	switch d.DataPattern {
+	case PatternZero:
+		return zeroReader{}
+	case PatternCompressible:
+		return &compressibleReader{r: d.newRandomReader(idx), percent: d.Compressibility}
+	}
+	return d.newRandomReader(idx)
+}
+
+// zeroReader - an endless stream of zeros.
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	clear(b)
+	return len(b), nil
+}
+
+// compressibleReader - random data whose every 4KiB chunk ends with
+// percent percent of zeros, so that it compresses by about percent
+// percent with any compression algorithm.
+type compressibleReader struct {
+	r       io.Reader
+	percent int
+	// pos is the position within the current chunk.
+	pos int
+}
+
+func (c *compressibleReader) Read(b []byte) (int, error) {
+	random := DirectioAlignSize * (100 - c.percent) / 100
+	var n int
+	for n < len(b) {
+		m := min(len(b)-n, DirectioAlignSize-c.pos)
+		if c.pos < random {
+			m = min(m, random-c.pos)
+			if _, err := io.ReadFull(c.r, b[n:n+m]); err != nil {
+				return n, err
+			}
+		} else {
+			clear(b[n : n+m])
+		}
+		n += m
+		c.pos = (c.pos + m) % DirectioAlignSize
+	}
+	return n, nil
+}
@@ -18,13 +18,38 @@ package dperf
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"sync"
+	"syscall"
+	"text/template"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/minio/pkg/v3/rng"
+)
+
+// DirectioAlignSize - DirectIO alignment needs to be 4K. Defined here as
+// directio.AlignSize is defined as 0 in MacOS causing divide by 0 error.
+const DirectioAlignSize = 4096
+
+// Sync modes of the writes
+const (
+	SyncModeDirect = "direct"
+	SyncModeDSync  = "dsync"
+	SyncModeSync   = "sync"
+)
+
+// Write modes of the write phase
+const (
+	WriteModeFresh     = "fresh"
+	WriteModeOverwrite = "overwrite"
 )
 
 // DrivePerf options
@@ -35,6 +60,266 @@ type DrivePerf struct {
 	FileSize   uint64
 	IOPerDrive int
 	WriteOnly  bool
+	ReadOnly   bool
+	// Destructive allows writing to block devices passed instead of
+	// directories, destroying the data on them. Block devices are only
+	// read in ReadOnly mode.
+	Destructive bool
+	// Offset if set is where the I/O starts in the test files, or on
+	// block devices, whose I/O workers operate on their own FileSize
+	// bytes one after the other from there.
+	Offset uint64
+	// FilesPerIO if set spreads the FileSize bytes of every I/O worker
+	// over as many test files, written and read a block of each in turn.
+	// Existing files read in ReadOnly mode are not spread.
+	FilesPerIO int
+	// Rate if set limits the reads and writes of every drive to Rate
+	// bytes/sec, split evenly between its I/O workers.
+	Rate uint64
+	// MaxWrite caps the total bytes written across all drives, 0 means no
+	// limit. Runs whose writes are unbounded, see UnboundedWrite, are
+	// refused.
+	MaxWrite uint64
+	// Output format of RunAndRender, one of the Output* constants.
+	Output string
+	// Out receives the rendered results, os.Stdout if nil.
+	Out io.Writer
+	// Format if set is executed for every result instead of rendering
+	// Output, see ParseFormat.
+	Format *template.Template
+	// Version of dperf recorded in the reports.
+	Version string
+	// Tags are arbitrary key=value metadata embedded in all outputs.
+	Tags map[string]string
+	// Seed makes the generated data reproducible across runs, 0 picks a random seed.
+	Seed int64
+	// PreRun if set is called before a drive is tested, an error fails the drive.
+	PreRun func(ctx context.Context, path string) error
+	// PostRun if set is called with the result once a drive is tested.
+	PostRun func(ctx context.Context, result *DrivePerfResult)
+	// Progress if set is called after every block transferred by an I/O
+	// worker, it is called concurrently and must not block.
+	Progress func(ProgressUpdate)
+	// SyncEvery if set issues an fdatasync after every SyncEvery blocks
+	// written and records its latency, the file is always synced once
+	// written.
+	SyncEvery int
+	// SyncMode is how the test files are opened for writing,
+	// SyncModeDirect if empty. SyncModeDSync adds O_DSYNC and SyncModeSync
+	// O_SYNC to O_DIRECT, every write then returns once it is durable.
+	SyncMode string
+	// WriteMode is WriteModeFresh if empty, the write phase creates the
+	// test files anew so that its writes allocate their blocks.
+	// WriteModeOverwrite writes the test files once before the write
+	// phase, which then overwrites them in place.
+	WriteMode string
+	// DataPattern is the data written, one of the Pattern* constants,
+	// PatternRandom if empty. PatternCompressible writes random data of
+	// which Compressibility percent compresses away, which matters to
+	// drives and filesystems that compress or deduplicate.
+	DataPattern     string
+	Compressibility int
+	// Fill if set writes test files of FileSize until Fill percent of the
+	// capacity of the filesystem is in use, or until it is full, and
+	// reports the write throughput by fill level. Nothing is read.
+	Fill float64
+	// Ramp if set writes the test files with a single I/O worker at
+	// first and starts another every Ramp/IOPerDrive, and reports the
+	// write throughput by depth. Nothing is read.
+	Ramp time.Duration
+	// KeepFiles if set leaves the test files on the drives, in a
+	// directory of their own with a description of them, for a later run
+	// to Reuse.
+	KeepFiles bool
+	// Reuse if set skips the write phase and reads the files kept by an
+	// earlier run with KeepFiles, with its file size and I/O workers. With
+	// Verify the data is checked against the checksums written then. The
+	// files are removed unless KeepFiles is set as well.
+	Reuse bool
+	// WarmRead if set reads the files again through the page cache after
+	// the read phase, once to load them into it then measured, and adds
+	// the result to that of the drive as WarmRead.
+	WarmRead bool
+	// Soak if set repeats the tests of all drives until it elapses,
+	// RunAndRender then renders and publishes the report of every cycle.
+	Soak time.Duration
+	// Verify embeds a checksum in every 4KiB of the data written and
+	// checks it when the data is read back, a drive whose data does not
+	// read back as written fails with ErrCorruptData. The checksums
+	// replace the last 16 bytes of every 4KiB of the DataPattern.
+	Verify bool
+	// Access is the order of the block operations within a file,
+	// AccessSequential if empty. AccessRandom issues them at the block
+	// aligned offsets of the file in random order, each once.
+	Access string
+	// Engine is how the blocks of the test files are transferred, one of
+	// the Engine* constants, EngineSync if empty.
+	Engine string
+	// AIODepth is the number of operations EngineLibaio keeps in flight
+	// per I/O worker, every block is split in up to AIODepth operations
+	// of whole 4KiB submitted together, 1 if 0.
+	AIODepth int
+	// RWFlags are the RWFlag* constants every block is transferred with
+	// by EngineSync, then with preadv2 and pwritev2, Linux only.
+	RWFlags []string
+	// Copy if set copies the test files of every drive once read with
+	// copy_file_range into CopyTo, or next to them on the drive if empty,
+	// and reports the throughput of the copies, Linux only.
+	Copy   bool
+	CopyTo string
+	// Poll if set runs the tests again with RWF_HIPRI, polling for the
+	// completion of every block rather than waiting for its interrupt,
+	// to compare the latency of both, Linux only.
+	Poll bool
+	// NewEngine if set creates the IOEngine of every I/O worker in place
+	// of Engine and RWFlags, extent is the number of bytes from the start
+	// of every test file the worker transfers. Engine then names it in
+	// the results.
+	NewEngine func(extent int64) (IOEngine, error)
+	// ReadMix if set replaces the read phase with a mixed phase reading
+	// ReadMix percent of the blocks of the files written and overwriting
+	// the others, interleaved. The writes of the mixed phase are then
+	// reported instead of those of the write phase.
+	ReadMix int
+	// Duration if set makes every phase last this long, the files are
+	// rewritten and reread until it elapses and the throughput is that of
+	// all the bytes moved. A write phase always writes the files once.
+	Duration time.Duration
+	// Runs if greater than 1 repeats the tests of every drive, the
+	// results report the mean, min, max and standard deviation of the
+	// throughput and IOPS over the runs.
+	Runs int
+	// Warmup if set transfers data for this long before the tests of
+	// every drive, unmeasured, so that caches and CPU frequencies settle.
+	Warmup time.Duration
+	// WarmupBytes if set transfers this many bytes per drive instead.
+	WarmupBytes uint64
+	// FilePercent if set sizes the test files of every drive so that
+	// together they take this percent of its free space, FileSize is
+	// then ignored.
+	FilePercent float64
+	// MaxFileSize caps the size of the test files sized by FilePercent,
+	// 0 means no limit.
+	MaxFileSize uint64
+	// MetadataFiles if set benchmarks creating, stating, renaming and
+	// unlinking this many empty files per I/O worker instead of the
+	// throughput.
+	MetadataFiles int
+	// Objects if set writes and reads back this many small objects per
+	// I/O worker, of ObjectMinSize to ObjectMaxSize bytes, instead of
+	// the large test files.
+	Objects       int
+	ObjectMinSize uint64
+	ObjectMaxSize uint64
+	// ErasureData if set lays the objects out as MinIO does on every
+	// drive of an erasure set of ErasureData data and ErasureParity
+	// parity drives: the shards of the parts of PartSize, all of the
+	// object if 0, are written to a temporary directory with their
+	// xl.meta, then renamed into place. Small objects are inlined in
+	// xl.meta.
+	ErasureData   int
+	ErasureParity int
+	PartSize      uint64
+	// Existing if set is the file or directory, relative to every drive,
+	// the files read in read-only mode are looked for under, the whole
+	// drive otherwise.
+	Existing string
+	// LatencyThreshold if set counts the block operations slower than it
+	// as outliers of their drive.
+	LatencyThreshold time.Duration
+	// SlowestOps is the number of slowest block operations recorded per
+	// drive with their offset and time.
+	SlowestOps int
+	// Latency if set is called with the latency of every block operation
+	// against the drive at path, it is called concurrently and must not block.
+	Latency func(path string, phase Phase, latency time.Duration)
+	// Publish if set is called with the report once it is rendered, to
+	// hand the results to sinks other than stdout.
+	Publish func(report *Report) error
+	// Score if set rates every drive with a composite score from 0 to 100
+	// and sorts the results by it instead of the read throughput.
+	Score *ScoreWeights
+	// Thresholds fail RunAndRender when the results are too slow.
+	Thresholds Thresholds
+	// Specs expected throughput by drive model, see DefaultSpec.
+	Specs map[string]DriveSpec
+	// Timeline if set samples the throughput of every drive every second,
+	// the stability of every drive is then computed from it.
+	Timeline *Timeline
+	// MaxDegradation is the drop in percent of the throughput from the
+	// start to the end of a phase beyond which a drive is flagged
+	// degraded, 0 uses DefaultMaxDegradation.
+	MaxDegradation float64
+	// Series adds the throughput over time recorded by Timeline, and the
+	// temperature over time, to the result of every drive.
+	Series bool
+
+	// cached reads through the page cache rather than with O_DIRECT.
+	cached bool
+	// reusedTag is the verify tag of the reused files.
+	reusedTag uint32
+	// zones of the zoned block device tested, nil otherwise.
+	zones *zoneLayout
+}
+
+// maxDegradation - the drop of throughput a drive is flagged degraded at.
+func (d *DrivePerf) maxDegradation() float64 {
+	if d.MaxDegradation > 0 {
+		return d.MaxDegradation
+	}
+	return DefaultMaxDegradation
+}
+
+// PlannedWrite returns the total bytes a run against n drives will write,
+// at least when the phases are timed, see UnboundedWrite.
+func (d *DrivePerf) PlannedWrite(n int) uint64 {
+	if d.ReadOnly || d.Reuse || d.MetadataFiles > 0 {
+		return 0
+	}
+	size := d.FileSize
+	if d.Objects > 0 {
+		// At most, the sizes of the objects are random.
+		size = d.ObjectMaxSize * uint64(d.Objects)
+		if d.erasure() {
+			size = d.erasureSizes([][]uint64{{d.ObjectMaxSize}})[0][0] * uint64(d.Objects)
+		}
+	}
+	if d.ReadMix > 0 {
+		// The mixed phase overwrites about 100-ReadMix percent of the files.
+		size += d.FileSize * uint64(100-d.ReadMix) / 100
+	}
+	if d.WriteMode == WriteModeOverwrite {
+		// The files are written once before the write phase.
+		size += d.FileSize
+	}
+	if d.WarmupBytes > 0 {
+		// The warmup before the tests, spread over the workers.
+		warmup := d.WarmupBytes / uint64(max(d.IOPerDrive, 1))
+		size += max(warmup-warmup%DirectioAlignSize, DirectioAlignSize)
+	}
+	if d.Access == AccessBoth {
+		// The sequential and the random phases write the files.
+		size *= 2
+	}
+	if d.Copy {
+		// The files are copied once written.
+		size += d.FileSize
+	}
+	if d.Poll {
+		// The tests run again polled.
+		size *= 2
+	}
+	return size * uint64(d.IOPerDrive) * uint64(n) * uint64(max(d.Runs, 1))
+}
+
+// UnboundedWrite reports if the bytes a run writes depend on how long its
+// timed phases last rather than on FileSize, PlannedWrite is then only
+// what the run writes at least and MaxWrite cannot be enforced.
+func (d *DrivePerf) UnboundedWrite() bool {
+	if d.ReadOnly || d.Reuse || d.MetadataFiles > 0 {
+		return false
+	}
+	return d.Duration > 0 || d.Warmup > 0 && d.WarmupBytes == 0
 }
 
 // mustGetUUID - get a random UUID.
@@ -47,56 +332,351 @@ func mustGetUUID() string {
 	return u.String()
 }
 
-func (d *DrivePerf) runTests(ctx context.Context, path string, testUUID string) (dr *DrivePerfResult) {
-	writeThroughputs := make([]uint64, d.IOPerDrive)
-	readThroughputs := make([]uint64, d.IOPerDrive)
-	errs := make([]error, d.IOPerDrive)
+// newRandomReader - returns random data for an I/O worker, deterministic
+// per worker when a Seed is set.
+func (d *DrivePerf) newRandomReader(idx int) io.Reader {
+	var opts []rng.ReaderOption
+	if d.Seed != 0 {
+		opts = append(opts, rng.WithRNG(rand.New(rand.NewSource(d.Seed+int64(idx)))))
+	}
+	r, err := rng.NewReader(opts...)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
 
-	dataBuffers := make([][]byte, d.IOPerDrive)
-	for i := 0; i < d.IOPerDrive; i++ {
-		// Read Aligned block upto a multiple of BlockSize
-		dataBuffers[i] = alignedBlock(int(d.BlockSize))
+// testFilePath - returns the path of the test file of an I/O worker.
+func testFilePath(path, testUUID string, idx int) string {
+	return filepath.Join(path, testUUID, ".writable-check.tmp-"+strconv.Itoa(idx))
+}
+
+// readSize - the bytes of the existing file at path read in read-only
+// mode, at most FileSize aligned down for O_DIRECT.
+func (d *DrivePerf) readSize(path string) (uint64, error) {
+	if isBlockDevice(path) {
+		// The region of the worker, checked to fit by checkDevice.
+		return d.FileSize - d.FileSize%DirectioAlignSize, nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if uint64(fi.Size()) <= d.Offset {
+		return 0, fmt.Errorf("%s is %s, it ends before the offset %s", path, FormatBytes(uint64(fi.Size())), FormatBytes(d.Offset))
+	}
+	size := min(uint64(fi.Size())-d.Offset, d.FileSize)
+	return size - size%DirectioAlignSize, nil
+}
+
+// existingFiles - returns the files read in read-only mode from the drive
+// at path, one per I/O worker, found under Existing.
+func (d *DrivePerf) existingFiles(path string) ([]string, error) {
+	if isBlockDevice(path) {
+		return d.deviceFiles(path), nil
+	}
+	root := filepath.Join(path, d.Existing)
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+	return findExistingFiles(root, d.IOPerDrive)
+}
+
+// findExistingFiles - returns up to n regular files under path that are
+// large enough to be read with O_DIRECT, path itself if it is one.
+func findExistingFiles(path string, n int) ([]string, error) {
+	files := make([]string, 0, n)
+	err := filepath.WalkDir(path, func(fpath string, de fs.DirEntry, err error) error {
+		if err != nil {
+			// skip entries we are not allowed to look at.
+			return nil
+		}
+		if !de.Type().IsRegular() {
+			return nil
+		}
+		fi, err := de.Info()
+		if err != nil || fi.Size() < DirectioAlignSize {
+			return nil
+		}
+		files = append(files, fpath)
+		if len(files) == n {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	if len(files) == 0 {
+		return nil, errors.New("no existing files found to read under '" + path + "'")
+	}
+	return files, nil
+}
+
+// runReadOnlyTests - benchmarks reads against files already present
+// under path, nothing is written to the drive.
+func (d *DrivePerf) runReadOnlyTests(ctx context.Context, path string) (dr *DrivePerfResult) {
+	files, err := d.existingFiles(path)
+	if err != nil {
+		return &DrivePerfResult{
+			Path:  path,
+			Error: err,
+		}
+	}
+
+	if err := d.warmup(ctx, path, files); err != nil {
+		return &DrivePerfResult{
+			Path:  path,
+			Error: err,
+		}
+	}
+
+	readResults := make([]ioResult, d.IOPerDrive)
+	errs := make([]error, d.IOPerDrive)
 
-	testUUIDPath := filepath.Join(path, testUUID)
-	testPath := filepath.Join(testUUIDPath, ".writable-check.tmp")
-	defer os.RemoveAll(testUUIDPath)
+	statsBefore := snapshotDiskStats(path)
+	qd := sampleQueueDepth(path)
+	temp := sampleTemperature(path)
 
 	var wg sync.WaitGroup
-	wg.Add(int(d.IOPerDrive))
-	for i := 0; i < int(d.IOPerDrive); i++ {
+	wg.Add(d.IOPerDrive)
+	for i := 0; i < d.IOPerDrive; i++ {
 		go func(idx int) {
 			defer wg.Done()
-			iopath := testPath + "-" + strconv.Itoa(idx)
-			writeThroughput, err := d.runWriteTest(ctx, iopath, dataBuffers[idx])
+			iopath := files[idx%len(files)]
+			size, err := d.readSize(iopath)
 			if err != nil {
 				errs[idx] = err
 				return
 			}
-			writeThroughputs[idx] = writeThroughput
+			readResult, err := d.runReadTest(ctx, iopath, alignedBlock(int(d.BlockSize)), size,
+				d.newIOStats(path, PhaseRead, idx, size), d.newProgress(path, PhaseRead, idx, size))
+			if err != nil {
+				errs[idx] = err
+				return
+			}
+			readResults[idx] = readResult
 		}(i)
 	}
 	wg.Wait()
+	queueDepth := qd.result()
+	temperature := temp.result()
 
+	for _, err := range errs {
+		if err != nil {
+			return &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
+		}
+	}
+
+	var warm *WarmRead
+	if d.WarmRead {
+		if warm, err = d.runWarmRead(ctx, path, files); err != nil {
+			return &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
+		}
+	}
+
+	read := phaseResults(readResults)
+	return &DrivePerfResult{
+		Path:                path,
+		ReadThroughput:      read.throughput,
+		ReadIOPS:            read.iops,
+		ReadOps:             read.ops,
+		ReadElapsed:         read.elapsed,
+		TotalBytesRead:      read.bytes,
+		ReadWorkers:         read.workers,
+		ReadLatency:         read.latencyStats(),
+		ReadSubmitLatency:   newLatencyStats(read.submitLatency),
+		ReadCompleteLatency: newLatencyStats(read.completeLatency),
+		Outliers:            d.latencyOutliers(read),
+		SlowestOps:          d.slowestOps(read),
+		Regions:             read.regions.stats(PhaseRead),
+		Zones:               read.zones.stats(PhaseRead, d.zones),
+		QueueDepth:          queueDepth,
+		Temperature:         temperature,
+		DiskStats:           diskStats(statsBefore, snapshotDiskStats(path)),
+		WarmRead:            warm,
+		readHist:            read.latency,
+	}
+}
+
+func (d *DrivePerf) runTests(ctx context.Context, path string, testUUID string) (dr *DrivePerfResult) {
+	device := isBlockDevice(path)
+	if device {
+		dd, err := d.checkDevice(path)
+		if err != nil {
+			return &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
+		}
+		d = dd
+	} else if d.Engine == EngineNVMe {
+		return &DrivePerfResult{
+			Path:  path,
+			Error: errNVMeEngine,
+		}
+	}
+	if d.ReadOnly {
+		return d.runReadOnlyTests(ctx, path)
+	}
+
+	readOnly, err := isReadOnlyFS(path)
+	if err != nil {
+		return &DrivePerfResult{
+			Path:  path,
+			Error: err,
+		}
+	}
+	if readOnly && !d.Reuse {
+		return &DrivePerfResult{
+			Path:  path,
+			Error: ErrReadOnlyFS,
+		}
+	}
+	if d.MetadataFiles > 0 {
+		return d.runMetadataTests(ctx, path, testUUID)
+	}
+	if d.Objects > 0 {
+		return d.runObjectTests(ctx, path, testUUID)
+	}
+	if d.Fill > 0 {
+		return d.runFillTest(ctx, path, testUUID)
+	}
+	if d.Ramp > 0 {
+		return d.runRampTest(ctx, path, testUUID)
+	}
+	if d.Reuse {
+		// The files, and their layout, are those of an earlier run.
+		if d, err = d.reuseFiles(path); err != nil {
+			return &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
+		}
+	}
+
+	writeResults := make([]ioResult, d.IOPerDrive)
+	readResults := make([]ioResult, d.IOPerDrive)
+	errs := make([]error, d.IOPerDrive)
+
+	dataBuffers := make([][]byte, d.IOPerDrive)
+	for i := 0; i < d.IOPerDrive; i++ {
+		// Read Aligned block upto a multiple of BlockSize
+		dataBuffers[i] = alignedBlock(int(d.BlockSize))
+	}
+
+	var files []string
+	if device {
+		files = d.deviceFiles(path)
+	} else if d.KeepFiles || d.Reuse {
+		if d.KeepFiles && !d.Reuse {
+			// Files kept by an earlier run are replaced.
+			os.RemoveAll(filepath.Join(path, keepDir))
+		}
+		if !d.KeepFiles {
+			defer os.RemoveAll(filepath.Join(path, keepDir))
+		}
+
+		files = make([]string, d.IOPerDrive)
+		for i := range files {
+			files[i] = testFilePath(path, keepDir, i)
+		}
+	} else {
+		var remove func()
+		files, remove = d.testFiles(path, testUUID)
+		defer remove()
+	}
+	if !d.Reuse {
+		if !device && d.WriteMode == WriteModeOverwrite {
+			if err := d.prefill(ctx, path, files); err != nil {
+				return &DrivePerfResult{
+					Path:  path,
+					Error: err,
+				}
+			}
+		}
+		if err := d.warmup(ctx, path, files); err != nil {
+			return &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
+		}
+	}
+
+	wearBefore := wearSnapshot(path)
+	statsBefore := snapshotDiskStats(path)
+	qd := sampleQueueDepth(path)
+	temp := sampleTemperature(path)
+
+	var wg sync.WaitGroup
+	if !d.Reuse {
+		wg.Add(int(d.IOPerDrive))
+		for i := 0; i < int(d.IOPerDrive); i++ {
+			go func(idx int) {
+				defer wg.Done()
+				iopath := files[idx]
+				writeResult, err := d.runWriteTest(ctx, iopath, dataBuffers[idx], d.newDataReader(idx),
+					d.newIOStats(path, PhaseWrite, idx, d.FileSize), d.newProgress(path, PhaseWrite, idx, d.FileSize))
+				if err != nil {
+					errs[idx] = err
+					return
+				}
+				writeResults[idx] = writeResult
+			}(i)
+		}
+		wg.Wait()
+		if d.KeepFiles && errors.Join(errs...) == nil {
+			// Described as soon as written, so that they can be reused
+			// whatever happens to the reads.
+			errs[0] = d.keepFiles(path)
+		}
+	}
+
+	// With a read mix the files just written are read and overwritten
+	// in the same phase, whose writes replace those of the write phase.
+	mixedResults := make([]ioResult, d.IOPerDrive)
 	if !d.WriteOnly {
 		wg.Add(d.IOPerDrive)
 		for i := 0; i < d.IOPerDrive; i++ {
 			go func(idx int) {
 				defer wg.Done()
-				iopath := testPath + "-" + strconv.Itoa(idx)
-				readThroughput, err := d.runReadTest(ctx, iopath, dataBuffers[idx])
+				iopath := files[idx]
+				if d.ReadMix > 0 {
+					readResult, writeResult, err := d.runMixedTest(ctx, iopath, dataBuffers[idx], d.newDataReader(idx),
+						d.newIOStats(path, PhaseRead, idx, d.FileSize), d.newIOStats(path, PhaseWrite, idx, d.FileSize),
+						d.newProgress(path, PhaseMixed, idx, d.FileSize))
+					if err != nil {
+						errs[idx] = err
+						return
+					}
+					readResults[idx], mixedResults[idx] = readResult, writeResult
+					return
+				}
+				readResult, err := d.runReadTest(ctx, iopath, dataBuffers[idx], d.FileSize,
+					d.newIOStats(path, PhaseRead, idx, d.FileSize), d.newProgress(path, PhaseRead, idx, d.FileSize))
 				if err != nil {
 					errs[idx] = err
 					return
 				}
-				readThroughputs[idx] = readThroughput
+				readResults[idx] = readResult
 			}(i)
 		}
 		wg.Wait()
 	}
+	queueDepth := qd.result()
+	temperature := temp.result()
 
 	for _, err := range errs {
 		if err != nil {
+			if errors.Is(err, syscall.EROFS) {
+				err = ErrReadOnlyFS
+			}
 			return &DrivePerfResult{
 				Path:  path,
 				Error: err,
@@ -104,23 +684,111 @@ func (d *DrivePerf) runTests(ctx context.Context, path string, testUUID string)
 		}
 	}
 
-	var writeThroughput uint64
-	for i := range writeThroughputs {
-		writeThroughput += writeThroughputs[i]
+	write := phaseResults(writeResults)
+	written := write.bytes
+	if d.ReadMix > 0 {
+		write = phaseResults(mixedResults)
+		written += write.bytes
 	}
 
-	var readThroughput uint64
+	var read phaseResult
 	if !d.WriteOnly {
-		for i := range readThroughputs {
-			readThroughput += readThroughputs[i]
+		read = phaseResults(readResults)
+	}
+
+	// Copied before the warm reads load the files into the page cache.
+	var cp *CopyStats
+	if d.Copy {
+		if cp, err = d.runCopy(ctx, path, files); err != nil {
+			return &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
+		}
+	}
+
+	var warm *WarmRead
+	if d.WarmRead && !d.WriteOnly && d.ReadMix == 0 {
+		if warm, err = d.runWarmRead(ctx, path, files); err != nil {
+			return &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
 		}
 	}
 
 	return &DrivePerfResult{
-		Path:            path,
-		ReadThroughput:  readThroughput,
-		WriteThroughput: writeThroughput,
+		Path:                 path,
+		ReadThroughput:       read.throughput,
+		WriteThroughput:      write.throughput,
+		ReadIOPS:             read.iops,
+		WriteIOPS:            write.iops,
+		WriteOps:             write.ops,
+		ReadOps:              read.ops,
+		WriteElapsed:         write.elapsed,
+		ReadElapsed:          read.elapsed,
+		WriteWorkers:         write.workers,
+		ReadWorkers:          read.workers,
+		WriteLatency:         write.latencyStats(),
+		SyncLatency:          newLatencyStats(write.syncLatency),
+		ReadLatency:          read.latencyStats(),
+		WriteSubmitLatency:   newLatencyStats(write.submitLatency),
+		WriteCompleteLatency: newLatencyStats(write.completeLatency),
+		ReadSubmitLatency:    newLatencyStats(read.submitLatency),
+		ReadCompleteLatency:  newLatencyStats(read.completeLatency),
+		Outliers:             d.latencyOutliers(write, read),
+		SlowestOps:           d.slowestOps(write, read),
+		Regions:              append(write.regions.stats(PhaseWrite), read.regions.stats(PhaseRead)...),
+		Zones:                append(write.zones.stats(PhaseWrite, d.zones), read.zones.stats(PhaseRead, d.zones)...),
+		writeHist:            write.latency,
+		readHist:             read.latency,
+		TotalBytesWritten:    write.bytes,
+		TotalBytesRead:       read.bytes,
+		Wear:                 driveWear(wearBefore, wearSnapshot(path), written),
+		QueueDepth:           queueDepth,
+		Temperature:          temperature,
+		DiskStats:            diskStats(statsBefore, snapshotDiskStats(path)),
+		WarmRead:             warm,
+		Copy:                 cp,
+		Verify:               read.verify,
+		Error:                read.verify.err(),
+	}
+}
+
+// runDrive - tests a single drive wrapped by the PreRun and PostRun hooks.
+func (d *DrivePerf) runDrive(ctx context.Context, path string, testUUID string) (dr *DrivePerfResult) {
+	if d.PreRun != nil {
+		if err := d.PreRun(ctx, path); err != nil {
+			dr = &DrivePerfResult{
+				Path:  path,
+				Error: fmt.Errorf("pre-run hook failed: %w", err),
+			}
+		}
 	}
+	if dr == nil {
+		dd, err := d.forDrive(path)
+		if err != nil {
+			dr = &DrivePerfResult{
+				Path:  path,
+				Error: err,
+			}
+		} else {
+			dr = dd.runPoll(ctx, path, testUUID)
+			if d.FilePercent > 0 {
+				dr.FileSize = dd.FileSize
+			}
+		}
+	}
+	if dr.Temperature != nil && !d.Series {
+		dr.Temperature.Samples = nil
+	}
+	dr.Model = driveModel(path)
+	dr.Zoned = zonedModel(path)
+	dr.Spec = d.specResult(dr)
+	if d.PostRun != nil {
+		d.PostRun(ctx, dr)
+	}
+	return dr
 }
 
 // Run drive performance
@@ -134,11 +802,28 @@ func (d *DrivePerf) Run(ctx context.Context, paths ...string) (results []*DriveP
 		}
 	}()
 
+	if d.MaxWrite > 0 {
+		if d.UnboundedWrite() {
+			return nil, fmt.Errorf("%w: the timed phases write until their deadline", ErrWriteBudgetExceeded)
+		}
+		planned, err := d.plannedWrite(paths)
+		if err != nil {
+			return nil, err
+		}
+		if planned > d.MaxWrite {
+			return nil, ErrWriteBudgetExceeded
+		}
+	}
+
+	if d.Timeline != nil {
+		defer d.Timeline.sample()()
+	}
+
 	uuidStr := mustGetUUID()
 	results = make([]*DrivePerfResult, len(paths))
 	if d.Serial {
 		for i, path := range paths {
-			results[i] = d.runTests(childCtx, path, uuidStr)
+			results[i] = d.runDrive(childCtx, path, uuidStr)
 		}
 		return results, nil
 	}
@@ -152,7 +837,7 @@ func (d *DrivePerf) Run(ctx context.Context, paths ...string) (results []*DriveP
 	for i, path := range paths {
 		go func(idx int, path string) {
 			defer wg.Done()
-			results[idx] = d.runTests(childCtx, path, uuidStr)
+			results[idx] = d.runDrive(childCtx, path, uuidStr)
 		}(i, path)
 	}
 	wg.Wait()
@@ -160,17 +845,60 @@ func (d *DrivePerf) Run(ctx context.Context, paths ...string) (results []*DriveP
 	return results, nil
 }
 
-// Run drive performance and render it
+// Run drive performance and render it, fails with ErrAllDrivesFailed
+// once rendered if the tests failed on every drive.
 func (d *DrivePerf) RunAndRender(ctx context.Context, paths ...string) error {
-	results, err := d.Run(ctx, paths...)
+	if d.Soak > 0 {
+		return d.soakAndRender(ctx, paths...)
+	}
+	report, err := d.runReport(ctx, paths...)
 	if err != nil {
 		return err
 	}
+	if err = d.render(report); err != nil {
+		return err
+	}
+	if d.Publish != nil {
+		if err = d.Publish(report); err != nil {
+			return err
+		}
+	}
+	if err = d.Thresholds.check(report); err != nil {
+		return err
+	}
+	return report.allFailed()
+}
 
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].ReadThroughput > results[j].ReadThroughput
-	})
+// runReport - runs the tests against paths and returns their report.
+func (d *DrivePerf) runReport(ctx context.Context, paths ...string) (*Report, error) {
+	cpuBefore := cpuSnapshot()
+	start := time.Now()
+	results, err := d.Run(ctx, paths...)
+	if err != nil {
+		return nil, err
+	}
+	elapsed := time.Since(start)
+	cpu := cpuUsage(cpuBefore, cpuSnapshot())
+
+	if d.Score != nil {
+		scoreDrives(results, *d.Score)
+	}
+	sortResults(results, d.Score != nil)
+	if d.Timeline != nil {
+		points := d.Timeline.Points()
+		for _, result := range results {
+			if result.Error != nil {
+				continue
+			}
+			result.Stability = newStability(points[result.Path], d.maxDegradation())
+			if d.Series {
+				result.Series = points[result.Path]
+			}
+		}
+	}
 
-	d.render(results)
-	return nil
+	report := d.newReport(results)
+	report.CPU = cpu
+	report.Elapsed = elapsed
+	return report, nil
 }
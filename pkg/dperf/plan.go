@@ -0,0 +1,243 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"time"
+)
+
+// DrivePlan describes what a run would do against a path
+type DrivePlan struct {
+	Path  string
+	Files []string
+	// FileSize of each test file, 0 in read-only mode where existing files are read.
+	FileSize   uint64
+	TotalWrite uint64
+	TotalRead  uint64
+	// Unbounded is set when the writes go on until the deadline of timed
+	// phases, TotalWrite is then only what the run writes at least.
+	Unbounded bool
+	// Unnamed is set when the test files are created with O_TMPFILE and
+	// reopened through /proc/self/fd, they are named files in a <uuid>
+	// directory where the filesystem does not support it.
+	Unnamed      bool
+	BufferMemory uint64
+	// EstimatedDuration is a rough guess based on the kind of drive.
+	EstimatedDuration time.Duration
+	Error             error
+}
+
+// Plan returns what a run against paths would do, without touching the drives.
+func (d *DrivePerf) Plan(paths ...string) []*DrivePlan {
+	plans := make([]*DrivePlan, 0, len(paths))
+	for _, path := range paths {
+		dd, err := d.forDrive(path)
+		if err != nil {
+			plans = append(plans, &DrivePlan{Path: path, Error: err})
+			continue
+		}
+		plans = append(plans, dd.planDrive(path))
+	}
+	return plans
+}
+
+// planDrive - what a run would do against the drive at path.
+func (d *DrivePerf) planDrive(path string) *DrivePlan {
+	plan := &DrivePlan{
+		Path:         path,
+		FileSize:     d.FileSize,
+		BufferMemory: d.BlockSize * uint64(d.IOPerDrive),
+	}
+	device := isBlockDevice(path)
+	if device {
+		dd, err := d.checkDevice(path)
+		if err != nil {
+			plan.Error = err
+			return plan
+		}
+		d = dd
+	} else if d.Engine == EngineNVMe {
+		plan.Error = errNVMeEngine
+		return plan
+	}
+
+	if d.ReadOnly {
+		files, err := d.existingFiles(path)
+		if err != nil {
+			plan.Error = err
+			return plan
+		}
+		for i := 0; i < d.IOPerDrive; i++ {
+			f := files[i%len(files)]
+			plan.Files = append(plan.Files, f)
+			if size, err := d.readSize(f); err == nil {
+				plan.TotalRead += size
+			}
+		}
+		plan.FileSize = 0
+	} else {
+		dir := "<uuid>"
+		if d.KeepFiles || d.Reuse {
+			dir = keepDir
+		}
+		if d.Reuse && !device {
+			dd, err := d.reuseFiles(path)
+			if err != nil {
+				plan.Error = err
+				return plan
+			}
+			d = dd
+			plan.FileSize = d.FileSize
+		}
+		if device {
+			plan.Files = d.deviceFiles(path)
+		} else if dir != keepDir && d.unnamedFiles() {
+			for i := 0; i < d.IOPerDrive; i++ {
+				plan.Files = append(plan.Files, tmpFilePath(path, i))
+			}
+			plan.Unnamed = true
+		} else {
+			for i := 0; i < d.IOPerDrive; i++ {
+				plan.Files = append(plan.Files, d.workerFiles(testFilePath(path, dir, i))...)
+			}
+			plan.FileSize /= uint64(max(d.FilesPerIO, 1))
+		}
+		plan.TotalWrite = d.PlannedWrite(1)
+		plan.Unbounded = d.UnboundedWrite()
+		if d.Fill > 0 {
+			target, _, _, err := d.fillBytes(path)
+			if err != nil {
+				plan.Error = err
+				return plan
+			}
+			plan.TotalWrite = target
+		}
+		if !d.WriteOnly {
+			plan.TotalRead = d.FileSize * uint64(d.IOPerDrive)
+			if d.ReadMix > 0 {
+				plan.TotalRead = plan.TotalRead * uint64(d.ReadMix) / 100
+			}
+		}
+		if d.Copy {
+			// The copies read the files again.
+			plan.TotalRead += d.FileSize * uint64(d.IOPerDrive)
+		}
+	}
+	runs := max(d.Runs, 1)
+	if d.Access == AccessBoth {
+		runs *= 2
+	}
+	if d.Poll {
+		runs *= 2
+	}
+	plan.TotalRead *= uint64(runs)
+
+	bps := assumedThroughput(path)
+	if d.Rate > 0 {
+		bps = min(bps, d.Rate)
+	}
+	plan.EstimatedDuration = time.Duration(float64(plan.TotalWrite+plan.TotalRead) / float64(bps) * float64(time.Second))
+	if d.Duration > 0 {
+		phases := 2
+		if d.ReadOnly || d.WriteOnly {
+			phases = 1
+		}
+		plan.EstimatedDuration = max(plan.EstimatedDuration, time.Duration(phases*runs)*d.Duration)
+	}
+	if d.Ramp > 0 {
+		// The files are rewritten until the ramp ends.
+		plan.TotalWrite = uint64(float64(bps) * d.Ramp.Seconds())
+		plan.TotalRead = 0
+		plan.EstimatedDuration = d.Ramp
+	}
+	return plan
+}
+
+// PlanAndRender prints what a run against paths would do.
+func (d *DrivePerf) PlanAndRender(paths ...string) error {
+	plans := d.Plan(paths...)
+
+	cellText := [][]string{{
+		"PATH",
+		"FILES",
+		"WRITE",
+		"READ",
+		"MEMORY",
+		"DURATION",
+		"",
+	}}
+	var totalWrite, totalMemory uint64
+	var duration time.Duration
+	var unbounded bool
+	for _, plan := range plans {
+		status := "✓"
+		if plan.Error != nil {
+			status = plan.Error.Error()
+		}
+		files := fmt.Sprintf("%d x %s", len(plan.Files), FormatBytes(plan.FileSize))
+		if d.ReadOnly {
+			files = fmt.Sprintf("%d existing", len(plan.Files))
+		}
+		write := FormatBytes(plan.TotalWrite)
+		if plan.Unbounded {
+			write = ">= " + write
+			unbounded = true
+		}
+		cellText = append(cellText, []string{
+			plan.Path,
+			files,
+			write,
+			FormatBytes(plan.TotalRead),
+			FormatBytes(plan.BufferMemory),
+			"~" + plan.EstimatedDuration.Round(time.Second).String(),
+			status,
+		})
+		totalWrite += plan.TotalWrite
+		if d.Serial {
+			totalMemory = max(totalMemory, plan.BufferMemory)
+			duration += plan.EstimatedDuration
+		} else {
+			totalMemory += plan.BufferMemory
+			duration = max(duration, plan.EstimatedDuration)
+		}
+	}
+
+	w := d.out()
+	if err := displayTable(w, cellText); err != nil {
+		return err
+	}
+
+	var unnamed bool
+	for _, plan := range plans {
+		for _, f := range plan.Files {
+			fmt.Fprintln(w, f)
+		}
+		unnamed = unnamed || plan.Unnamed
+	}
+	if unnamed {
+		fmt.Fprintln(w, "\nO_TMPFILE files are unnamed and freed once closed, they are PATH/<uuid>/.writable-check.tmp-N"+
+			" where the filesystem does not support them")
+	}
+	writes := FormatBytes(totalWrite)
+	if unbounded {
+		writes = "at least " + writes + ", unbounded as the phases are timed"
+	}
+	fmt.Fprintf(w, "\nTotal writes: %s, buffer memory: %s, estimated duration: ~%s\n",
+		writes, FormatBytes(totalMemory), duration.Round(time.Second))
+	return nil
+}
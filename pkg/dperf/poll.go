@@ -0,0 +1,110 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// Polling modes of a block device, see DrivePerfResult.PollMode.
+const (
+	// PollModeOff - the device has no poll queues, polled I/O completes
+	// on interrupts as any other.
+	PollModeOff = "off"
+	// PollModeClassic - the kernel busy-polls for the completions.
+	PollModeClassic = "classic"
+	// PollModeHybrid - the kernel sleeps for about half the expected
+	// latency before it busy-polls.
+	PollModeHybrid = "hybrid"
+)
+
+// runPoll - tests the drive at path, with Poll the tests run again with
+// RWF_HIPRI back to back, the result is that of the tests completing
+// on interrupts with those polling for completions as Polled.
+func (d *DrivePerf) runPoll(ctx context.Context, path string, testUUID string) *DrivePerfResult {
+	if !d.Poll {
+		return d.runAccess(ctx, path, testUUID)
+	}
+	polled := *d
+	polled.RWFlags = append(slices.Clone(d.RWFlags), RWFlagHipri)
+	dr := d.runAccess(ctx, path, testUUID)
+	if dr.Error != nil {
+		return dr
+	}
+	dr.PollMode = pollMode(path)
+	dr.Polled = polled.runAccess(ctx, path, testUUID)
+	if dr.Polled.Error != nil {
+		dr.Error = fmt.Errorf("polled: %w", dr.Polled.Error)
+	}
+	return dr
+}
+
+// pollCells - IOPS and latency of the blocks of every drive tested
+// completing on interrupts and polled, by phase, the first row is the
+// header.
+func (r *Report) pollCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"PHASE",
+		"POLL MODE",
+		"IOPS",
+		"POLLED IOPS",
+		"P50",
+		"POLLED P50",
+		"P99",
+		"POLLED P99",
+		"P50 CHANGE",
+	}}
+	for _, result := range r.Results {
+		polled := result.Polled
+		if polled == nil {
+			continue
+		}
+		mode := result.PollMode
+		if mode == "" {
+			mode = "-"
+		}
+		for _, phase := range []struct {
+			name           Phase
+			iops, pollIOPS uint64
+			lat, pollLat   *LatencyStats
+		}{
+			{PhaseWrite, result.WriteIOPS, polled.WriteIOPS, result.WriteLatency, polled.WriteLatency},
+			{PhaseRead, result.ReadIOPS, polled.ReadIOPS, result.ReadLatency, polled.ReadLatency},
+		} {
+			if phase.lat == nil || phase.pollLat == nil {
+				continue
+			}
+			cellText = append(cellText, []string{
+				result.Path,
+				string(phase.name),
+				mode,
+				strconv.FormatUint(phase.iops, 10),
+				strconv.FormatUint(phase.pollIOPS, 10),
+				formatLatency(phase.lat.P50),
+				formatLatency(phase.pollLat.P50),
+				formatLatency(phase.lat.P99),
+				formatLatency(phase.pollLat.P99),
+				trend(uint64(phase.lat.P50), uint64(phase.pollLat.P50)),
+			})
+		}
+	}
+	return cellText
+}
@@ -0,0 +1,207 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase of a drive test
+type Phase string
+
+// Test phases
+const (
+	PhaseWrite Phase = "write"
+	PhaseRead  Phase = "read"
+	// PhaseMixed reads and writes the files at once, see DrivePerf.ReadMix.
+	PhaseMixed Phase = "mixed"
+)
+
+// ProgressUpdate progress of a single I/O worker within a phase
+type ProgressUpdate struct {
+	Path    string `json:"path"`
+	Phase   Phase  `json:"phase"`
+	IOIndex int    `json:"io"`
+	// Bytes transferred so far out of Total, which is projected from the
+	// time left in timed phases.
+	Bytes uint64 `json:"bytes"`
+	Total uint64 `json:"total"`
+	// Throughput is the average since the start of the phase in bytes/sec.
+	Throughput uint64 `json:"throughput"`
+}
+
+// ioProgress - reports the progress of a single I/O worker.
+type ioProgress struct {
+	fn     func(ProgressUpdate)
+	update ProgressUpdate
+	start  time.Time
+	// duration of a timed phase.
+	duration time.Duration
+	// counter if set counts the bytes for the samples of Timeline.
+	counter *workerBytes
+}
+
+// workerBytes - the bytes moved by an I/O worker in a phase, counted
+// without a lock and read by the sampler of Timeline.
+type workerBytes struct {
+	bytes atomic.Uint64
+	// total is the bytes of the phase, unknown until done if timed.
+	total uint64
+	timed bool
+	done  atomic.Bool
+}
+
+// newProgress - returns the progress reporter of an I/O worker, nil if
+// nobody is listening for progress and no Timeline is sampled.
+func (d *DrivePerf) newProgress(path string, phase Phase, idx int, total uint64) *ioProgress {
+	if d.Progress == nil && d.Timeline == nil {
+		return nil
+	}
+	p := &ioProgress{
+		fn: d.Progress,
+		update: ProgressUpdate{
+			Path:    path,
+			Phase:   phase,
+			IOIndex: idx,
+			Total:   total,
+		},
+		start:    time.Now(),
+		duration: d.Duration,
+	}
+	if d.Timeline != nil {
+		p.counter = d.Timeline.worker(path, phase, idx, total, d.Duration > 0)
+	}
+	return p
+}
+
+func (p *ioProgress) add(n int) {
+	if p.counter != nil {
+		p.counter.bytes.Add(uint64(n))
+	}
+	if p.fn == nil {
+		return
+	}
+	p.update.Bytes += uint64(n)
+	if dt := time.Since(p.start); dt > 0 {
+		p.update.Throughput = uint64(float64(p.update.Bytes) / dt.Seconds())
+		if p.duration > 0 {
+			// Timed phases move as many bytes as they can, the total
+			// is projected from the time left.
+			p.update.Total = max(p.update.Bytes, uint64(float64(p.update.Bytes)*p.duration.Seconds()/dt.Seconds()))
+		}
+	}
+	p.fn(p.update)
+}
+
+// finish - reports the end of a timed phase, whose total is only known
+// once it is over.
+func (p *ioProgress) finish() {
+	if p == nil {
+		return
+	}
+	if p.counter != nil {
+		p.counter.done.Store(true)
+	}
+	if p.fn == nil || p.update.Total == p.update.Bytes {
+		return
+	}
+	p.update.Total = p.update.Bytes
+	p.fn(p.update)
+}
+
+// writer - wraps w to report the bytes written through it.
+func (p *ioProgress) writer(w io.Writer) io.Writer {
+	if p == nil {
+		return w
+	}
+	return &progressWriter{w: w, p: p}
+}
+
+type progressWriter struct {
+	w io.Writer
+	p *ioProgress
+}
+
+func (pw *progressWriter) Write(b []byte) (int, error) {
+	n, err := pw.w.Write(b)
+	if n > 0 {
+		pw.p.add(n)
+	}
+	return n, err
+}
+
+// DriveProgress aggregated progress of all I/O workers of a drive
+type DriveProgress struct {
+	Path       string `json:"path"`
+	Phase      Phase  `json:"phase"`
+	Bytes      uint64 `json:"bytes"`
+	Total      uint64 `json:"total"`
+	Throughput uint64 `json:"throughput"`
+}
+
+// ProgressTracker aggregates progress updates per drive, it is safe
+// for concurrent use and its Update method can be used as DrivePerf.Progress.
+type ProgressTracker struct {
+	mu     sync.Mutex
+	drives map[string]*driveProgress
+}
+
+type driveProgress struct {
+	phase   Phase
+	workers map[int]ProgressUpdate
+}
+
+// Update records a progress update.
+func (t *ProgressTracker) Update(u ProgressUpdate) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.drives == nil {
+		t.drives = make(map[string]*driveProgress)
+	}
+	dp, ok := t.drives[u.Path]
+	if !ok || dp.phase != u.Phase {
+		dp = &driveProgress{phase: u.Phase, workers: make(map[int]ProgressUpdate)}
+		t.drives[u.Path] = dp
+	}
+	dp.workers[u.IOIndex] = u
+}
+
+// Snapshot returns the current progress of every drive sorted by path.
+func (t *ProgressTracker) Snapshot() []DriveProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	snapshot := make([]DriveProgress, 0, len(t.drives))
+	for path, dp := range t.drives {
+		p := DriveProgress{Path: path, Phase: dp.phase}
+		for _, u := range dp.workers {
+			p.Bytes += u.Bytes
+			p.Total += u.Total
+			p.Throughput += u.Throughput
+		}
+		snapshot = append(snapshot, p)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		return snapshot[i].Path < snapshot[j].Path
+	})
+	return snapshot
+}
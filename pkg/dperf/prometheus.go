@@ -0,0 +1,249 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WritePrometheus writes the results in the Prometheus text exposition
+// format, as expected by the node_exporter textfile collector. Tags are
+// added as labels to every sample.
+func (r *Report) WritePrometheus(w io.Writer) error {
+	pw := newPromWriter(w, r.Tags)
+
+	pw.family("dperf_write_bytes_per_second", "gauge", "Write throughput of the drive.")
+	for _, result := range r.Results {
+		if result.Error == nil {
+			pw.sample("dperf_write_bytes_per_second", float64(result.WriteThroughput), "path", result.Path)
+		}
+	}
+	pw.family("dperf_read_bytes_per_second", "gauge", "Read throughput of the drive.")
+	for _, result := range r.Results {
+		if result.Error == nil {
+			pw.sample("dperf_read_bytes_per_second", float64(result.ReadThroughput), "path", result.Path)
+		}
+	}
+	pw.family("dperf_write_iops", "gauge", "Write operations per second of the drive.")
+	for _, result := range r.Results {
+		if result.Error == nil {
+			pw.sample("dperf_write_iops", float64(result.WriteIOPS), "path", result.Path)
+		}
+	}
+	pw.family("dperf_read_iops", "gauge", "Read operations per second of the drive.")
+	for _, result := range r.Results {
+		if result.Error == nil {
+			pw.sample("dperf_read_iops", float64(result.ReadIOPS), "path", result.Path)
+		}
+	}
+	pw.family("dperf_write_latency_seconds", "gauge", "Write latency percentiles of the drive.")
+	for _, result := range r.Results {
+		if result.Error == nil {
+			pw.latency("dperf_write_latency_seconds", result.WriteLatency, result.Path)
+		}
+	}
+	pw.family("dperf_read_latency_seconds", "gauge", "Read latency percentiles of the drive.")
+	for _, result := range r.Results {
+		if result.Error == nil {
+			pw.latency("dperf_read_latency_seconds", result.ReadLatency, result.Path)
+		}
+	}
+	pw.family("dperf_sync_latency_seconds", "gauge", "Latency percentiles of fdatasync on the drive.")
+	for _, result := range r.Results {
+		if result.Error == nil {
+			pw.latency("dperf_sync_latency_seconds", result.SyncLatency, result.Path)
+		}
+	}
+	pw.family("dperf_metadata_ops_per_second", "gauge", "Metadata operations per second of the drive.")
+	for _, result := range r.Results {
+		for _, m := range result.Metadata {
+			pw.sample("dperf_metadata_ops_per_second", float64(m.OpsPerSec), "path", result.Path, "op", m.Op)
+		}
+	}
+	pw.family("dperf_objects_per_second", "gauge", "Small objects written or read per second on the drive.")
+	for _, result := range r.Results {
+		for _, o := range result.Objects {
+			pw.sample("dperf_objects_per_second", float64(o.ObjectsPerSec), "path", result.Path, "phase", string(o.Phase))
+		}
+	}
+	pw.family("dperf_corrupt_blocks", "gauge", "4KiB blocks of the drive read back different from written.")
+	for _, result := range r.Results {
+		if result.Verify != nil {
+			pw.sample("dperf_corrupt_blocks", float64(result.Verify.Corrupt), "path", result.Path)
+		}
+	}
+	pw.family("dperf_written_bytes", "gauge", "Bytes written to the drive by the run.")
+	for _, result := range r.Results {
+		pw.sample("dperf_written_bytes", float64(result.TotalBytesWritten), "path", result.Path)
+	}
+	pw.family("dperf_host_write_ratio", "gauge", "Host writes the NVMe drive accounted for per byte written by the run.")
+	for _, result := range r.Results {
+		if result.Wear != nil && result.Wear.HostWriteRatio > 0 {
+			pw.sample("dperf_host_write_ratio", result.Wear.HostWriteRatio, "path", result.Path)
+		}
+	}
+	pw.family("dperf_temperature_max_celsius", "gauge", "Highest temperature of the drive during the run.")
+	for _, result := range r.Results {
+		if result.Temperature != nil {
+			pw.sample("dperf_temperature_max_celsius", result.Temperature.Max, "path", result.Path)
+		}
+	}
+	pw.family("dperf_read_bytes", "gauge", "Bytes read from the drive by the run.")
+	for _, result := range r.Results {
+		pw.sample("dperf_read_bytes", float64(result.TotalBytesRead), "path", result.Path)
+	}
+	pw.family("dperf_operations", "gauge", "Block operations completed on the drive by the run.")
+	for _, result := range r.Results {
+		pw.sample("dperf_operations", float64(result.WriteOps), "path", result.Path, "phase", string(PhaseWrite))
+		pw.sample("dperf_operations", float64(result.ReadOps), "path", result.Path, "phase", string(PhaseRead))
+	}
+	pw.family("dperf_phase_duration_seconds", "gauge", "Duration of the phases of the run on the drive.")
+	for _, result := range r.Results {
+		pw.sample("dperf_phase_duration_seconds", result.WriteElapsed.Seconds(), "path", result.Path, "phase", string(PhaseWrite))
+		pw.sample("dperf_phase_duration_seconds", result.ReadElapsed.Seconds(), "path", result.Path, "phase", string(PhaseRead))
+	}
+	pw.family("dperf_score", "gauge", "Composite score of the drive from 0 to 100.")
+	for _, result := range r.Results {
+		if result.Score != nil {
+			pw.sample("dperf_score", *result.Score, "path", result.Path)
+		}
+	}
+	pw.family("dperf_drive_failed", "gauge", "Whether testing the drive failed.")
+	for _, result := range r.Results {
+		var failed float64
+		if result.Error != nil {
+			failed = 1
+		}
+		pw.sample("dperf_drive_failed", failed, "path", result.Path)
+	}
+	return pw.flush()
+}
+
+// WriteOpenMetrics writes the current progress of every drive in the
+// OpenMetrics text format. Tags are added as labels to every sample.
+func (t *ProgressTracker) WriteOpenMetrics(w io.Writer, tags map[string]string) error {
+	drives := t.Snapshot()
+	pw := newPromWriter(w, tags)
+
+	pw.family("dperf_progress_bytes_per_second", "gauge", "Throughput of the drive in the current phase.")
+	for _, p := range drives {
+		pw.sample("dperf_progress_bytes_per_second", float64(p.Throughput), "path", p.Path, "phase", string(p.Phase))
+	}
+	pw.family("dperf_progress_bytes", "gauge", "Bytes transferred in the current phase.")
+	for _, p := range drives {
+		pw.sample("dperf_progress_bytes", float64(p.Bytes), "path", p.Path, "phase", string(p.Phase))
+	}
+	pw.family("dperf_progress_total_bytes", "gauge", "Bytes to transfer in the current phase.")
+	for _, p := range drives {
+		pw.sample("dperf_progress_total_bytes", float64(p.Total), "path", p.Path, "phase", string(p.Phase))
+	}
+	pw.printf("# EOF\n")
+	return pw.flush()
+}
+
+// promWriter - writes metric families and samples in the Prometheus text
+// format, which OpenMetrics is compatible with for gauges. The first error
+// is kept and returned by flush.
+type promWriter struct {
+	bw *bufio.Writer
+	// labels added to every sample as name/value pairs.
+	labels []string
+	err    error
+}
+
+func newPromWriter(w io.Writer, tags map[string]string) *promWriter {
+	pw := &promWriter{bw: bufio.NewWriter(w)}
+	for _, k := range sortedKeys(tags) {
+		name := promLabelName(k)
+		// Do not let tags shadow the labels set by dperf.
+		if name == "path" || name == "phase" || name == "quantile" || name == "op" {
+			continue
+		}
+		pw.labels = append(pw.labels, name, tags[k])
+	}
+	return pw
+}
+
+// latency - one sample per percentile of l, labeled with the quantile.
+func (pw *promWriter) latency(name string, l *LatencyStats, path string) {
+	if l == nil {
+		return
+	}
+	for _, q := range l.Percentiles() {
+		pw.sample(name, q.Latency.Seconds(), "path", path, "quantile", q.Quantile)
+	}
+}
+
+func (pw *promWriter) family(name, typ, help string) {
+	pw.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample - writes a sample of the metric name, labels are name/value pairs.
+func (pw *promWriter) sample(name string, value float64, labels ...string) {
+	labels = append(labels, pw.labels...)
+	var sb strings.Builder
+	sb.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i == 0 {
+			sb.WriteByte('{')
+		} else {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(labels[i])
+		sb.WriteString(`="`)
+		sb.WriteString(promEscaper.Replace(labels[i+1]))
+		sb.WriteByte('"')
+	}
+	if len(labels) > 1 {
+		sb.WriteByte('}')
+	}
+	pw.printf("%s %s\n", sb.String(), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (pw *promWriter) printf(format string, args ...interface{}) {
+	if pw.err == nil {
+		_, pw.err = fmt.Fprintf(pw.bw, format, args...)
+	}
+}
+
+func (pw *promWriter) flush() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.bw.Flush()
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// promLabelName - maps a tag key to a valid label name, invalid characters
+// are replaced with '_'.
+func promLabelName(k string) string {
+	b := []byte(k)
+	for i, c := range b {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
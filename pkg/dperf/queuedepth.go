@@ -0,0 +1,57 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// QueueDepth requests in flight on the block device of a drive, sampled
+// from sysfs while the drive was tested
+type QueueDepth struct {
+	// Device is the name of the block device, e.g. "nvme0n1".
+	Device string  `json:"device"`
+	Avg    float64 `json:"avg"`
+	Max    uint64  `json:"max"`
+}
+
+// queueDepthCells - effective queue depth of every drive next to the
+// concurrent I/O it was tested with, the first row is the header.
+func (r *Report) queueDepthCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"DEVICE",
+		"IOPERDRIVE",
+		"AVG QD",
+		"MAX QD",
+	}}
+	for _, result := range r.Results {
+		qd := result.QueueDepth
+		if qd == nil {
+			continue
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			qd.Device,
+			strconv.Itoa(r.Config.IOPerDrive),
+			fmt.Sprintf("%.1f", qd.Avg),
+			strconv.FormatUint(qd.Max, 10),
+		})
+	}
+	return cellText
+}
@@ -0,0 +1,117 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queueDepthInterval - interval between two samples of the in-flight requests.
+const queueDepthInterval = 10 * time.Millisecond
+
+// queueDepthSampler - samples the requests in flight on a block device
+// until stopped.
+type queueDepthSampler struct {
+	dev  *blockDev
+	stop chan struct{}
+	done chan struct{}
+
+	sum, samples, max uint64
+}
+
+// sampleQueueDepth - starts sampling the block device backing path,
+// returns nil if it has none.
+func sampleQueueDepth(path string) *queueDepthSampler {
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return nil
+	}
+	if _, err = dev.inflight(); err != nil {
+		return nil
+	}
+	s := &queueDepthSampler{
+		dev:  dev,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *queueDepthSampler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(queueDepthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			n, err := s.dev.inflight()
+			if err != nil {
+				continue
+			}
+			s.sum += n
+			s.samples++
+			s.max = max(s.max, n)
+		}
+	}
+}
+
+// result - stops sampling and returns the queue depth, nil if no sample
+// was taken.
+func (s *queueDepthSampler) result() *QueueDepth {
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	if s.samples == 0 {
+		return nil
+	}
+	return &QueueDepth{
+		Device: s.dev.name,
+		Avg:    float64(s.sum) / float64(s.samples),
+		Max:    s.max,
+	}
+}
+
+// inflight - reads the number of read and write requests in flight on
+// the device, see Documentation/ABI/stable/sysfs-block.
+func (b *blockDev) inflight() (uint64, error) {
+	s, err := readSysfsString(filepath.Join("/sys/class/block", b.name, "inflight"))
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0, fmt.Errorf("unexpected inflight format for %s: %q", b.name, s)
+	}
+	var total uint64
+	for _, f := range fields {
+		v, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		total += v
+	}
+	return total, nil
+}
@@ -0,0 +1,210 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rampSaturation - fraction of the peak throughput from which a drive is
+// considered saturated.
+const rampSaturation = 0.95
+
+// RampPoint write throughput of a drive with Depth I/O workers in flight
+type RampPoint struct {
+	Depth      int    `json:"depth"`
+	Throughput uint64 `json:"throughput"`
+}
+
+// rampTracker - counts the bytes written by the workers of a drive in
+// ramp mode by depth, a worker starts every step.
+type rampTracker struct {
+	start time.Time
+	step  time.Duration
+	bytes []atomic.Uint64
+}
+
+// add - counts n more bytes written at the current depth.
+func (t *rampTracker) add(n int) {
+	if i := int(time.Since(t.start) / t.step); i < len(t.bytes) {
+		t.bytes[i].Add(uint64(n))
+	}
+}
+
+// points - the throughput of every depth.
+func (t *rampTracker) points() []RampPoint {
+	points := make([]RampPoint, len(t.bytes))
+	for i := range t.bytes {
+		points[i] = RampPoint{
+			Depth:      i + 1,
+			Throughput: uint64(float64(t.bytes[i].Load()) / t.step.Seconds()),
+		}
+	}
+	return points
+}
+
+// rampReader - the data written by a worker in ramp mode.
+type rampReader struct {
+	r io.Reader
+	t *rampTracker
+	n uint64
+}
+
+func (r *rampReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.n += uint64(n)
+	r.t.add(n)
+	return n, err
+}
+
+// saturation - the lowest depth of points reaching rampSaturation of the
+// peak throughput, 0 if nothing was written.
+func saturation(points []RampPoint) int {
+	var peak uint64
+	for _, p := range points {
+		peak = max(peak, p.Throughput)
+	}
+	for _, p := range points {
+		if peak > 0 && float64(p.Throughput) >= float64(peak)*rampSaturation {
+			return p.Depth
+		}
+	}
+	return 0
+}
+
+// runRampTest - writes the test files of the drive at path with one I/O
+// worker in flight at first and another started every Ramp/IOPerDrive,
+// until IOPerDrive are and Ramp elapsed. Every worker rewrites its file
+// until then. Nothing is read.
+func (d *DrivePerf) runRampTest(ctx context.Context, path, testUUID string) *DrivePerfResult {
+	files, remove := d.testFiles(path, testUUID)
+	defer remove()
+
+	t := &rampTracker{
+		start: time.Now(),
+		step:  d.Ramp / time.Duration(d.IOPerDrive),
+		bytes: make([]atomic.Uint64, d.IOPerDrive),
+	}
+	end := t.start.Add(d.Ramp)
+	results := make([]ioResult, d.IOPerDrive)
+	errs := make([]error, d.IOPerDrive)
+
+	wearBefore := wearSnapshot(path)
+	statsBefore := snapshotDiskStats(path)
+	temp := sampleTemperature(path)
+
+	var wg sync.WaitGroup
+	wg.Add(d.IOPerDrive)
+	for i := 0; i < d.IOPerDrive; i++ {
+		go func(idx int) {
+			defer wg.Done()
+			timer := time.NewTimer(time.Until(t.start.Add(time.Duration(idx) * t.step)))
+			defer timer.Stop()
+			select {
+			case <-ctx.Done():
+				errs[idx] = ctx.Err()
+				return
+			case <-timer.C:
+			}
+
+			w := *d
+			w.Duration = time.Until(end)
+			start := time.Now()
+			data := alignedBlock(int(d.BlockSize))
+			stats := w.newIOStats(path, PhaseWrite, idx, d.FileSize)
+			src := &rampReader{r: untilDeadline(w.newDataReader(idx), end), t: t}
+			if _, err := w.runWriteTest(ctx, files[idx], data, src, stats, w.newProgress(path, PhaseWrite, idx, d.FileSize)); err != nil {
+				errs[idx] = err
+				return
+			}
+			results[idx] = stats.result(src.n, time.Since(start))
+		}(i)
+	}
+	wg.Wait()
+	temperature := temp.result()
+
+	for _, err := range errs {
+		if err != nil {
+			return &DrivePerfResult{Path: path, Error: fmt.Errorf("ramp: %w", err)}
+		}
+	}
+
+	points := t.points()
+	write := phaseResults(results)
+	// The workers ran for different times, the throughput and IOPS are
+	// those of the drive over the whole ramp.
+	return &DrivePerfResult{
+		Path:              path,
+		WriteThroughput:   uint64(float64(write.bytes) / d.Ramp.Seconds()),
+		WriteIOPS:         uint64(float64(write.ops) / d.Ramp.Seconds()),
+		WriteOps:          write.ops,
+		WriteElapsed:      write.elapsed,
+		WriteWorkers:      write.workers,
+		WriteLatency:      write.latencyStats(),
+		SyncLatency:       newLatencyStats(write.syncLatency),
+		Outliers:          d.latencyOutliers(write),
+		SlowestOps:        d.slowestOps(write),
+		writeHist:         write.latency,
+		TotalBytesWritten: write.bytes,
+		Ramp:              points,
+		Saturation:        saturation(points),
+		Wear:              driveWear(wearBefore, wearSnapshot(path), write.bytes),
+		Temperature:       temperature,
+		DiskStats:         diskStats(statsBefore, snapshotDiskStats(path)),
+	}
+}
+
+// rampCells - write throughput of every drive by depth, with the percent
+// of the peak, the depth the drive saturates at is marked, the first row
+// is the header.
+func (r *Report) rampCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"DEPTH",
+		"WRITE",
+		"OF PEAK",
+		"",
+	}}
+	for _, result := range r.Results {
+		var peak uint64
+		for _, p := range result.Ramp {
+			peak = max(peak, p.Throughput)
+		}
+		for _, p := range result.Ramp {
+			ofPeak, mark := "-", ""
+			if peak > 0 {
+				ofPeak = fmt.Sprintf("%.0f%%", float64(p.Throughput)/float64(peak)*100)
+			}
+			if p.Depth == result.Saturation {
+				mark = "saturated"
+			}
+			cellText = append(cellText, []string{
+				result.Path,
+				fmt.Sprint(p.Depth),
+				formatRate(p.Throughput),
+				ofPeak,
+				mark,
+			})
+		}
+	}
+	return cellText
+}
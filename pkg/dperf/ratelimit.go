@@ -0,0 +1,49 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import "time"
+
+// rateLimiter - a token bucket of bytes, refilled at rate bytes/sec up
+// to a tenth of a second of I/O so that bursts stay short.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter - returns the limiter of an I/O worker of a drive
+// limited to Rate, nil if it is not.
+func (d *DrivePerf) newRateLimiter() *rateLimiter {
+	if d.Rate == 0 {
+		return nil
+	}
+	rate := float64(d.Rate) / float64(max(d.IOPerDrive, 1))
+	return &rateLimiter{rate: rate, burst: rate / 10, last: time.Now()}
+}
+
+// wait - blocks until n bytes may be transferred.
+func (l *rateLimiter) wait(n int) {
+	now := time.Now()
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*l.rate, max(l.burst, float64(n)))
+	l.last = now
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		time.Sleep(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+	}
+}
@@ -0,0 +1,145 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import "time"
+
+// Regions of the test files, the first and last 10% of a file and the rest
+const (
+	RegionHead   = "head"
+	RegionMiddle = "middle"
+	RegionTail   = "tail"
+)
+
+// regionEdgePercent - share of a file covered by the head and tail regions.
+const regionEdgePercent = 10
+
+var regionNames = [...]string{RegionHead, RegionMiddle, RegionTail}
+
+// RegionStats throughput and latency of the block operations of a drive
+// within a region of the test files. On HDDs the outer tracks at the
+// start of a file are faster than the inner ones, SSDs running out of
+// cache slow down towards the end.
+type RegionStats struct {
+	Phase      Phase         `json:"phase"`
+	Region     string        `json:"region"`
+	Throughput uint64        `json:"throughput"`
+	Latency    *LatencyStats `json:"latency,omitempty"`
+}
+
+// regionStats - operations of an I/O worker or a drive within a region.
+type regionStats struct {
+	latency *histogram
+	bytes   uint64
+	// busy is the time spent in the operations.
+	busy time.Duration
+	// throughput in bytes/sec, the sum of that of the workers for a drive.
+	throughput uint64
+}
+
+// fileRegions - operations by region of the file, indexed like regionNames.
+type fileRegions [len(regionNames)]regionStats
+
+// record - records an operation of n bytes at offset of a file of size bytes.
+func (fr *fileRegions) record(offset, size uint64, n int, latency time.Duration) {
+	if size == 0 {
+		return
+	}
+	i := 1
+	switch pos := offset * 100 / size; {
+	case pos < regionEdgePercent:
+		i = 0
+	case pos >= 100-regionEdgePercent:
+		i = 2
+	}
+	r := &fr[i]
+	if r.latency == nil {
+		r.latency = newHistogram()
+	}
+	r.latency.record(latency)
+	r.bytes += uint64(n)
+	r.busy += latency
+}
+
+// done - computes the throughput of the regions of a worker.
+func (fr *fileRegions) done() {
+	for i := range fr {
+		if fr[i].busy > 0 {
+			fr[i].throughput = uint64(float64(fr[i].bytes) / fr[i].busy.Seconds())
+		}
+	}
+}
+
+// merge - adds the regions of a worker to those of its drive.
+func (fr *fileRegions) merge(o fileRegions) {
+	for i := range fr {
+		if o[i].latency == nil {
+			continue
+		}
+		if fr[i].latency == nil {
+			fr[i].latency = newHistogram()
+		}
+		fr[i].latency.merge(o[i].latency)
+		fr[i].bytes += o[i].bytes
+		fr[i].busy += o[i].busy
+		fr[i].throughput += o[i].throughput
+	}
+}
+
+// stats - the regions operated on in phase.
+func (fr *fileRegions) stats(phase Phase) []RegionStats {
+	var stats []RegionStats
+	for i, r := range fr {
+		if r.latency == nil {
+			continue
+		}
+		stats = append(stats, RegionStats{
+			Phase:      phase,
+			Region:     regionNames[i],
+			Throughput: r.throughput,
+			Latency:    newLatencyStats(r.latency),
+		})
+	}
+	return stats
+}
+
+// regionCells - throughput and latency by region of every phase of every
+// drive, the first row is the header.
+func (r *Report) regionCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"PHASE",
+		"REGION",
+		"THROUGHPUT",
+		"P50",
+		"P99",
+		"MAX",
+	}}
+	for _, result := range r.Results {
+		if result.Error != nil {
+			continue
+		}
+		for _, rs := range result.Regions {
+			row := []string{result.Path, string(rs.Phase), rs.Region, formatRate(rs.Throughput), "-", "-", "-"}
+			if l := rs.Latency; l != nil {
+				row[4], row[5], row[6] = formatLatency(l.P50), formatLatency(l.P99), formatLatency(l.Max)
+			}
+			cellText = append(cellText, row)
+		}
+	}
+	return cellText
+}
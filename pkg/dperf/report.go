@@ -0,0 +1,524 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Output formats
+const (
+	OutputTable    = "table"
+	OutputJSON     = "json"
+	OutputMarkdown = "markdown"
+	OutputTSV      = "tsv"
+	OutputCBOR     = "cbor"
+)
+
+// ReportVersion version of the Report schema, bumped on incompatible changes.
+const ReportVersion = 1
+
+// Report results of a run along with their aggregates
+type Report struct {
+	// Version of the schema, reports without it predate versioning.
+	Version int `json:"version"`
+	// Time the run finished.
+	Time        time.Time   `json:"time"`
+	Environment Environment `json:"environment"`
+	Config      RunConfig   `json:"config"`
+
+	Tags                 map[string]string  `json:"tags,omitempty"`
+	Results              []*DrivePerfResult `json:"results"`
+	TotalWriteThroughput uint64             `json:"totalWriteThroughput"`
+	TotalReadThroughput  uint64             `json:"totalReadThroughput"`
+	TotalWriteIOPS       uint64             `json:"totalWriteIOPS"`
+	TotalReadIOPS        uint64             `json:"totalReadIOPS"`
+	TotalBytesWritten    uint64             `json:"totalBytesWritten"`
+	TotalBytesRead       uint64             `json:"totalBytesRead"`
+	// Elapsed is the duration of the run, encoded in nanoseconds.
+	Elapsed time.Duration `json:"elapsed"`
+	// CPU is nil when the CPU utilization of the host is unknown.
+	CPU *CPUUsage `json:"cpu,omitempty"`
+	// Cycle is the number of the cycle of a soak the report is of.
+	Cycle int `json:"cycle,omitempty"`
+}
+
+// CPUUsage average CPU utilization of the host during the run, in percent
+// of the time of all CPUs
+type CPUUsage struct {
+	// Busy is the time spent running code, including dperf itself.
+	Busy float64 `json:"busy"`
+	// IOWait is the idle time with I/O outstanding.
+	IOWait float64 `json:"iowait"`
+}
+
+// Environment the run was made in
+type Environment struct {
+	Hostname     string `json:"hostname"`
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+	Kernel       string `json:"kernel,omitempty"`
+	DperfVersion string `json:"dperfVersion,omitempty"`
+	GoVersion    string `json:"goVersion"`
+}
+
+// RunConfig options a run was made with
+type RunConfig struct {
+	BlockSize  uint64 `json:"blockSize"`
+	FileSize   uint64 `json:"fileSize"`
+	IOPerDrive int    `json:"ioPerDrive"`
+	// Mode is one of read-write, mixed, write-only, read-only, metadata,
+	// objects, fill or ramp.
+	Mode   string `json:"mode"`
+	Serial bool   `json:"serial"`
+	Seed   int64  `json:"seed,omitempty"`
+	// SyncEvery is the number of blocks written between fdatasyncs.
+	SyncEvery int `json:"syncEvery,omitempty"`
+	// SyncMode is dsync or sync if the files were written with O_DSYNC
+	// or O_SYNC.
+	SyncMode string `json:"syncMode,omitempty"`
+	// WriteMode is overwrite if the files were overwritten in place.
+	WriteMode string `json:"writeMode,omitempty"`
+	// DataPattern is zero or compressible if the data written was not
+	// random, Compressibility the percent of compressible data.
+	DataPattern     string `json:"dataPattern,omitempty"`
+	Compressibility int    `json:"compressibility,omitempty"`
+	// Fill is the percent of the capacity of the filesystems filled.
+	Fill float64 `json:"fill,omitempty"`
+	// Ramp is the time the I/O workers were started over, encoded in
+	// nanoseconds.
+	Ramp time.Duration `json:"ramp,omitempty"`
+	// Soak is the duration of a soak the run is a cycle of, encoded in
+	// nanoseconds.
+	Soak time.Duration `json:"soak,omitempty"`
+	// Verify is set if the data read back was checked.
+	Verify bool `json:"verify,omitempty"`
+	// KeepFiles is set if the test files were left on the drives, Reuse
+	// if the files left by an earlier run were read.
+	KeepFiles bool `json:"keepFiles,omitempty"`
+	Reuse     bool `json:"reuse,omitempty"`
+	// WarmRead is set if the files were read again through the page cache.
+	WarmRead bool `json:"warmRead,omitempty"`
+	// Access is random if the blocks were transferred at random offsets.
+	Access string `json:"access,omitempty"`
+	// Engine is how the blocks were transferred, one of the Engine*
+	// constants.
+	Engine string `json:"engine,omitempty"`
+	// AIODepth is the number of operations in flight per I/O worker of
+	// EngineLibaio.
+	AIODepth int `json:"aioDepth,omitempty"`
+	// RWFlags are the preadv2 and pwritev2 flags of the blocks.
+	RWFlags []string `json:"rwFlags,omitempty"`
+	// Copy is set if the files were copied, into CopyTo if not empty.
+	Copy   bool   `json:"copy,omitempty"`
+	CopyTo string `json:"copyTo,omitempty"`
+	// Poll is set if the tests ran again polling for completions.
+	Poll bool `json:"poll,omitempty"`
+	// ReadMix is the percent of reads of the mixed mode.
+	ReadMix int `json:"readMix,omitempty"`
+	// Duration of every phase, encoded in nanoseconds, 0 if the files
+	// were transferred once.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Runs is the number of times every drive was tested.
+	Runs int `json:"runs,omitempty"`
+	// Warmup and WarmupBytes are the unmeasured transfer before the
+	// tests, Warmup encoded in nanoseconds.
+	Warmup      time.Duration `json:"warmup,omitempty"`
+	WarmupBytes uint64        `json:"warmupBytes,omitempty"`
+	// FilePercent is the percent of the free space of every drive its
+	// test files were sized to, FileSize is then unused.
+	FilePercent float64 `json:"filePercent,omitempty"`
+	MaxFileSize uint64  `json:"maxFileSize,omitempty"`
+	// Objects is the number of small objects per I/O worker of the
+	// objects mode, of ObjectMinSize to ObjectMaxSize bytes.
+	Objects       int    `json:"objects,omitempty"`
+	ObjectMinSize uint64 `json:"objectMinSize,omitempty"`
+	ObjectMaxSize uint64 `json:"objectMaxSize,omitempty"`
+	// ErasureData and ErasureParity are the erasure set the objects were
+	// laid out for, in parts of PartSize if set.
+	ErasureData   int    `json:"erasureData,omitempty"`
+	ErasureParity int    `json:"erasureParity,omitempty"`
+	PartSize      uint64 `json:"partSize,omitempty"`
+	// Existing is the file or directory of every drive read in read-only
+	// mode, relative to the drive.
+	Existing string `json:"existing,omitempty"`
+	// Destructive is set if block devices were written to.
+	Destructive bool `json:"destructive,omitempty"`
+	// FilesPerIO is the number of files of every I/O worker if more
+	// than one.
+	FilesPerIO int `json:"filesPerIO,omitempty"`
+	// Rate is the limit of the throughput of every drive in bytes/sec.
+	Rate uint64 `json:"rate,omitempty"`
+	// Offset is where the I/O started in the files or devices.
+	Offset uint64 `json:"offset,omitempty"`
+	// Score is the weights of the composite score of the drives.
+	Score *ScoreWeights `json:"score,omitempty"`
+}
+
+// Config returns the options of the run.
+func (d *DrivePerf) Config() RunConfig {
+	mode := "read-write"
+	switch {
+	case d.MetadataFiles > 0:
+		mode = "metadata"
+	case d.Objects > 0:
+		mode = "objects"
+	case d.Fill > 0:
+		mode = "fill"
+	case d.Ramp > 0:
+		mode = "ramp"
+	case d.ReadOnly:
+		mode = "read-only"
+	case d.WriteOnly:
+		mode = "write-only"
+	case d.ReadMix > 0:
+		mode = "mixed"
+	}
+	return RunConfig{
+		BlockSize:       d.BlockSize,
+		FileSize:        d.FileSize,
+		IOPerDrive:      d.IOPerDrive,
+		Mode:            mode,
+		Serial:          d.Serial,
+		Seed:            d.Seed,
+		SyncEvery:       d.SyncEvery,
+		SyncMode:        d.SyncMode,
+		WriteMode:       d.WriteMode,
+		DataPattern:     d.DataPattern,
+		Compressibility: d.Compressibility,
+		Verify:          d.Verify,
+		KeepFiles:       d.KeepFiles,
+		Reuse:           d.Reuse,
+		WarmRead:        d.WarmRead,
+		Soak:            d.Soak,
+		Fill:            d.Fill,
+		Ramp:            d.Ramp,
+		Access:          d.Access,
+		Engine:          d.Engine,
+		AIODepth:        d.AIODepth,
+		RWFlags:         d.RWFlags,
+		Poll:            d.Poll,
+		Copy:            d.Copy,
+		CopyTo:          d.CopyTo,
+		ReadMix:         d.ReadMix,
+		Duration:        d.Duration,
+		Runs:            d.Runs,
+		Warmup:          d.Warmup,
+		WarmupBytes:     d.WarmupBytes,
+		FilePercent:     d.FilePercent,
+		MaxFileSize:     d.MaxFileSize,
+		Objects:         d.Objects,
+		ObjectMinSize:   d.ObjectMinSize,
+		ObjectMaxSize:   d.ObjectMaxSize,
+		ErasureData:     d.ErasureData,
+		ErasureParity:   d.ErasureParity,
+		PartSize:        d.PartSize,
+		Existing:        d.Existing,
+		Destructive:     d.Destructive,
+		Offset:          d.Offset,
+		Rate:            d.Rate,
+		FilesPerIO:      d.FilesPerIO,
+		Score:           d.Score,
+	}
+}
+
+// String - a short summary of the options, e.g. "4MiB x4 1GiB".
+func (c RunConfig) String() string {
+	size := FormatBytes(c.FileSize)
+	if c.FilePercent > 0 {
+		size = fmt.Sprintf("%g%%", c.FilePercent)
+	}
+	s := fmt.Sprintf("%s x%d %s", FormatBytes(c.BlockSize), c.IOPerDrive, size)
+	if c.FilesPerIO > 1 {
+		s += fmt.Sprintf(" in %d files", c.FilesPerIO)
+	}
+	if c.Offset > 0 {
+		s += " at " + FormatBytes(c.Offset)
+	}
+	if c.Rate > 0 {
+		s += " limited to " + FormatBytes(c.Rate) + "/s"
+	}
+	if c.Mode != "" && c.Mode != "read-write" {
+		s += " " + c.Mode
+	}
+	if c.ReadMix > 0 {
+		s += fmt.Sprintf(" %d%% reads", c.ReadMix)
+	}
+	if c.Fill > 0 {
+		s += fmt.Sprintf(" to %g%%", c.Fill)
+	}
+	if c.Ramp > 0 {
+		s += " over " + c.Ramp.String()
+	}
+	if c.ErasureData > 0 {
+		s += fmt.Sprintf(" EC %d+%d", c.ErasureData, c.ErasureParity)
+	}
+	if c.Engine != "" && c.Engine != EngineSync {
+		s += " " + c.Engine
+	}
+	if len(c.RWFlags) > 0 {
+		s += " rwf " + strings.Join(c.RWFlags, ",")
+	}
+	if c.Poll {
+		s += " interrupts and polled"
+	}
+	if c.CopyTo != "" {
+		s += " copied to " + c.CopyTo
+	} else if c.Copy {
+		s += " copied"
+	}
+	switch c.Access {
+	case AccessRandom:
+		s += " random"
+	case AccessBoth:
+		s += " sequential and random"
+	}
+	if c.Duration > 0 {
+		s += " " + c.Duration.String()
+	}
+	switch {
+	case c.Warmup > 0:
+		s += " warmup " + c.Warmup.String()
+	case c.WarmupBytes > 0:
+		s += " warmup " + FormatBytes(c.WarmupBytes)
+	}
+	if c.Soak > 0 {
+		s += " soak " + c.Soak.String()
+	}
+	if c.Runs > 1 {
+		s += fmt.Sprintf(" %d runs", c.Runs)
+	}
+	switch c.DataPattern {
+	case PatternZero:
+		s += " zeros"
+	case PatternCompressible:
+		s += fmt.Sprintf(" %d%% compressible", c.Compressibility)
+	}
+	if c.Verify {
+		s += " verify"
+	}
+	if c.Reuse {
+		s += " reused files"
+	}
+	if c.KeepFiles {
+		s += " kept files"
+	}
+	if c.WarmRead {
+		s += " warm read"
+	}
+	if c.SyncMode != "" && c.SyncMode != SyncModeDirect {
+		s += " " + c.SyncMode
+	}
+	if c.WriteMode == WriteModeOverwrite {
+		s += " overwrite"
+	}
+	if c.SyncEvery > 0 {
+		s += fmt.Sprintf(" sync/%d", c.SyncEvery)
+	}
+	if c.Serial {
+		s += " serial"
+	}
+	return s
+}
+
+// newReport - aggregates the results of a run.
+func (d *DrivePerf) newReport(results []*DrivePerfResult) *Report {
+	hostname, _ := os.Hostname()
+	report := &Report{
+		Version: ReportVersion,
+		Time:    time.Now().UTC(),
+		Environment: Environment{
+			Hostname:     hostname,
+			OS:           runtime.GOOS,
+			Arch:         runtime.GOARCH,
+			Kernel:       kernelVersion(),
+			DperfVersion: d.Version,
+			GoVersion:    runtime.Version(),
+		},
+		Config:  d.Config(),
+		Tags:    d.Tags,
+		Results: results,
+	}
+	for _, result := range results {
+		report.TotalWriteThroughput += result.WriteThroughput
+		report.TotalReadThroughput += result.ReadThroughput
+		report.TotalWriteIOPS += result.WriteIOPS
+		report.TotalReadIOPS += result.ReadIOPS
+		report.TotalBytesWritten += result.TotalBytesWritten
+		report.TotalBytesRead += result.TotalBytesRead
+	}
+	markSharedDevices(results)
+	return report
+}
+
+// decodeReport - decodes a JSON or CBOR report of a supported schema version.
+func decodeReport(b []byte) (*Report, error) {
+	report := &Report{}
+	if isCBOR(b) {
+		if err := cbor.Unmarshal(b, report); err != nil {
+			return nil, fmt.Errorf("not a CBOR report: %w", err)
+		}
+	} else if err := json.Unmarshal(b, report); err != nil {
+		return nil, fmt.Errorf("not a JSON report: %w", err)
+	}
+	if report.Version > ReportVersion {
+		return nil, fmt.Errorf("report version %d is newer than the supported version %d, upgrade dperf",
+			report.Version, ReportVersion)
+	}
+	return report, nil
+}
+
+func (r *Report) renderJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// renderMarkdown - renders the results as GitHub-flavored Markdown tables.
+func (r *Report) renderMarkdown(w io.Writer) error {
+	tables := r.detailCells()
+	tables = append(tables, r.totalCells())
+	return renderMarkdownTables(w, tables)
+}
+
+// renderMarkdownTables - renders every table, header row first, as a
+// Markdown table.
+func renderMarkdownTables(w io.Writer, tables [][][]string) error {
+	for i, cellText := range tables {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if cellText[0][len(cellText[0])-1] == "" {
+			cellText[0][len(cellText[0])-1] = "STATUS"
+		}
+		writeMarkdownRow(w, cellText[0])
+		sep := make([]string, len(cellText[0]))
+		for j := range sep {
+			sep[j] = "---"
+		}
+		writeMarkdownRow(w, sep)
+		for _, row := range cellText[1:] {
+			writeMarkdownRow(w, row)
+		}
+	}
+	return nil
+}
+
+func writeMarkdownRow(w io.Writer, row []string) {
+	cells := make([]string, len(row))
+	for i, c := range row {
+		cells[i] = strings.ReplaceAll(c, "|", "\\|")
+	}
+	fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+}
+
+// renderTSV - one "path, write, read, error, write IOPS, read IOPS,
+// bytes written, bytes read, write ops, read ops, write seconds, read
+// seconds" line per drive separated by tabs, throughput in bytes/sec,
+// without headers or colors.
+func (r *Report) renderTSV(w io.Writer) error {
+	for _, result := range r.Results {
+		var errStr string
+		if result.Error != nil {
+			errStr = tsvEscaper.Replace(result.Error.Error())
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%.3f\t%.3f\n", tsvEscaper.Replace(result.Path),
+			result.WriteThroughput, result.ReadThroughput, errStr, result.WriteIOPS, result.ReadIOPS,
+			result.TotalBytesWritten, result.TotalBytesRead, result.WriteOps, result.ReadOps,
+			result.WriteElapsed.Seconds(), result.ReadElapsed.Seconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var tsvEscaper = strings.NewReplacer("\t", " ", "\n", " ")
+
+// ParseFormat parses a text/template executed for every DrivePerfResult,
+// e.g. '{{.Path}} {{.WriteThroughput}} {{.ReadThroughput}}'. Besides the
+// result fields templates can use 'bytes' to humanize a byte count,
+// 'json' to encode a value and 'tag' to look up a tag by key.
+func ParseFormat(text string) (*template.Template, error) {
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return template.New("format").Funcs(template.FuncMap{
+		"bytes": FormatBytes,
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		// Overridden with the tags of the run by renderFormat.
+		"tag": func(string) string { return "" },
+	}).Parse(text)
+}
+
+func (r *Report) renderFormat(w io.Writer, tmpl *template.Template) error {
+	tmpl, err := tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	tmpl.Funcs(template.FuncMap{
+		"tag": func(k string) string { return r.Tags[k] },
+	})
+	for _, result := range r.Results {
+		if err := tmpl.Execute(w, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// driveResultJSON - DrivePerfResult with Error as a string.
+type driveResultJSON struct {
+	driveResult
+	Error string `json:"error,omitempty"`
+}
+
+type driveResult DrivePerfResult
+
+// MarshalJSON encodes the result with Error as a string.
+func (r DrivePerfResult) MarshalJSON() ([]byte, error) {
+	v := driveResultJSON{driveResult: driveResult(r)}
+	if r.Error != nil {
+		v.Error = r.Error.Error()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON.
+func (r *DrivePerfResult) UnmarshalJSON(b []byte) error {
+	var v driveResultJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*r = DrivePerfResult(v.driveResult)
+	if v.Error != "" {
+		r.Error = errors.New(v.Error)
+	}
+	return nil
+}
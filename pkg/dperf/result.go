@@ -18,8 +18,15 @@ package dperf
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
 
-	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/minio/pkg/v3/console"
 )
@@ -27,71 +34,412 @@ import (
 // ErrNotImplemented returned for platforms where dperf will not run.
 var ErrNotImplemented = errors.New("not implemented")
 
+// ErrReadOnlyFS returned for paths whose filesystem is mounted read-only.
+var ErrReadOnlyFS = errors.New("filesystem mounted read-only")
+
+// ErrWriteBudgetExceeded returned when a run would write more than MaxWrite.
+var ErrWriteBudgetExceeded = errors.New("run exceeds the maximum write budget")
+
+// ErrAllDrivesFailed returned once rendered when the tests failed on
+// every drive.
+var ErrAllDrivesFailed = errors.New("tests failed on every drive")
+
 // DrivePerfResult drive run result
 type DrivePerfResult struct {
-	Path            string
-	WriteThroughput uint64
-	ReadThroughput  uint64
-	Error           error
+	Path string `json:"path"`
+	// Model of the drive backing Path, empty if unknown.
+	Model string `json:"model,omitempty"`
+	// Zoned is the zone model of the drive backing Path, one of the
+	// Zoned* constants, empty if it is not zoned.
+	Zoned           string `json:"zoned,omitempty"`
+	WriteThroughput uint64 `json:"writeThroughput"`
+	ReadThroughput  uint64 `json:"readThroughput"`
+	// WriteIOPS and ReadIOPS are block operations per second.
+	WriteIOPS uint64 `json:"writeIOPS"`
+	ReadIOPS  uint64 `json:"readIOPS"`
+	// WriteOps and ReadOps are the block operations completed.
+	WriteOps uint64 `json:"writeOps"`
+	ReadOps  uint64 `json:"readOps"`
+	// WriteElapsed and ReadElapsed are the durations of the phases, up to
+	// the slowest I/O worker, encoded in nanoseconds.
+	WriteElapsed time.Duration `json:"writeElapsed"`
+	ReadElapsed  time.Duration `json:"readElapsed"`
+	// WriteWorkers and ReadWorkers summarize the throughput of the
+	// individual I/O workers, WriteThroughput and ReadThroughput are their sums.
+	WriteWorkers WorkerStats `json:"writeWorkers"`
+	ReadWorkers  WorkerStats `json:"readWorkers"`
+	// WriteLatency and ReadLatency are nil when the phase did not run.
+	WriteLatency *LatencyStats `json:"writeLatency,omitempty"`
+	ReadLatency  *LatencyStats `json:"readLatency,omitempty"`
+	// SyncLatency is the latency of fdatasync, nil unless DrivePerf.SyncEvery is set.
+	SyncLatency *LatencyStats `json:"syncLatency,omitempty"`
+	// WriteSubmitLatency and ReadSubmitLatency are the time io_submit
+	// takes, WriteCompleteLatency and ReadCompleteLatency the time from
+	// its return to the completion of the block in io_getevents, nil
+	// unless DrivePerf.Engine is EngineLibaio.
+	WriteSubmitLatency   *LatencyStats `json:"writeSubmitLatency,omitempty"`
+	WriteCompleteLatency *LatencyStats `json:"writeCompleteLatency,omitempty"`
+	ReadSubmitLatency    *LatencyStats `json:"readSubmitLatency,omitempty"`
+	ReadCompleteLatency  *LatencyStats `json:"readCompleteLatency,omitempty"`
+	// Metadata is the rate of every metadata operation, nil unless
+	// DrivePerf.MetadataFiles is set.
+	Metadata []MetadataStats `json:"metadata,omitempty"`
+	// Objects is the rate of the small objects written and read, nil
+	// unless DrivePerf.Objects is set.
+	Objects []ObjectStats `json:"objects,omitempty"`
+	// Fill is the write throughput by fill level, nil unless DrivePerf.Fill
+	// is set.
+	Fill []FillPoint `json:"fill,omitempty"`
+	// Ramp is the write throughput by depth, nil unless DrivePerf.Ramp is
+	// set, Saturation the lowest depth reaching 95% of the peak.
+	Ramp       []RampPoint `json:"ramp,omitempty"`
+	Saturation int         `json:"saturation,omitempty"`
+	// WarmRead is nil unless DrivePerf.WarmRead is set, the others are
+	// the reads from the drive.
+	WarmRead *WarmRead `json:"warmRead,omitempty"`
+	// Verify is nil unless DrivePerf.Verify is set.
+	Verify *Verification `json:"verify,omitempty"`
+	// Outliers is nil unless DrivePerf.LatencyThreshold is set.
+	Outliers *LatencyOutliers `json:"outliers,omitempty"`
+	// Regions is the throughput and latency by region of the test files.
+	Regions []RegionStats `json:"regions,omitempty"`
+	// Zones is the throughput by zone of a zoned block device tested in
+	// place of files.
+	Zones []ZoneStats `json:"zones,omitempty"`
+	// SlowestOps are the DrivePerf.SlowestOps slowest operations, slowest first.
+	SlowestOps        []SlowOp `json:"slowestOps,omitempty"`
+	TotalBytesWritten uint64   `json:"totalBytesWritten"`
+	TotalBytesRead    uint64   `json:"totalBytesRead"`
+	// FileSize is the size of the test files of the drive when sized
+	// from its free space by DrivePerf.FilePercent.
+	FileSize uint64 `json:"fileSize,omitempty"`
+	// Wear is nil when the drive does not expose SMART endurance data.
+	Wear *DriveWear `json:"wear,omitempty"`
+	// QueueDepth is nil when the drive is not backed by a block device.
+	QueueDepth *QueueDepth `json:"queueDepth,omitempty"`
+	// DiskStats is nil when the drive is not backed by a block device.
+	DiskStats *DiskStats `json:"diskStats,omitempty"`
+	// Temperature is nil when the drive does not report its temperature.
+	Temperature *DriveTemperature `json:"temperature,omitempty"`
+	// Runs is nil unless DrivePerf.Runs is greater than 1, the throughput
+	// and IOPS are then the means over the runs.
+	Runs *RunStats `json:"runs,omitempty"`
+	// Random is nil unless DrivePerf.Access is AccessBoth, it is then the
+	// result of the random phases and the others are the sequential ones.
+	Random *DrivePerfResult `json:"random,omitempty"`
+	// Polled is nil unless DrivePerf.Poll is set, it is then the result
+	// of the tests polling for completions and the others are those of
+	// the tests completing on interrupts. PollMode is how the device
+	// polls, one of the PollMode* constants, empty if unknown.
+	Polled   *DrivePerfResult `json:"polled,omitempty"`
+	PollMode string           `json:"pollMode,omitempty"`
+	// Copy is nil unless DrivePerf.Copy is set.
+	Copy *CopyStats `json:"copy,omitempty"`
+	// Score is nil unless DrivePerf.Score is set.
+	Score *float64 `json:"score,omitempty"`
+	// Spec is nil unless DrivePerf.Specs has an entry for the drive.
+	Spec *SpecResult `json:"spec,omitempty"`
+	// Stability is nil unless the run was long enough to tell.
+	Stability *Stability `json:"stability,omitempty"`
+	// Series is the throughput sampled every second, only recorded when
+	// DrivePerf.Series is set.
+	Series []ThroughputPoint `json:"series,omitempty"`
+	Error  error             `json:"-"`
+
+	// writeHist and readHist hold the latency of every block operation,
+	// they are not part of the report.
+	writeHist, readHist *histogram
 }
 
-// An alias of string to represent the health color code of an object
-type col string
+// WorkerStats throughput of the I/O workers of a drive
+type WorkerStats struct {
+	Min uint64 `json:"min"`
+	Avg uint64 `json:"avg"`
+	Max uint64 `json:"max"`
+	// Spread is Max - Min, a large spread hints at unfair concurrent I/O.
+	Spread uint64 `json:"spread"`
+	// StdDev is the population standard deviation of the throughputs.
+	StdDev uint64 `json:"stddev"`
+	// Throughputs of the individual workers, indexed by worker.
+	Throughputs []uint64 `json:"throughputs,omitempty"`
+}
 
-const (
-	colGrey   col = "Grey"
-	colRed    col = "Red"
-	colYellow col = "Yellow"
-	colGreen  col = "Green"
-)
+// LatencyStats latency percentiles of the block operations of a drive,
+// encoded in nanoseconds
+type LatencyStats struct {
+	P50  time.Duration `json:"p50"`
+	P90  time.Duration `json:"p90"`
+	P99  time.Duration `json:"p99"`
+	P999 time.Duration `json:"p999"`
+	Max  time.Duration `json:"max"`
+	// FirstBlock is the longest time an I/O worker took from its start,
+	// opening the file included, to its first completed block, indexed
+	// by worker in FirstBlocks. Slow spin-up, link retrain or power
+	// state exits show up here rather than in the percentiles.
+	FirstBlock  time.Duration   `json:"firstBlock,omitempty"`
+	FirstBlocks []time.Duration `json:"firstBlocks,omitempty"`
+}
+
+// LatencyPercentile a latency percentile with its name, e.g. "p99", and
+// quantile, e.g. "0.99"
+type LatencyPercentile struct {
+	Name     string
+	Quantile string
+	Latency  time.Duration
+}
+
+// Percentiles returns the percentiles from P50 to Max, Max is the
+// quantile "1".
+func (l *LatencyStats) Percentiles() []LatencyPercentile {
+	return []LatencyPercentile{
+		{"p50", "0.5", l.P50},
+		{"p90", "0.9", l.P90},
+		{"p99", "0.99", l.P99},
+		{"p999", "0.999", l.P999},
+		{"max", "1", l.Max},
+	}
+}
+
+// newWorkerStats - summarizes per-worker throughputs.
+func newWorkerStats(throughputs []uint64) WorkerStats {
+	if len(throughputs) == 0 {
+		return WorkerStats{}
+	}
+	ws := WorkerStats{
+		Min:         throughputs[0],
+		Max:         throughputs[0],
+		Throughputs: append([]uint64(nil), throughputs...),
+	}
+	var sum uint64
+	for _, t := range throughputs {
+		ws.Min = min(ws.Min, t)
+		ws.Max = max(ws.Max, t)
+		sum += t
+	}
+	ws.Avg = sum / uint64(len(throughputs))
+	ws.Spread = ws.Max - ws.Min
+
+	mean := float64(sum) / float64(len(throughputs))
+	var sq float64
+	for _, t := range throughputs {
+		d := float64(t) - mean
+		sq += d * d
+	}
+	ws.StdDev = uint64(math.Sqrt(sq / float64(len(throughputs))))
+	return ws
+}
+
+// DriveWear endurance cost of a run as reported by the drive SMART log
+type DriveWear struct {
+	PercentUsedBefore uint8 `json:"percentUsedBefore"`
+	PercentUsedAfter  uint8 `json:"percentUsedAfter"`
+	// BytesWritten is the host writes the drive accounted for during the run.
+	BytesWritten uint64 `json:"bytesWritten"`
+	// HostWriteRatio is BytesWritten divided by the bytes written by
+	// dperf, it includes the filesystem overhead and any other writes to
+	// the drive. It is not the write amplification factor of the drive,
+	// the SMART log only counts the writes of the host, not those of the
+	// media. The SMART counters have a granularity of 512KB, runs
+	// writing little data only give a rough estimate.
+	HostWriteRatio float64 `json:"hostWriteRatio,omitempty"`
+}
+
+func (d *DrivePerf) render(report *Report) error {
+	w := d.out()
+	if d.Format != nil {
+		return report.renderFormat(w, d.Format)
+	}
+	switch d.Output {
+	case OutputJSON:
+		return report.renderJSON(w)
+	case OutputMarkdown:
+		return report.renderMarkdown(w)
+	case OutputTSV:
+		return report.renderTSV(w)
+	case OutputCBOR:
+		return report.renderCBOR(w)
+	}
+	return d.renderTable(w, report)
+}
+
+// out - writer the results are rendered to.
+func (d *DrivePerf) out() io.Writer {
+	if d.Out != nil {
+		return d.Out
+	}
+	return os.Stdout
+}
+
+func (d *DrivePerf) renderTable(w io.Writer, report *Report) error {
+	if d.Verbose {
+		for _, cellText := range report.detailCells() {
+			if err := displayTable(w, cellText); err != nil {
+				return err
+			}
+		}
+	} else if metadata := report.metadataCells(); len(metadata) > 1 {
+		// The metadata rates are the results of a metadata test.
+		if err := displayTable(w, metadata); err != nil {
+			return err
+		}
+	} else if objects := report.objectCells(); len(objects) > 1 {
+		if err := displayTable(w, objects); err != nil {
+			return err
+		}
+	} else if fill := report.fillCells(); len(fill) > 1 {
+		if err := displayTable(w, fill); err != nil {
+			return err
+		}
+	} else if ramp := report.rampCells(); len(ramp) > 1 {
+		if err := displayTable(w, ramp); err != nil {
+			return err
+		}
+	} else if warm := report.warmReadCells(); len(warm) > 1 {
+		if err := displayTable(w, warm); err != nil {
+			return err
+		}
+	} else if access := report.accessCells(); len(access) > 1 {
+		if err := displayTable(w, access); err != nil {
+			return err
+		}
+	} else if poll := report.pollCells(); len(poll) > 1 {
+		if err := displayTable(w, poll); err != nil {
+			return err
+		}
+	} else if cp := report.copyCells(); len(cp) > 1 {
+		if err := displayTable(w, cp); err != nil {
+			return err
+		}
+	}
+	if corrupt := report.corruptCells(); !d.Verbose && len(corrupt) > 1 {
+		// Corrupt data is never left out.
+		if err := displayTable(w, corrupt); err != nil {
+			return err
+		}
+	}
+	if errs := report.errorCells(); !d.Verbose && len(errs) > 1 {
+		// Nor are the drives that failed.
+		if err := displayTable(w, errs); err != nil {
+			return err
+		}
+	}
+	return displayTable(w, report.totalCells())
+}
 
-// getPrintCol - map color code to color for printing
-func getPrintCol(c col) *color.Color {
-	switch c {
-	case colGrey:
-		return color.New(color.FgWhite, color.Bold)
-	case colRed:
-		return color.New(color.FgRed, color.Bold)
-	case colYellow:
-		return color.New(color.FgYellow, color.Bold)
-	case colGreen:
-		return color.New(color.FgGreen, color.Bold)
+// errorCells - the error of every drive that failed, the first row is
+// the header.
+func (r *Report) errorCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"ERROR",
+	}}
+	for _, result := range r.Results {
+		if result.Error != nil {
+			cellText = append(cellText, []string{result.Path, result.Error.Error()})
+		}
 	}
-	return nil
+	return cellText
 }
 
-func (d *DrivePerf) render(results []*DrivePerfResult) {
-	dspOrder := []col{colGreen} // Header
-	for i := 0; i < len(results); i++ {
-		dspOrder = append(dspOrder, colGrey)
+// allFailed - ErrAllDrivesFailed with the first error if every drive
+// of the report failed.
+func (r *Report) allFailed() error {
+	if len(r.Results) == 0 {
+		return nil
+	}
+	for _, result := range r.Results {
+		if result.Error == nil {
+			return nil
+		}
 	}
+	return fmt.Errorf("%w: %s: %v", ErrAllDrivesFailed, r.Results[0].Path, r.Results[0].Error)
+}
 
-	var printColors []*color.Color
-	for _, c := range dspOrder {
-		printColors = append(printColors, getPrintCol(c))
+// detailCells - the per-drive tables of the verbose renderers, tables
+// without rows are left out, the first is always the drives.
+func (r *Report) detailCells() [][][]string {
+	tables := [][][]string{r.driveCells()}
+	for _, cellText := range [][][]string{
+		r.volumeCells(),
+		r.workerCells(),
+		r.runCells(),
+		r.accessCells(),
+		r.pollCells(),
+		r.copyCells(),
+		r.warmReadCells(),
+		r.metadataCells(),
+		r.objectCells(),
+		r.fillCells(),
+		r.rampCells(),
+		r.verifyCells(),
+		r.corruptCells(),
+		r.latencyCells(),
+		r.regionCells(),
+		r.zoneCells(),
+		r.outlierCells(),
+		r.slowOpCells(),
+		r.stabilityCells(),
+		r.queueDepthCells(),
+		r.diskStatsCells(),
+		r.temperatureCells(),
+		r.wearCells(),
+	} {
+		if len(cellText) > 1 {
+			tables = append(tables, cellText)
+		}
 	}
+	return tables
+}
 
-	tbl := console.NewTable(printColors, []bool{false, false, false, false}, 0)
+// displayTable - prints cellText as a table with a highlighted header row,
+// colors are only used on stdout.
+func displayTable(w io.Writer, cellText [][]string) error {
+	printColors := []*color.Color{getPrintColFor(w, colGreen)} // Header
+	for i := 1; i < len(cellText); i++ {
+		printColors = append(printColors, getPrintColFor(w, colGrey))
+	}
+	tbl := console.NewTable(printColors, make([]bool, len(cellText[0])), 0)
+	return tbl.PopulateTable(w, cellText)
+}
 
-	cellText := make([][]string, len(results)+1)
+// driveCells - per-drive rows shared by the human readable renderers,
+// the first row is the header.
+func (r *Report) driveCells() [][]string {
+	cellText := make([][]string, len(r.Results)+1)
 	cellText[0] = []string{
 		"PATH",
 		"WRITE",
+		"WRITE IOPS",
 		"READ",
+		"READ IOPS",
+		"WRITTEN",
+		"READ BYTES",
 		"",
 	}
 
-	var aggregateRead uint64
-	var aggregateWrite uint64
-	for idx, result := range results {
+	withSpec := r.hasSpec()
+	if withSpec {
+		cellText[0] = slices.Insert(cellText[0], 2, "OF SPEC")
+		cellText[0] = slices.Insert(cellText[0], 5, "OF SPEC")
+	}
+	threshold := r.latencyThreshold()
+	if threshold > 0 {
+		cellText[0] = slices.Insert(cellText[0], len(cellText[0])-1, "> "+formatLatency(threshold))
+	}
+	scored := r.Config.Score != nil
+	if scored {
+		cellText[0] = slices.Insert(cellText[0], 1, "SCORE")
+	}
+
+	for idx, result := range r.Results {
 		idx++
-		read := humanize.IBytes(result.ReadThroughput) + "/s"
-		write := humanize.IBytes(result.WriteThroughput) + "/s"
-		aggregateRead += result.ReadThroughput
-		aggregateWrite += result.WriteThroughput
+		read := formatRate(result.ReadThroughput)
+		write := formatRate(result.WriteThroughput)
+		readIOPS := strconv.FormatUint(result.ReadIOPS, 10)
+		writeIOPS := strconv.FormatUint(result.WriteIOPS, 10)
 		if result.Error != nil {
-			read = "-"
-			write = "-"
+			read, readIOPS = "-", "-"
+			write, writeIOPS = "-", "-"
 		}
 
 		err := func() string {
@@ -104,29 +452,305 @@ func (d *DrivePerf) render(results []*DrivePerfResult) {
 		cellText[idx] = []string{
 			result.Path,
 			write,
+			writeIOPS,
 			read,
+			readIOPS,
+			FormatBytes(result.TotalBytesWritten),
+			FormatBytes(result.TotalBytesRead),
 			err,
 		}
+		if withSpec {
+			writeSpec, readSpec := "-", "-"
+			if result.Spec != nil {
+				writeSpec = percentOfSpec(result.Spec.WritePercent)
+				readSpec = percentOfSpec(result.Spec.ReadPercent)
+			}
+			cellText[idx] = slices.Insert(cellText[idx], 2, writeSpec)
+			cellText[idx] = slices.Insert(cellText[idx], 5, readSpec)
+		}
+		if threshold > 0 {
+			cellText[idx] = slices.Insert(cellText[idx], len(cellText[idx])-1, outlierCount(result.Outliers))
+		}
+		if scored {
+			cellText[idx] = slices.Insert(cellText[idx], 1, formatScore(result.Score))
+		}
 	}
-	if d.Verbose {
-		tbl.DisplayTable(cellText)
+	return cellText
+}
+
+// hasSpec - true if any drive was compared to a spec.
+func (r *Report) hasSpec() bool {
+	for _, result := range r.Results {
+		if result.Spec != nil {
+			return true
+		}
 	}
+	return false
+}
 
-	dspAggOrder := []col{colGreen, colGrey} // Header
-	printColors = []*color.Color{}
-	for _, c := range dspAggOrder {
-		printColors = append(printColors, getPrintCol(c))
+// latencyThreshold - the latency threshold of the outliers, 0 if they
+// were not tracked.
+func (r *Report) latencyThreshold() time.Duration {
+	for _, result := range r.Results {
+		if result.Outliers != nil {
+			return result.Outliers.Threshold
+		}
 	}
+	return 0
+}
 
-	tblAgg := console.NewTable(printColors, []bool{false, false}, 0)
-	cellText = make([][]string, 2)
-	cellText[0] = []string{
-		"TotalWRITE",
-		"TotalREAD",
+// volumeCells - bytes, operations and duration of every phase of every
+// drive, the first row is the header.
+func (r *Report) volumeCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"PHASE",
+		"BYTES",
+		"OPS",
+		"ELAPSED",
+	}}
+	for _, result := range r.Results {
+		if result.Error != nil {
+			continue
+		}
+		for _, phase := range []struct {
+			name    Phase
+			bytes   uint64
+			ops     uint64
+			elapsed time.Duration
+		}{
+			{PhaseWrite, result.TotalBytesWritten, result.WriteOps, result.WriteElapsed},
+			{PhaseRead, result.TotalBytesRead, result.ReadOps, result.ReadElapsed},
+		} {
+			if phase.ops == 0 {
+				continue
+			}
+			cellText = append(cellText, []string{
+				result.Path,
+				string(phase.name),
+				FormatBytes(phase.bytes),
+				strconv.FormatUint(phase.ops, 10),
+				formatLatency(phase.elapsed),
+			})
+		}
+	}
+	return cellText
+}
+
+// slowWorkerFactor - a worker is flagged slow when its throughput is below
+// the average of its drive divided by this factor.
+const slowWorkerFactor = 2
+
+// workerCells - throughput of every I/O worker of drives tested with more
+// than one worker, the first row is the header.
+func (r *Report) workerCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"WORKER",
+		"WRITE",
+		"READ",
+		"",
+	}}
+	for _, result := range r.Results {
+		workers := max(len(result.WriteWorkers.Throughputs), len(result.ReadWorkers.Throughputs))
+		if result.Error != nil || workers < 2 {
+			continue
+		}
+		for i := 0; i < workers; i++ {
+			write, writeSlow := workerThroughput(result.WriteWorkers, i)
+			read, readSlow := workerThroughput(result.ReadWorkers, i)
+			status := "✓"
+			if writeSlow || readSlow {
+				status = "slow"
+			}
+			cellText = append(cellText, []string{
+				result.Path,
+				strconv.Itoa(i),
+				write,
+				read,
+				status,
+			})
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			"stddev",
+			workerStdDev(result.WriteWorkers),
+			workerStdDev(result.ReadWorkers),
+			"",
+		})
+	}
+	return cellText
+}
+
+// workerStdDev - standard deviation of the worker throughputs, also as a
+// percent of their average.
+func workerStdDev(ws WorkerStats) string {
+	if len(ws.Throughputs) == 0 {
+		return "-"
+	}
+	if ws.Avg == 0 {
+		return formatRate(ws.StdDev)
+	}
+	return fmt.Sprintf("%s (%.1f%%)", formatRate(ws.StdDev), float64(ws.StdDev)/float64(ws.Avg)*100)
+}
+
+// workerThroughput - throughput of worker i and whether it is slow
+// compared to the other workers of the drive.
+func workerThroughput(ws WorkerStats, i int) (string, bool) {
+	if i >= len(ws.Throughputs) {
+		return "-", false
 	}
-	cellText[1] = []string{
-		humanize.IBytes(aggregateWrite) + "/s",
-		humanize.IBytes(aggregateRead) + "/s",
+	t := ws.Throughputs[i]
+	return formatRate(t), t < ws.Avg/slowWorkerFactor
+}
+
+// latencyCells - latency percentiles of every phase of every drive, the
+// first row is the header.
+func (r *Report) latencyCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"PHASE",
+		"P50",
+		"P90",
+		"P99",
+		"P99.9",
+		"MAX",
+		"FIRST BLOCK",
+	}}
+	for _, result := range r.Results {
+		if result.Error != nil {
+			continue
+		}
+		for _, phase := range []struct {
+			name    string
+			latency *LatencyStats
+		}{
+			{string(PhaseWrite), result.WriteLatency},
+			{"write submit", result.WriteSubmitLatency},
+			{"write complete", result.WriteCompleteLatency},
+			{"sync", result.SyncLatency},
+			{string(PhaseRead), result.ReadLatency},
+			{"read submit", result.ReadSubmitLatency},
+			{"read complete", result.ReadCompleteLatency},
+		} {
+			l := phase.latency
+			if l == nil {
+				continue
+			}
+			cellText = append(cellText, []string{
+				result.Path,
+				phase.name,
+				formatLatency(l.P50),
+				formatLatency(l.P90),
+				formatLatency(l.P99),
+				formatLatency(l.P999),
+				formatLatency(l.Max),
+				firstBlock(l),
+			})
+		}
+	}
+	return cellText
+}
+
+// firstBlock - time to the first block of the slowest worker, "-" when
+// not measured, e.g. for fdatasync.
+func firstBlock(l *LatencyStats) string {
+	if l.FirstBlock == 0 {
+		return "-"
+	}
+	return formatLatency(l.FirstBlock)
+}
+
+// formatLatency - latency rounded to 3 significant digits.
+func formatLatency(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	case d >= 100*time.Millisecond:
+		return d.Round(time.Millisecond).String()
+	case d >= 10*time.Millisecond:
+		return d.Round(100 * time.Microsecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	}
+	return d.Round(time.Microsecond).String()
+}
+
+// wearCells - endurance cost of the run for drives exposing SMART data,
+// the first row is the header.
+func (r *Report) wearCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"DRIVE WRITTEN",
+		"HOST WRITE RATIO",
+		"PERCENTAGE USED",
+	}}
+	for _, result := range r.Results {
+		if result.Wear == nil {
+			continue
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			FormatBytes(result.Wear.BytesWritten),
+			hostWriteRatio(result.Wear.HostWriteRatio),
+			fmt.Sprintf("%d%% -> %d%%", result.Wear.PercentUsedBefore, result.Wear.PercentUsedAfter),
+		})
+	}
+	return cellText
+}
+
+// hostWriteRatio - host writes of the drive per byte dperf wrote, "-"
+// if nothing was written.
+func hostWriteRatio(ratio float64) string {
+	if ratio == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.2fx", ratio)
+}
+
+// totalCells - aggregate throughput and tags, the first row is the header.
+func (r *Report) totalCells() [][]string {
+	// A metadata test moves no data, its totals are the rates of its
+	// operations.
+	cellText := r.metadataTotalCells()
+	if cellText == nil {
+		cellText = make([][]string, 2)
+		cellText[0] = []string{
+			"TotalWRITE",
+			"TotalREAD",
+			"WRITE IOPS",
+			"READ IOPS",
+			"WRITTEN",
+			"READ BYTES",
+			"ELAPSED",
+		}
+		cellText[1] = []string{
+			formatRate(r.TotalWriteThroughput),
+			formatRate(r.TotalReadThroughput),
+			strconv.FormatUint(r.TotalWriteIOPS, 10),
+			strconv.FormatUint(r.TotalReadIOPS, 10),
+			FormatBytes(r.TotalBytesWritten),
+			FormatBytes(r.TotalBytesRead),
+			formatLatency(r.Elapsed),
+		}
+	}
+	if r.CPU != nil {
+		cellText[0] = append(cellText[0], "CPU", "IOWAIT")
+		cellText[1] = append(cellText[1], fmt.Sprintf("%.1f%%", r.CPU.Busy), fmt.Sprintf("%.1f%%", r.CPU.IOWait))
+	}
+	for _, k := range sortedKeys(r.Tags) {
+		cellText[0] = append(cellText[0], k)
+		cellText[1] = append(cellText[1], r.Tags[k])
+	}
+	return cellText
+}
+
+// sortedKeys - returns the keys of m in sorted order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
 	}
-	tblAgg.DisplayTable(cellText)
+	sort.Strings(keys)
+	return keys
 }
@@ -0,0 +1,91 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dsyncFlag - the open flag of the writes in SyncModeDSync.
+const dsyncFlag = unix.O_DSYNC
+
+// statFS - the filesystem backing path.
+func statFS(path string) (fsStat, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return fsStat{}, err
+	}
+	return fsStat{
+		size:     st.Blocks * uint64(st.Bsize),
+		free:     st.Bfree * uint64(st.Bsize),
+		avail:    st.Bavail * uint64(st.Bsize),
+		readOnly: st.Flags&unix.MNT_RDONLY == unix.MNT_RDONLY,
+	}, nil
+}
+
+// openDirect - opens the file at path with flag and F_NOCACHE, darwin
+// has no O_DIRECT, its I/O bypasses the unified buffer cache.
+func openDirect(path string, flag int, perm os.FileMode) (*os.File, error) {
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = unix.FcntlInt(f.Fd(), unix.F_NOCACHE, 1); err != nil {
+		f.Close()
+		return nil, &os.PathError{Op: "fcntl", Path: path, Err: err}
+	}
+	return f, nil
+}
+
+// checkDeviceUnused - O_EXCL does not detect a mounted device on darwin,
+// writes to the disk of a mounted volume fail with EBUSY instead.
+func checkDeviceUnused(path string) error {
+	return nil
+}
+
+// fdatasync - fsync() on darwin only hands the data to the drive, which
+// may keep it in its volatile cache, F_FULLFSYNC also flushes the cache
+// of the drive as fdatasync() does on Linux. Filesystems which do not
+// support it, as network ones, fall back to fsync().
+func fdatasync(fd int) error {
+	_, err := unix.FcntlInt(uintptr(fd), unix.F_FULLFSYNC, 0)
+	if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.ENOTTY) {
+		return unix.Fsync(fd)
+	}
+	return err
+}
+
+// advise - declares the access pattern of every file of the region,
+// read-ahead is disabled for random reads.
+func (r region) advise(_ int64, random bool) {
+	readAhead := 1
+	if random {
+		readAhead = 0
+	}
+	for _, f := range r.files {
+		unix.FcntlInt(f.Fd(), unix.F_RDAHEAD, readAhead)
+	}
+}
+
+// disableDirectIO - F_NOCACHE has no alignment requirements, unaligned
+// buffers are written as they are.
+func disableDirectIO(fd uintptr) error {
+	return nil
+}
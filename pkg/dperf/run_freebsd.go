@@ -0,0 +1,94 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// dsyncFlag - O_DSYNC is not known to every FreeBSD release, the writes
+// in SyncModeDSync are opened with O_SYNC instead.
+const dsyncFlag = unix.O_SYNC
+
+// The advice of posix_fadvise(2).
+const (
+	fadvRandom     = 1
+	fadvSequential = 2
+)
+
+// statFS - the filesystem backing path.
+func statFS(path string) (fsStat, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return fsStat{}, err
+	}
+	return fsStat{
+		size:     st.Blocks * st.Bsize,
+		free:     st.Bfree * st.Bsize,
+		avail:    uint64(max(st.Bavail, 0)) * st.Bsize,
+		readOnly: st.Flags&unix.MNT_RDONLY == unix.MNT_RDONLY,
+	}, nil
+}
+
+// openDirect - opens the file at path with flag and O_DIRECT, UFS then
+// bypasses the buffer cache for aligned transfers, ZFS ignores it.
+func openDirect(path string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, unix.O_DIRECT|flag, perm)
+}
+
+// checkDeviceUnused - GEOM refuses to open the disk of a mounted
+// filesystem for writes, there is nothing to check up front.
+func checkDeviceUnused(path string) error {
+	return nil
+}
+
+// fdatasync - fdatasync(2), fsync(2) on releases before 11.1.
+func fdatasync(fd int) error {
+	_, _, errno := unix.Syscall(unix.SYS_FDATASYNC, uintptr(fd), 0, 0)
+	if errno == unix.ENOSYS {
+		return unix.Fsync(fd)
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// advise - declares the access pattern of the size bytes of every file
+// of the region, random or sequential.
+func (r region) advise(size int64, random bool) {
+	advice := fadvSequential
+	if random {
+		advice = fadvRandom
+	}
+	for _, f := range r.files {
+		unix.Fadvise(int(f.Fd()), r.base, size, advice)
+	}
+}
+
+// disableDirectIO - disables directio mode.
+func disableDirectIO(fd uintptr) error {
+	flag, err := unix.FcntlInt(fd, unix.F_GETFL, 0)
+	if err != nil {
+		return err
+	}
+	flag &= ^(unix.O_DIRECT)
+	_, err = unix.FcntlInt(fd, unix.F_SETFL, flag)
+	return err
+}
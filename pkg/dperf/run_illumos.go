@@ -0,0 +1,99 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// dsyncFlag - the open flag of the writes in SyncModeDSync.
+const dsyncFlag = unix.O_DSYNC
+
+// The ioctl behind directio(3C) and its advice, from <sys/filio.h> and
+// <sys/fcntl.h>.
+const (
+	fioDirectIO = 'f'<<8 | 76
+	directIOOn  = 1
+)
+
+// stReadOnly - ST_RDONLY of statvfs(2).
+const stReadOnly = 0x01
+
+// statFS - the filesystem backing path.
+func statFS(path string) (fsStat, error) {
+	var st unix.Statvfs_t
+	if err := unix.Statvfs(path, &st); err != nil {
+		return fsStat{}, err
+	}
+	return fsStat{
+		size:     st.Blocks * st.Frsize,
+		free:     st.Bfree * st.Frsize,
+		avail:    st.Bavail * st.Frsize,
+		readOnly: st.Flag&stReadOnly == stReadOnly,
+	}, nil
+}
+
+// openDirect - opens the file at path with flag and advises directio(3C)
+// on it, UFS then bypasses the page cache for aligned transfers. ZFS
+// does not support it and caches the blocks in the ARC.
+func openDirect(path string, flag int, perm os.FileMode) (*os.File, error) {
+	f, err := os.OpenFile(path, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	err = unix.IoctlSetInt(int(f.Fd()), fioDirectIO, directIOOn)
+	if err != nil && !errors.Is(err, unix.ENOTTY) {
+		f.Close()
+		return nil, &os.PathError{Op: "directio", Path: path, Err: err}
+	}
+	return f, nil
+}
+
+// alignedBlock - a block of blockSize bytes aligned to DirectioAlignSize,
+// github.com/ncw/directio does not build on illumos.
+func alignedBlock(blockSize int) []byte {
+	b := make([]byte, blockSize+DirectioAlignSize)
+	if blockSize == 0 {
+		return b[:0]
+	}
+	off := (DirectioAlignSize - int(uintptr(unsafe.Pointer(&b[0]))&(DirectioAlignSize-1))) % DirectioAlignSize
+	return b[off : off+blockSize : off+blockSize]
+}
+
+// checkDeviceUnused - O_EXCL does not detect a mounted device on illumos,
+// there is nothing to check up front.
+func checkDeviceUnused(path string) error {
+	return nil
+}
+
+// fdatasync - fdatasync(3C).
+func fdatasync(fd int) error {
+	return unix.Fdatasync(fd)
+}
+
+// advise - posix_fadvise(3C) is a no-op on illumos.
+func (r region) advise(_ int64, _ bool) {}
+
+// disableDirectIO - UFS transfers unaligned buffers through the page
+// cache by itself, they are written as they are.
+func disableDirectIO(fd uintptr) error {
+	return nil
+}
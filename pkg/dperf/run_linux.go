@@ -17,51 +17,122 @@
 package dperf
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"syscall"
-	"time"
 
-	"github.com/minio/pkg/v3/rng"
-	"github.com/ncw/directio"
 	"golang.org/x/sys/unix"
 )
 
-type nullWriter struct{}
+// dsyncFlag - the open flag of the writes in SyncModeDSync.
+const dsyncFlag = unix.O_DSYNC
 
-func (n nullWriter) Write(b []byte) (int, error) {
-	return len(b), nil
+// statFS - the filesystem backing path.
+func statFS(path string) (fsStat, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return fsStat{}, err
+	}
+	return fsStat{
+		size:     st.Blocks * uint64(st.Bsize),
+		free:     st.Bfree * uint64(st.Bsize),
+		avail:    st.Bavail * uint64(st.Bsize),
+		readOnly: st.Flags&unix.ST_RDONLY == unix.ST_RDONLY,
+	}, nil
 }
 
-func (d *DrivePerf) runReadTest(ctx context.Context, path string, data []byte) (uint64, error) {
-	startTime := time.Now()
-	r, err := os.OpenFile(path, syscall.O_DIRECT|os.O_RDONLY, 0o400)
-	if err != nil {
-		return 0, err
+// builtinEngine - the engine of a region ending at extent in its files
+// for the Engine of the run.
+func (d *DrivePerf) builtinEngine(extent int64) (IOEngine, error) {
+	switch d.Engine {
+	case EngineLibaio:
+		return newAIOEngine(d.AIODepth)
+	case EngineMmap:
+		return newMmapEngine(extent), nil
+	case EngineNVMe:
+		return &nvmeEngine{}, nil
+	}
+	if flags := d.rwFlags(); flags != 0 {
+		return rwfEngine{flags: flags}, nil
 	}
-	unix.Fadvise(int(r.Fd()), 0, int64(d.FileSize), unix.FADV_SEQUENTIAL)
+	return syncEngine{}, nil
+}
 
-	n, err := copyAligned(&nullWriter{}, r, data, int64(d.FileSize), r.Fd())
-	r.Close()
-	if err != nil {
-		return 0, err
+// rwFlags - the RWF_* flags of RWFlags.
+func (d *DrivePerf) rwFlags() int {
+	var flags int
+	for _, f := range d.RWFlags {
+		switch f {
+		case RWFlagDsync:
+			flags |= unix.RWF_DSYNC
+		case RWFlagHipri:
+			flags |= unix.RWF_HIPRI
+		}
 	}
-	if n != int64(d.FileSize) {
-		return 0, fmt.Errorf("Expected read %d, read %d", d.FileSize, n)
+	return flags
+}
+
+// rwfEngine - EngineSync transferring the blocks with preadv2 and
+// pwritev2 and flags.
+type rwfEngine struct {
+	syncEngine
+	flags int
+}
+
+func (e rwfEngine) ReadBlock(f *os.File, b []byte, off int64) (int, error) {
+	return rwfFull(f, b, off, "preadv2", func(fd int, b []byte, off int64) (int, error) {
+		return unix.Preadv2(fd, [][]byte{b}, off, e.flags)
+	})
+}
+
+func (e rwfEngine) WriteBlock(f *os.File, b []byte, off int64) (int, error) {
+	return rwfFull(f, b, off, "pwritev2", func(fd int, b []byte, off int64) (int, error) {
+		return unix.Pwritev2(fd, [][]byte{b}, off, e.flags)
+	})
+}
+
+// rwfFull - transfers all of b at off with op as ReadAt and WriteAt do,
+// short reads end with io.EOF.
+func rwfFull(f *os.File, b []byte, off int64, name string, op func(int, []byte, int64) (int, error)) (int, error) {
+	var done int
+	for done < len(b) {
+		n, err := op(int(f.Fd()), b[done:], off+int64(done))
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return done, &os.PathError{Op: name, Path: f.Name(), Err: err}
+		}
+		if n == 0 {
+			if name == "preadv2" {
+				return done, io.EOF
+			}
+			return done, io.ErrShortWrite
+		}
+		done += n
 	}
+	return done, nil
+}
 
-	dt := float64(time.Since(startTime))
-	throughputInSeconds := (float64(d.FileSize) / dt) * float64(time.Second)
-	return uint64(throughputInSeconds), nil
+// openDirect - opens the file at path with flag and O_DIRECT, its I/O
+// bypasses the page cache.
+func openDirect(path string, flag int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(path, syscall.O_DIRECT|flag, perm)
 }
 
-// alignedBlock - pass through to directio implementation.
-func alignedBlock(blockSize int) []byte {
-	return directio.AlignedBlock(blockSize)
+// checkDeviceUnused - returns an error if the block device at path is
+// mounted or otherwise held open exclusively, as by a filesystem.
+func checkDeviceUnused(path string) error {
+	f, err := os.OpenFile(path, os.O_RDONLY|unix.O_EXCL, 0)
+	if err != nil {
+		if errors.Is(err, syscall.EBUSY) {
+			return fmt.Errorf("block device %s is in use, it may be mounted", path)
+		}
+		return err
+	}
+	return f.Close()
 }
 
 // fdatasync - fdatasync() is similar to fsync(), but does not flush modified metadata
@@ -78,27 +149,20 @@ func fdatasync(fd int) error {
 	return syscall.Fdatasync(fd)
 }
 
-func fadviseSequential(f *os.File, length int64) error {
-	return unix.Fadvise(int(f.Fd()), 0, length, unix.FADV_SEQUENTIAL)
-}
-
-type nullReader struct {
-	ctx context.Context
-}
-
-func (n nullReader) Read(b []byte) (int, error) {
-	if n.ctx.Err() != nil {
-		return 0, n.ctx.Err()
+// advise - declares the access pattern of the size bytes of every file
+// of the region, random or sequential.
+func (r region) advise(size int64, random bool) {
+	advice := unix.FADV_SEQUENTIAL
+	if random {
+		advice = unix.FADV_RANDOM
+	}
+	for _, f := range r.files {
+		unix.Fadvise(int(f.Fd()), r.base, size, advice)
 	}
-	return len(b), nil
 }
 
-func newRandomReader(ctx context.Context) io.Reader {
-	r, err := rng.NewReader()
-	if err != nil {
-		panic(err)
-	}
-	return r
+func fadviseSequential(f *os.File, length int64) error {
+	return unix.Fadvise(int(f.Fd()), 0, length, unix.FADV_SEQUENTIAL)
 }
 
 // disableDirectIO - disables directio mode.
@@ -111,145 +175,3 @@ func disableDirectIO(fd uintptr) error {
 	_, err = unix.FcntlInt(fd, unix.F_SETFL, flag)
 	return err
 }
-
-// DirectioAlignSize - DirectIO alignment needs to be 4K. Defined here as
-// directio.AlignSize is defined as 0 in MacOS causing divide by 0 error.
-const DirectioAlignSize = 4096
-
-// copyAligned - copies from reader to writer using the aligned input
-// buffer, it is expected that input buffer is page aligned to
-// 4K page boundaries. Without passing aligned buffer may cause
-// this function to return error.
-//
-// This code is similar in spirit to io.Copy but it is only to be
-// used with DIRECT I/O based file descriptor and it is expected that
-// input writer *os.File not a generic io.Writer. Make sure to have
-// the file opened for writes with syscall.O_DIRECT flag.
-func copyAligned(w io.Writer, r io.Reader, alignedBuf []byte, totalSize int64, fd uintptr) (int64, error) {
-	if totalSize == 0 {
-		return 0, nil
-	}
-
-	var written int64
-	for {
-		buf := alignedBuf
-		if totalSize > 0 {
-			remaining := totalSize - written
-			if remaining < int64(len(buf)) {
-				buf = buf[:remaining]
-			}
-		}
-
-		if len(buf)%DirectioAlignSize != 0 {
-			// Disable O_DIRECT on fd's on unaligned buffer
-			// perform an amortized Fdatasync(fd) on the fd at
-			// the end, this is performed by the caller before
-			// closing 'w'.
-			if err := disableDirectIO(fd); err != nil {
-				return written, err
-			}
-		}
-
-		nr, err := io.ReadFull(r, buf)
-		eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
-		if err != nil && !eof {
-			return written, err
-		}
-
-		buf = buf[:nr]
-		var (
-			n  int
-			un int
-			nw int64
-		)
-
-		remain := len(buf) % DirectioAlignSize
-		if remain == 0 {
-			// buf is aligned for directio write()
-			n, err = w.Write(buf)
-			nw = int64(n)
-		} else {
-			if remain < len(buf) {
-				n, err = w.Write(buf[:len(buf)-remain])
-				if err != nil {
-					return written, err
-				}
-				nw = int64(n)
-			}
-
-			// Disable O_DIRECT on fd's on unaligned buffer
-			// perform an amortized Fdatasync(fd) on the fd at
-			// the end, this is performed by the caller before
-			// closing 'w'.
-			if err = disableDirectIO(fd); err != nil {
-				return written, err
-			}
-
-			// buf is not aligned, hence use writeUnaligned()
-			// for the remainder
-			un, err = w.Write(buf[len(buf)-remain:])
-			nw += int64(un)
-		}
-
-		if nw > 0 {
-			written += nw
-		}
-
-		if err != nil {
-			return written, err
-		}
-
-		if nw != int64(len(buf)) {
-			return written, io.ErrShortWrite
-		}
-
-		if totalSize > 0 && written == totalSize {
-			// we have written the entire stream, return right here.
-			return written, nil
-		}
-
-		if eof {
-			// We reached EOF prematurely but we did not write everything
-			// that we promised that we would write.
-			if totalSize > 0 && written != totalSize {
-				return written, io.ErrUnexpectedEOF
-			}
-			return written, nil
-		}
-	}
-}
-
-func (d *DrivePerf) runWriteTest(ctx context.Context, path string, data []byte) (uint64, error) {
-	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
-		return 0, err
-	}
-
-	startTime := time.Now()
-	w, err := os.OpenFile(path, syscall.O_DIRECT|os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
-	if err != nil {
-		return 0, err
-	}
-
-	n, err := copyAligned(w, newRandomReader(ctx), data, int64(d.FileSize), w.Fd())
-	if err != nil {
-		w.Close()
-		return 0, err
-	}
-
-	if n != int64(d.FileSize) {
-		w.Close()
-		return 0, fmt.Errorf("Expected to write %d, wrote %d bytes", d.FileSize, n)
-	}
-
-	if err := fdatasync(int(w.Fd())); err != nil {
-		return 0, err
-	}
-
-	if err := w.Close(); err != nil {
-		return 0, err
-	}
-
-	dt := float64(time.Since(startTime))
-	throughputInSeconds := (float64(d.FileSize) / dt) * float64(time.Second)
-	return uint64(throughputInSeconds), nil
-}
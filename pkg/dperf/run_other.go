@@ -1,5 +1,5 @@
-//go:build !linux
-// +build !linux
+//go:build !linux && !darwin && !freebsd && !illumos
+// +build !linux,!darwin,!freebsd,!illumos
 
 // This file is part of MinIO dperf
 // Copyright (c) 2021 MinIO, Inc.
@@ -19,16 +19,68 @@
 
 package dperf
 
-import "context"
+import (
+	"context"
+	"io"
+	"os"
+)
 
-func (d *DrivePerf) runReadTest(ctx context.Context, path string, _ []byte) (uint64, error) {
-	return 0, ErrNotImplemented
+func (d *DrivePerf) runReadTest(ctx context.Context, path string, _ []byte, _ uint64, _ *ioStats, _ *ioProgress) (ioResult, error) {
+	return ioResult{}, ErrNotImplemented
 }
 
-func (d *DrivePerf) runWriteTest(ctx context.Context, path string, _ []byte) (uint64, error) {
-	return 0, ErrNotImplemented
+func (d *DrivePerf) runWriteTest(ctx context.Context, path string, _ []byte, _ io.Reader, _ *ioStats, _ *ioProgress) (ioResult, error) {
+	return ioResult{}, ErrNotImplemented
+}
+
+func (d *DrivePerf) runMixedTest(ctx context.Context, path string, _ []byte, _ io.Reader, _, _ *ioStats, _ *ioProgress) (ioResult, ioResult, error) {
+	return ioResult{}, ioResult{}, ErrNotImplemented
+}
+
+func openDirect(path string, _ int, _ os.FileMode) (*os.File, error) {
+	return nil, ErrNotImplemented
+}
+
+func fdatasync(fd int) error {
+	return ErrNotImplemented
+}
+
+func writeObject(path string, _ []byte) error {
+	return ErrNotImplemented
+}
+
+func readObject(path string, _ []byte) error {
+	return ErrNotImplemented
+}
+
+func writePart(path string, _ []byte, _ io.Reader, _ int64) error {
+	return ErrNotImplemented
+}
+
+func readPart(path string, _ []byte) error {
+	return ErrNotImplemented
 }
 
 func alignedBlock(blockSize int) []byte {
 	return make([]byte, 0)
 }
+
+func isReadOnlyFS(path string) (bool, error) {
+	return false, nil
+}
+
+func freeSpace(path string) (uint64, error) {
+	return 0, ErrNotImplemented
+}
+
+func diskUsage(path string) (uint64, uint64, error) {
+	return 0, 0, ErrNotImplemented
+}
+
+func checkDeviceUnused(path string) error {
+	return nil
+}
+
+func kernelVersion() string {
+	return ""
+}
@@ -0,0 +1,431 @@
+//go:build linux || darwin || freebsd || illumos
+// +build linux darwin freebsd illumos
+
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+type nullWriter struct{}
+
+func (n nullWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (d *DrivePerf) runReadTest(ctx context.Context, path string, data []byte, size uint64, stats *ioStats, progress *ioProgress) (ioResult, error) {
+	startTime := time.Now()
+	deadline := d.deadline(startTime)
+	// Cached reads go through the page cache.
+	rg, err := d.openRegion(path, os.O_RDONLY, 0o400, !d.cached, stats.worker)
+	if err != nil {
+		return ioResult{}, err
+	}
+	defer rg.Close()
+	rg.advise(int64(size), d.random())
+	stats.engine(rg.engine)
+
+	// Timed phases reread the file until the deadline.
+	var read uint64
+	for {
+		var in io.Reader = &fileAt{f: rg}
+		if d.random() {
+			in = newOffsetReader(rg, d.blockOffsets(size, stats.worker), d.BlockSize, size)
+		}
+		n, err := copyAligned(progress.writer(&nullWriter{}), untilDeadline(stats.verifier(stats.reader(in)), deadline), data, int64(size), rg.Fd())
+		read += uint64(n)
+		if errors.Is(err, errDeadline) {
+			break
+		}
+		if err != nil {
+			return ioResult{}, err
+		}
+		if n != int64(size) {
+			return ioResult{}, fmt.Errorf("Expected read %d, read %d", size, n)
+		}
+		if !another(deadline) {
+			break
+		}
+	}
+	progress.finish()
+
+	return stats.result(read, time.Since(startTime)), nil
+}
+
+// fsStat - the capacity, free and available bytes of a filesystem, and
+// whether it is mounted read-only.
+type fsStat struct {
+	size, free, avail uint64
+	readOnly          bool
+}
+
+// isReadOnlyFS - reports if the filesystem backing path is mounted read-only.
+func isReadOnlyFS(path string) (bool, error) {
+	st, err := statFS(path)
+	return st.readOnly, err
+}
+
+// freeSpace - bytes available to unprivileged users on the filesystem
+// backing path.
+func freeSpace(path string) (uint64, error) {
+	st, err := statFS(path)
+	return st.avail, err
+}
+
+// syncFlag - the open flag of the writes in SyncMode.
+func (d *DrivePerf) syncFlag() int {
+	switch d.SyncMode {
+	case SyncModeDSync:
+		return dsyncFlag
+	case SyncModeSync:
+		return unix.O_SYNC
+	}
+	return 0
+}
+
+// diskUsage - capacity and free bytes of the filesystem backing path.
+func diskUsage(path string) (uint64, uint64, error) {
+	st, err := statFS(path)
+	return st.size, st.free, err
+}
+
+type nullReader struct {
+	ctx context.Context
+}
+
+func (n nullReader) Read(b []byte) (int, error) {
+	if n.ctx.Err() != nil {
+		return 0, n.ctx.Err()
+	}
+	return len(b), nil
+}
+
+// copyAligned - copies from reader to writer using the aligned input
+// buffer, it is expected that input buffer is page aligned to
+// 4K page boundaries. Without passing aligned buffer may cause
+// this function to return error.
+//
+// This code is similar in spirit to io.Copy but it is only to be
+// used with DIRECT I/O based file descriptor and it is expected that
+// input writer *os.File not a generic io.Writer. Make sure to have
+// the file opened for writes with openDirect().
+func copyAligned(w io.Writer, r io.Reader, alignedBuf []byte, totalSize int64, fd uintptr) (int64, error) {
+	if totalSize == 0 {
+		return 0, nil
+	}
+
+	var written int64
+	for {
+		buf := alignedBuf
+		if totalSize > 0 {
+			remaining := totalSize - written
+			if remaining < int64(len(buf)) {
+				buf = buf[:remaining]
+			}
+		}
+
+		if len(buf)%DirectioAlignSize != 0 {
+			// Disable O_DIRECT on fd's on unaligned buffer
+			// perform an amortized Fdatasync(fd) on the fd at
+			// the end, this is performed by the caller before
+			// closing 'w'.
+			if err := disableDirectIO(fd); err != nil {
+				return written, err
+			}
+		}
+
+		nr, err := io.ReadFull(r, buf)
+		eof := errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+		if err != nil && !eof {
+			return written, err
+		}
+
+		buf = buf[:nr]
+		var (
+			n  int
+			un int
+			nw int64
+		)
+
+		remain := len(buf) % DirectioAlignSize
+		if remain == 0 {
+			// buf is aligned for directio write()
+			n, err = w.Write(buf)
+			nw = int64(n)
+		} else {
+			if remain < len(buf) {
+				n, err = w.Write(buf[:len(buf)-remain])
+				if err != nil {
+					return written, err
+				}
+				nw = int64(n)
+			}
+
+			// Disable O_DIRECT on fd's on unaligned buffer
+			// perform an amortized Fdatasync(fd) on the fd at
+			// the end, this is performed by the caller before
+			// closing 'w'.
+			if err = disableDirectIO(fd); err != nil {
+				return written, err
+			}
+
+			// buf is not aligned, hence use writeUnaligned()
+			// for the remainder
+			un, err = w.Write(buf[len(buf)-remain:])
+			nw += int64(un)
+		}
+
+		if nw > 0 {
+			written += nw
+		}
+
+		if err != nil {
+			return written, err
+		}
+
+		if nw != int64(len(buf)) {
+			return written, io.ErrShortWrite
+		}
+
+		if totalSize > 0 && written == totalSize {
+			// we have written the entire stream, return right here.
+			return written, nil
+		}
+
+		if eof {
+			// We reached EOF prematurely but we did not write everything
+			// that we promised that we would write.
+			if totalSize > 0 && written != totalSize {
+				return written, io.ErrUnexpectedEOF
+			}
+			return written, nil
+		}
+	}
+}
+
+func (d *DrivePerf) runWriteTest(ctx context.Context, path string, data []byte, src io.Reader, stats *ioStats, progress *ioProgress) (ioResult, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return ioResult{}, err
+	}
+
+	startTime := time.Now()
+	deadline := d.deadline(startTime)
+	flag := d.syncFlag() | os.O_RDWR | os.O_CREATE
+	if d.WriteMode != WriteModeOverwrite && !isBlockDevice(path) {
+		flag |= os.O_TRUNC
+	}
+	rg, err := d.openRegion(path, flag, 0o600, true, stats.worker)
+	if err != nil {
+		return ioResult{}, err
+	}
+	stats.engine(rg.engine)
+
+	// Timed phases rewrite the file until the deadline, the first pass
+	// always completes so that the file can be read back.
+	var written uint64
+	for pass := 0; ; pass++ {
+		if rg.zones != nil {
+			// Every pass writes the zones from their start.
+			if err := rg.resetZones(d.FileSize); err != nil {
+				rg.Close()
+				return ioResult{}, err
+			}
+		}
+		var out io.Writer = &fileAt{f: rg}
+		if d.random() {
+			out = newOffsetWriter(rg, d.blockOffsets(d.FileSize, stats.worker), d.BlockSize, d.FileSize)
+		}
+		fw := stats.syncer(stats.stamper(stats.writer(out)), d.SyncEvery, rg.sync)
+		in := src
+		if pass > 0 {
+			in = untilDeadline(src, deadline)
+		}
+		n, err := copyAligned(progress.writer(fw), in, data, int64(d.FileSize), rg.Fd())
+		written += uint64(n)
+		if errors.Is(err, errDeadline) {
+			break
+		}
+		if err != nil {
+			rg.Close()
+			return ioResult{}, err
+		}
+
+		if n != int64(d.FileSize) {
+			rg.Close()
+			return ioResult{}, fmt.Errorf("Expected to write %d, wrote %d bytes", d.FileSize, n)
+		}
+		if !another(deadline) {
+			break
+		}
+	}
+	progress.finish()
+
+	if err := rg.sync(); err != nil {
+		return ioResult{}, err
+	}
+
+	if err := rg.Close(); err != nil {
+		return ioResult{}, err
+	}
+
+	return stats.result(written, time.Since(startTime)), nil
+}
+
+// runMixedTest - reads and overwrites the blocks of the file at path in
+// one pass, returns the results of the reads and of the writes.
+func (d *DrivePerf) runMixedTest(ctx context.Context, path string, data []byte, src io.Reader, readStats, writeStats *ioStats, progress *ioProgress) (ioResult, ioResult, error) {
+	// The reads and writes of the worker share its rate.
+	writeStats.limit = readStats.limit
+	startTime := time.Now()
+	rg, err := d.openRegion(path, d.syncFlag()|os.O_RDWR, 0o600, true, readStats.worker)
+	if err != nil {
+		return ioResult{}, ioResult{}, err
+	}
+	defer rg.Close()
+	rg.advise(int64(d.FileSize), true)
+	readStats.engine(rg.engine)
+	writeStats.engine(rg.engine)
+
+	at := &fileAt{f: rg}
+	r := readStats.verifier(readStats.reader(at))
+	w := writeStats.syncer(writeStats.stamper(writeStats.writer(at)), d.SyncEvery, rg.sync)
+	p := progress.writer(&nullWriter{})
+	deadline := d.deadline(startTime)
+	var read, written uint64
+	// Timed phases walk the file again until the deadline.
+	for pass := true; pass; pass = another(deadline) {
+		for _, op := range d.mixedOps(d.FileSize, readStats.worker) {
+			if err := ctx.Err(); err != nil {
+				return ioResult{}, ioResult{}, err
+			}
+			if !deadline.IsZero() && !time.Now().Before(deadline) {
+				break
+			}
+			buf := data[:min(d.BlockSize, d.FileSize-uint64(op.offset))]
+			at.off = op.offset
+			if op.read {
+				if _, err = io.ReadFull(r, buf); err != nil {
+					return ioResult{}, ioResult{}, err
+				}
+				read += uint64(len(buf))
+			} else {
+				if _, err = io.ReadFull(src, buf); err != nil {
+					return ioResult{}, ioResult{}, err
+				}
+				if _, err = w.Write(buf); err != nil {
+					return ioResult{}, ioResult{}, err
+				}
+				written += uint64(len(buf))
+			}
+			p.Write(buf)
+		}
+	}
+	progress.finish()
+
+	if written > 0 {
+		if err := rg.sync(); err != nil {
+			return ioResult{}, ioResult{}, err
+		}
+	}
+	elapsed := time.Since(startTime)
+	return readStats.result(read, elapsed), writeStats.result(written, elapsed), nil
+}
+
+// writeObject - writes data to a new file at path with O_DIRECT and
+// syncs it, as MinIO does with the parts of an object.
+func writeObject(path string, data []byte) error {
+	f, err := openDirect(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = fdatasync(int(f.Fd())); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writePart - writes size bytes of src to a new file at path with
+// O_DIRECT through buf, the unaligned tail without, and syncs it, as
+// MinIO writes the parts of objects.
+func writePart(path string, buf []byte, src io.Reader, size int64) error {
+	f, err := openDirect(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = copyAligned(f, src, buf, size, f.Fd()); err != nil {
+		f.Close()
+		return err
+	}
+	if err = fdatasync(int(f.Fd())); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readPart - reads the file at path through buf with O_DIRECT.
+func readPart(path string, buf []byte) error {
+	f, err := openDirect(path, os.O_RDONLY, 0o400)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for {
+		_, err := f.Read(buf)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readObject - reads the file at path into data with O_DIRECT.
+func readObject(path string, data []byte) error {
+	f, err := openDirect(path, os.O_RDONLY, 0o400)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.ReadFull(f, data)
+	return err
+}
+
+// kernelVersion - release of the running kernel.
+func kernelVersion() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(uts.Release[:])
+}
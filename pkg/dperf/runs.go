@@ -0,0 +1,157 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// RunStats spread of the throughput and IOPS of a drive over the runs of
+// DrivePerf.Runs
+type RunStats struct {
+	Runs            int         `json:"runs"`
+	WriteThroughput MetricStats `json:"writeThroughput"`
+	ReadThroughput  MetricStats `json:"readThroughput"`
+	WriteIOPS       MetricStats `json:"writeIOPS"`
+	ReadIOPS        MetricStats `json:"readIOPS"`
+}
+
+// MetricStats summary of a metric measured once per run
+type MetricStats struct {
+	Mean uint64 `json:"mean"`
+	Min  uint64 `json:"min"`
+	Max  uint64 `json:"max"`
+	// StdDev is the sample standard deviation of Values.
+	StdDev uint64 `json:"stddev"`
+	// Values of the individual runs, in order.
+	Values []uint64 `json:"values"`
+}
+
+// newMetricStats - summarizes the values of a metric over the runs.
+func newMetricStats(values []uint64) MetricStats {
+	ms := MetricStats{
+		Min:    values[0],
+		Max:    values[0],
+		Values: values,
+	}
+	var sum uint64
+	for _, v := range values {
+		ms.Min = min(ms.Min, v)
+		ms.Max = max(ms.Max, v)
+		sum += v
+	}
+	mean := float64(sum) / float64(len(values))
+	ms.Mean = uint64(mean)
+	if len(values) > 1 {
+		var sq float64
+		for _, v := range values {
+			d := float64(v) - mean
+			sq += d * d
+		}
+		ms.StdDev = uint64(math.Sqrt(sq / float64(len(values)-1)))
+	}
+	return ms
+}
+
+// newRunStats - spread of the metrics of results, one per run.
+func newRunStats(results []*DrivePerfResult) *RunStats {
+	metric := func(value func(*DrivePerfResult) uint64) MetricStats {
+		values := make([]uint64, len(results))
+		for i, result := range results {
+			values[i] = value(result)
+		}
+		return newMetricStats(values)
+	}
+	return &RunStats{
+		Runs:            len(results),
+		WriteThroughput: metric(func(r *DrivePerfResult) uint64 { return r.WriteThroughput }),
+		ReadThroughput:  metric(func(r *DrivePerfResult) uint64 { return r.ReadThroughput }),
+		WriteIOPS:       metric(func(r *DrivePerfResult) uint64 { return r.WriteIOPS }),
+		ReadIOPS:        metric(func(r *DrivePerfResult) uint64 { return r.ReadIOPS }),
+	}
+}
+
+// runRepeated - tests the drive at path Runs times, the result is that
+// of the last run with the throughput and IOPS averaged over all runs.
+// The first failed run fails the drive.
+func (d *DrivePerf) runRepeated(ctx context.Context, path string, testUUID string) *DrivePerfResult {
+	if d.Runs <= 1 {
+		return d.runTests(ctx, path, testUUID)
+	}
+	results := make([]*DrivePerfResult, 0, d.Runs)
+	for i := 0; i < d.Runs; i++ {
+		dr := d.runTests(ctx, path, testUUID)
+		if dr.Error != nil {
+			return dr
+		}
+		results = append(results, dr)
+	}
+	dr := results[len(results)-1]
+	dr.Runs = newRunStats(results)
+	dr.WriteThroughput = dr.Runs.WriteThroughput.Mean
+	dr.ReadThroughput = dr.Runs.ReadThroughput.Mean
+	dr.WriteIOPS = dr.Runs.WriteIOPS.Mean
+	dr.ReadIOPS = dr.Runs.ReadIOPS.Mean
+	return dr
+}
+
+// runCells - mean, min, max and standard deviation of every metric of
+// the drives tested more than once, the first row is the header.
+func (r *Report) runCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"METRIC",
+		"MEAN",
+		"MIN",
+		"MAX",
+		"STDDEV",
+	}}
+	iops := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	for _, result := range r.Results {
+		rs := result.Runs
+		if rs == nil {
+			continue
+		}
+		for _, m := range []struct {
+			name   string
+			stats  MetricStats
+			format func(uint64) string
+		}{
+			{"write", rs.WriteThroughput, formatRate},
+			{"read", rs.ReadThroughput, formatRate},
+			{"write IOPS", rs.WriteIOPS, iops},
+			{"read IOPS", rs.ReadIOPS, iops},
+		} {
+			stddev := m.format(m.stats.StdDev)
+			if m.stats.Mean > 0 {
+				stddev = fmt.Sprintf("%s (%.1f%%)", stddev, float64(m.stats.StdDev)/float64(m.stats.Mean)*100)
+			}
+			cellText = append(cellText, []string{
+				result.Path,
+				m.name,
+				m.format(m.stats.Mean),
+				m.format(m.stats.Min),
+				m.format(m.stats.Max),
+				stddev,
+			})
+		}
+	}
+	return cellText
+}
@@ -0,0 +1,141 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScoreWeights weights of the components of the composite score of a
+// drive, only their ratio matters
+type ScoreWeights struct {
+	Throughput float64 `json:"throughput"`
+	IOPS       float64 `json:"iops"`
+	Latency    float64 `json:"latency"`
+}
+
+// DefaultScoreWeights favor throughput, then IOPS, then tail latency.
+var DefaultScoreWeights = ScoreWeights{Throughput: 5, IOPS: 3, Latency: 2}
+
+// ParseScoreWeights parses weights of the form
+// "throughput=5,iops=3,latency=2", components left out weigh 0.
+func ParseScoreWeights(s string) (ScoreWeights, error) {
+	var w ScoreWeights
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		if !ok {
+			return w, fmt.Errorf("expected name=weight: %q", kv)
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			return w, fmt.Errorf("invalid weight of %s: %q", k, v)
+		}
+		switch k {
+		case "throughput":
+			w.Throughput = f
+		case "iops":
+			w.IOPS = f
+		case "latency":
+			w.Latency = f
+		default:
+			return w, fmt.Errorf("unknown score component %q, must be one of throughput, iops, latency", k)
+		}
+	}
+	if w.Throughput+w.IOPS+w.Latency == 0 {
+		return w, fmt.Errorf("at least one weight must be greater than 0")
+	}
+	return w, nil
+}
+
+// scoreDrives - sets the composite score of every drive, from 0 to 100,
+// relative to the best drive of the run for each component: the sum of
+// the write and read throughput, the sum of the write and read IOPS, and
+// the worst of the write and read p99 latency, lower being better.
+func scoreDrives(results []*DrivePerfResult, w ScoreWeights) {
+	var bestThroughput, bestIOPS uint64
+	var bestLatency time.Duration
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		bestThroughput = max(bestThroughput, result.WriteThroughput+result.ReadThroughput)
+		bestIOPS = max(bestIOPS, result.WriteIOPS+result.ReadIOPS)
+		if l := tailLatency(result); l > 0 && (bestLatency == 0 || l < bestLatency) {
+			bestLatency = l
+		}
+	}
+	total := w.Throughput + w.IOPS + w.Latency
+	for _, result := range results {
+		if result.Error != nil {
+			continue
+		}
+		var score float64
+		if bestThroughput > 0 {
+			score += w.Throughput * float64(result.WriteThroughput+result.ReadThroughput) / float64(bestThroughput)
+		}
+		if bestIOPS > 0 {
+			score += w.IOPS * float64(result.WriteIOPS+result.ReadIOPS) / float64(bestIOPS)
+		}
+		if l := tailLatency(result); l > 0 {
+			score += w.Latency * float64(bestLatency) / float64(l)
+		}
+		score = score / total * 100
+		result.Score = &score
+	}
+}
+
+// tailLatency - the worst p99 latency of the phases of a drive, 0 if
+// none was recorded.
+func tailLatency(result *DrivePerfResult) time.Duration {
+	var l time.Duration
+	for _, pl := range []*LatencyStats{result.WriteLatency, result.ReadLatency} {
+		if pl != nil {
+			l = max(l, pl.P99)
+		}
+	}
+	return l
+}
+
+// sortResults - sorts the results by score when scored, by read
+// throughput otherwise, best first.
+func sortResults(results []*DrivePerfResult, scored bool) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if scored {
+			return scoreOf(results[i]) > scoreOf(results[j])
+		}
+		return results[i].ReadThroughput > results[j].ReadThroughput
+	})
+}
+
+func scoreOf(result *DrivePerfResult) float64 {
+	if result.Score == nil {
+		return -1
+	}
+	return *result.Score
+}
+
+// formatScore - score of a drive, "-" if not scored.
+func formatScore(score *float64) string {
+	if score == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", *score)
+}
@@ -0,0 +1,55 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+)
+
+// WriteSeriesCSV writes the throughput over time of every drive to file
+// as CSV, one path,phase,elapsed_seconds,bytes_per_second row per sample.
+func WriteSeriesCSV(file string, report *Report) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err = writeSeriesCSV(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func writeSeriesCSV(w io.Writer, report *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"path", "phase", "elapsed_seconds", "bytes_per_second"})
+	for _, result := range report.Results {
+		for _, p := range result.Series {
+			cw.Write([]string{
+				result.Path,
+				string(p.Phase),
+				strconv.FormatFloat(p.Elapsed.Seconds(), 'f', 3, 64),
+				strconv.FormatUint(p.Throughput, 10),
+			})
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
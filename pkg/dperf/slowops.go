@@ -0,0 +1,120 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SlowOp a block operation among the slowest of its drive
+type SlowOp struct {
+	// Time the operation started.
+	Time  time.Time `json:"time"`
+	Phase Phase     `json:"phase"`
+	// Worker is the index of the I/O worker, Offset the position of the
+	// operation in the file of the worker.
+	Worker  int           `json:"worker"`
+	Offset  uint64        `json:"offset"`
+	Latency time.Duration `json:"latency"`
+}
+
+// slowOps - the slowest operations of an I/O worker or a drive, up to n
+// of them.
+type slowOps struct {
+	n   int
+	ops []SlowOp
+	// fastest is the index of the fastest of ops, replaced first.
+	fastest int
+}
+
+func (s *slowOps) add(op SlowOp) {
+	if s.n <= 0 {
+		return
+	}
+	if len(s.ops) < s.n {
+		s.ops = append(s.ops, op)
+		if op.Latency < s.ops[s.fastest].Latency {
+			s.fastest = len(s.ops) - 1
+		}
+		return
+	}
+	if op.Latency <= s.ops[s.fastest].Latency {
+		return
+	}
+	s.ops[s.fastest] = op
+	for i, o := range s.ops {
+		if o.Latency < s.ops[s.fastest].Latency {
+			s.fastest = i
+		}
+	}
+}
+
+// mergeSlowOps - the n slowest of ops, sorted from the slowest.
+func mergeSlowOps(n int, ops ...[]SlowOp) []SlowOp {
+	var all []SlowOp
+	for _, o := range ops {
+		all = append(all, o...)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Latency > all[j].Latency
+	})
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
+
+// slowestOps - the slowest operations of the phases of a drive, nil when
+// they are not recorded.
+func (d *DrivePerf) slowestOps(phases ...phaseResult) []SlowOp {
+	if d.SlowestOps <= 0 {
+		return nil
+	}
+	var ops [][]SlowOp
+	for _, pr := range phases {
+		ops = append(ops, pr.slowest...)
+	}
+	return mergeSlowOps(d.SlowestOps, ops...)
+}
+
+// slowOpCells - slowest operations of every drive, the first row is the
+// header.
+func (r *Report) slowOpCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"PHASE",
+		"TIME",
+		"WORKER",
+		"OFFSET",
+		"LATENCY",
+	}}
+	for _, result := range r.Results {
+		for _, op := range result.SlowestOps {
+			cellText = append(cellText, []string{
+				result.Path,
+				string(op.Phase),
+				op.Time.Local().Format("15:04:05.000"),
+				strconv.Itoa(op.Worker),
+				FormatBytes(op.Offset),
+				formatLatency(op.Latency),
+			})
+		}
+	}
+	return cellText
+}
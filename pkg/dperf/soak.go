@@ -0,0 +1,126 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// soakAndRender - repeats write and read cycles against paths until Soak
+// elapses, every cycle is rendered and published as a report of its own.
+// Table output ends with the spread of the throughput and IOPS of every
+// drive over the cycles. The thresholds missed in every cycle, or else the
+// first cycle failed on every drive, are returned once the soak is over. MaxWrite bounds the writes of all the cycles, the
+// soak ends early before a cycle would exceed it.
+func (d *DrivePerf) soakAndRender(ctx context.Context, paths ...string) error {
+	start := time.Now()
+	deadline := start.Add(d.Soak)
+	results := make(map[string][]*DrivePerfResult, len(paths))
+	failed := make(map[string]int, len(paths))
+	var cycles int
+	var written uint64
+	var violations []string
+	var errFailed error
+	for time.Now().Before(deadline) {
+		if d.MaxWrite > 0 {
+			planned, err := d.plannedWrite(paths)
+			if err != nil {
+				return err
+			}
+			if written+planned > d.MaxWrite {
+				if cycles == 0 {
+					return ErrWriteBudgetExceeded
+				}
+				if d.Output == OutputTable && d.Format == nil {
+					fmt.Fprintf(d.out(), "Soak stopped, cycle %d would write more than %s in total\n", cycles+1, FormatBytes(d.MaxWrite))
+				}
+				break
+			}
+			written += planned
+		}
+		report, err := d.runReport(ctx, paths...)
+		if err != nil {
+			if cycles > 0 {
+				d.renderSoak(paths, results, failed, cycles, time.Since(start))
+			}
+			return err
+		}
+		cycles++
+		report.Cycle = cycles
+		if d.Output == OutputTable && d.Format == nil {
+			fmt.Fprintf(d.out(), "Cycle %d, %s of %s\n", cycles, time.Since(start).Round(time.Second), d.Soak)
+		}
+		if err = d.render(report); err != nil {
+			return err
+		}
+		if d.Publish != nil {
+			if err = d.Publish(report); err != nil {
+				return err
+			}
+		}
+		for _, v := range d.Thresholds.Violations(report) {
+			violations = append(violations, fmt.Sprintf("cycle %d: %s", cycles, v))
+		}
+		if err = report.allFailed(); err != nil && errFailed == nil {
+			errFailed = fmt.Errorf("cycle %d: %w", cycles, err)
+		}
+		for _, result := range report.Results {
+			if result.Error != nil {
+				failed[result.Path]++
+				continue
+			}
+			results[result.Path] = append(results[result.Path], result)
+		}
+	}
+	if err := d.renderSoak(paths, results, failed, cycles, time.Since(start)); err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &ThresholdError{Violations: violations}
+	}
+	return errFailed
+}
+
+// renderSoak - renders the spread of the metrics of every drive over the
+// cycles of a soak, only for table output.
+func (d *DrivePerf) renderSoak(paths []string, results map[string][]*DrivePerfResult, failed map[string]int, cycles int, elapsed time.Duration) error {
+	if d.Output != OutputTable || d.Format != nil {
+		return nil
+	}
+	w := d.out()
+	fmt.Fprintf(w, "\nSoak of %d cycles in %s\n", cycles, elapsed.Round(time.Second))
+	summary := &Report{}
+	for _, path := range paths {
+		if len(results[path]) == 0 {
+			continue
+		}
+		summary.Results = append(summary.Results, &DrivePerfResult{Path: path, Runs: newRunStats(results[path])})
+	}
+	if cellText := summary.runCells(); len(cellText) > 1 {
+		if err := displayTable(w, cellText); err != nil {
+			return err
+		}
+	}
+	for _, path := range paths {
+		if failed[path] > 0 {
+			fmt.Fprintf(w, "%s failed %d of %d cycles\n", path, failed[path], cycles)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// DriveSpec expected throughput of a drive in bytes/sec, zero if unknown
+type DriveSpec struct {
+	Write uint64 `json:"write,omitempty"`
+	Read  uint64 `json:"read,omitempty"`
+}
+
+// SpecResult throughput of a drive relative to its spec
+type SpecResult struct {
+	DriveSpec
+	// WritePercent and ReadPercent are the measured throughput in percent
+	// of the spec, zero when the spec or the measurement is missing.
+	WritePercent float64 `json:"writePercent,omitempty"`
+	ReadPercent  float64 `json:"readPercent,omitempty"`
+}
+
+// DefaultSpec is the key of the DrivePerf.Specs entry used for drives
+// whose model has no entry of its own.
+const DefaultSpec = "*"
+
+// ReadSpecFile reads drive specs from a CSV file of "model,write,read"
+// lines, throughputs are byte sizes per second such as "3GiB" or "3.2GB",
+// an empty throughput has no expectation. Lines starting with '#' are
+// ignored and the model '*' applies to every other drive.
+func ReadSpecFile(file string) (map[string]DriveSpec, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+
+	specs := make(map[string]DriveSpec)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return specs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		line, _ := r.FieldPos(0)
+		var spec DriveSpec
+		if spec.Write, err = parseSpecThroughput(record[1]); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid write throughput: %w", file, line, err)
+		}
+		if spec.Read, err = parseSpecThroughput(record[2]); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid read throughput: %w", file, line, err)
+		}
+		specs[specKey(record[0])] = spec
+	}
+}
+
+func parseSpecThroughput(s string) (uint64, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "/s")
+	if s == "" {
+		return 0, nil
+	}
+	return humanize.ParseBytes(s)
+}
+
+// specKey - models are matched ignoring case and surrounding spaces.
+func specKey(model string) string {
+	return strings.ToLower(strings.TrimSpace(model))
+}
+
+// specResult - compares a result to the spec of its drive model, nil if
+// there is no spec for it.
+func (d *DrivePerf) specResult(result *DrivePerfResult) *SpecResult {
+	spec, ok := d.Specs[specKey(result.Model)]
+	if !ok || result.Model == "" {
+		spec, ok = d.Specs[DefaultSpec]
+	}
+	if !ok || spec == (DriveSpec{}) || result.Error != nil {
+		return nil
+	}
+	return &SpecResult{
+		DriveSpec:    spec,
+		WritePercent: percentOf(result.WriteThroughput, spec.Write),
+		ReadPercent:  percentOf(result.ReadThroughput, spec.Read),
+	}
+}
+
+func percentOf(v, of uint64) float64 {
+	if v == 0 || of == 0 {
+		return 0
+	}
+	return float64(v) / float64(of) * 100
+}
+
+// percentOfSpec - "87%" of the spec, "-" if unknown.
+func percentOfSpec(percent float64) string {
+	if percent == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", percent)
+}
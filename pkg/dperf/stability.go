@@ -0,0 +1,182 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// UnstableCoV - a drive is flagged unstable when the coefficient of
+// variation of its throughput over time exceeds this percentage.
+const UnstableCoV = 25
+
+// minStabilitySamples - phases with fewer full seconds are too short to tell.
+const minStabilitySamples = 3
+
+// DefaultMaxDegradation - a drive is flagged degraded when the throughput
+// at the end of a phase is lower than at its start by more than this
+// percentage, unless DrivePerf.MaxDegradation says otherwise.
+const DefaultMaxDegradation = 20
+
+// minDegradationSamples - phases with fewer full seconds are too short to
+// compare their start with their end.
+const minDegradationSamples = 8
+
+// Stability variation of the throughput of a drive over time, as the
+// coefficient of variation (stddev / mean) of its per-second throughput in
+// percent, nil for phases too short to tell
+type Stability struct {
+	WriteCoV *float64 `json:"writeCoV,omitempty"`
+	ReadCoV  *float64 `json:"readCoV,omitempty"`
+	// WriteDegradation and ReadDegradation are the drop of the throughput
+	// of the last quarter of the phase from its first quarter in percent,
+	// negative when the drive got faster. SLC cache exhaustion and thermal
+	// throttling show up here on long runs.
+	WriteDegradation *float64 `json:"writeDegradation,omitempty"`
+	ReadDegradation  *float64 `json:"readDegradation,omitempty"`
+	// Degraded is set when either drop exceeds DrivePerf.MaxDegradation.
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// Unstable - true if the throughput of either phase oscillates wildly.
+func (s *Stability) Unstable() bool {
+	return s != nil && ((s.WriteCoV != nil && *s.WriteCoV > UnstableCoV) ||
+		(s.ReadCoV != nil && *s.ReadCoV > UnstableCoV))
+}
+
+// newStability - stability of a drive from its throughput over time, nil
+// if no phase lasted long enough. Drops beyond maxDegradation percent flag
+// the drive as degraded.
+func newStability(points []ThroughputPoint, maxDegradation float64) *Stability {
+	write, read := phaseSamples(points, PhaseWrite), phaseSamples(points, PhaseRead)
+	s := &Stability{
+		WriteCoV:         phaseCoV(write),
+		ReadCoV:          phaseCoV(read),
+		WriteDegradation: phaseDegradation(write),
+		ReadDegradation:  phaseDegradation(read),
+	}
+	if s.WriteCoV == nil && s.ReadCoV == nil {
+		return nil
+	}
+	s.Degraded = (s.WriteDegradation != nil && *s.WriteDegradation > maxDegradation) ||
+		(s.ReadDegradation != nil && *s.ReadDegradation > maxDegradation)
+	return s
+}
+
+// phaseSamples - throughput of the samples of a phase. The first and last
+// samples only partially cover the phase and are left out.
+func phaseSamples(points []ThroughputPoint, phase Phase) []float64 {
+	var samples []float64
+	for _, p := range points {
+		if p.Phase == phase {
+			samples = append(samples, float64(p.Throughput))
+		}
+	}
+	if len(samples) < 2 {
+		return nil
+	}
+	return samples[1 : len(samples)-1]
+}
+
+// phaseDegradation - drop of the average throughput of the last quarter
+// of the samples from the first quarter, in percent.
+func phaseDegradation(samples []float64) *float64 {
+	if len(samples) < minDegradationSamples {
+		return nil
+	}
+	window := len(samples) / 4
+	early, late := mean(samples[:window]), mean(samples[len(samples)-window:])
+	if early == 0 {
+		return nil
+	}
+	drop := (early - late) / early * 100
+	return &drop
+}
+
+func mean(samples []float64) float64 {
+	var sum float64
+	for _, v := range samples {
+		sum += v
+	}
+	return sum / float64(len(samples))
+}
+
+// phaseCoV - coefficient of variation of the samples of a phase.
+func phaseCoV(samples []float64) *float64 {
+	if len(samples) < minStabilitySamples {
+		return nil
+	}
+
+	avg := mean(samples)
+	if avg == 0 {
+		return nil
+	}
+	var sq float64
+	for _, v := range samples {
+		sq += (v - avg) * (v - avg)
+	}
+	cov := math.Sqrt(sq/float64(len(samples))) / avg * 100
+	return &cov
+}
+
+// stabilityCells - coefficient of variation of the throughput of every
+// drive, the first row is the header.
+func (r *Report) stabilityCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"WRITE CoV",
+		"READ CoV",
+		"WRITE DROP",
+		"READ DROP",
+		"",
+	}}
+	for _, result := range r.Results {
+		s := result.Stability
+		if s == nil {
+			continue
+		}
+		var flags []string
+		if s.Unstable() {
+			flags = append(flags, "unstable")
+		}
+		if s.Degraded {
+			flags = append(flags, "degraded")
+		}
+		status := "✓"
+		if len(flags) > 0 {
+			status = strings.Join(flags, ", ")
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			formatCoV(s.WriteCoV),
+			formatCoV(s.ReadCoV),
+			formatCoV(s.WriteDegradation),
+			formatCoV(s.ReadDegradation),
+			status,
+		})
+	}
+	return cellText
+}
+
+func formatCoV(cov *float64) string {
+	if cov == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", *cov)
+}
@@ -0,0 +1,258 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Sweep parameters
+const (
+	SweepIOPerDrive = "ioPerDrive"
+	SweepBlockSize  = "blockSize"
+)
+
+// ErrUnknownSweep returned for a parameter other than the Sweep* ones.
+var ErrUnknownSweep = errors.New("unknown sweep parameter")
+
+// SweepReport reports of a run repeated for every value of a parameter
+type SweepReport struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	// Parameter is SweepIOPerDrive or SweepBlockSize.
+	Parameter string `json:"parameter"`
+	// Reports of the runs in the order of the values, the value of each
+	// is in its Config.
+	Reports []*Report `json:"reports"`
+}
+
+// Sweep runs the tests against paths once for every value of parameter,
+// the other options are kept. MaxWrite bounds the writes of all the runs,
+// the sweep is refused before it starts if they would exceed it.
+func (d *DrivePerf) Sweep(ctx context.Context, parameter string, values []uint64, paths ...string) (*SweepReport, error) {
+	steps, err := d.sweepSteps(parameter, values)
+	if err != nil {
+		return nil, err
+	}
+	if d.MaxWrite > 0 {
+		var planned uint64
+		for _, step := range steps {
+			n, err := step.plannedWrite(paths)
+			if err != nil {
+				return nil, err
+			}
+			planned += n
+		}
+		if planned > d.MaxWrite {
+			return nil, fmt.Errorf("%w: %s planned over %d runs, %s allowed", ErrWriteBudgetExceeded,
+				FormatBytes(planned), len(steps), FormatBytes(d.MaxWrite))
+		}
+	}
+	sweep := &SweepReport{
+		Version:   ReportVersion,
+		Parameter: parameter,
+	}
+	for _, step := range steps {
+		report, err := step.runReport(ctx, paths...)
+		if err != nil {
+			return nil, err
+		}
+		sweep.Reports = append(sweep.Reports, report)
+	}
+	sweep.Time = time.Now().UTC()
+	return sweep, nil
+}
+
+// SweepPlannedWrite returns the total bytes a sweep of parameter over
+// values will write against n drives, see PlannedWrite. It is 0 for an
+// unknown parameter, which Sweep refuses.
+func (d *DrivePerf) SweepPlannedWrite(parameter string, values []uint64, n int) uint64 {
+	steps, err := d.sweepSteps(parameter, values)
+	if err != nil {
+		return 0
+	}
+	var planned uint64
+	for _, step := range steps {
+		planned += step.PlannedWrite(n)
+	}
+	return planned
+}
+
+// sweepSteps - a copy of d for every value of parameter.
+func (d *DrivePerf) sweepSteps(parameter string, values []uint64) ([]*DrivePerf, error) {
+	steps := make([]*DrivePerf, 0, len(values))
+	for _, v := range values {
+		step := *d
+		switch parameter {
+		case SweepIOPerDrive:
+			step.IOPerDrive = int(v)
+		case SweepBlockSize:
+			step.BlockSize = v
+		default:
+			return nil, ErrUnknownSweep
+		}
+		steps = append(steps, &step)
+	}
+	return steps, nil
+}
+
+// SweepAndRender runs the sweep and renders its results as Output, the
+// report of every run is published. The thresholds missed in every run, or
+// else the first run failed on every drive, are returned once the sweep
+// is rendered.
+func (d *DrivePerf) SweepAndRender(ctx context.Context, parameter string, values []uint64, paths ...string) error {
+	sweep, err := d.Sweep(ctx, parameter, values, paths...)
+	if err != nil {
+		return err
+	}
+	if err = sweep.render(d.out(), d.Output); err != nil {
+		return err
+	}
+	var violations []string
+	var errFailed error
+	for _, report := range sweep.Reports {
+		if d.Publish != nil {
+			if err = d.Publish(report); err != nil {
+				return err
+			}
+		}
+		for _, v := range d.Thresholds.Violations(report) {
+			violations = append(violations, fmt.Sprintf("%s %s: %s", sweep.Parameter, sweep.value(report), v))
+		}
+		if err = report.allFailed(); err != nil && errFailed == nil {
+			errFailed = fmt.Errorf("%s %s: %w", sweep.Parameter, sweep.value(report), err)
+		}
+	}
+	if len(violations) > 0 {
+		return &ThresholdError{Violations: violations}
+	}
+	return errFailed
+}
+
+// render - renders the sweep to w as output.
+func (s *SweepReport) render(w io.Writer, output string) error {
+	switch output {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case OutputCBOR:
+		return cborEncMode.NewEncoder(w).Encode(s)
+	case OutputTSV:
+		return s.renderTSV(w)
+	case OutputMarkdown:
+		return renderMarkdownTables(w, [][][]string{s.cells()})
+	}
+	return displayTable(w, s.cells())
+}
+
+// value - the value of the parameter in the run of report.
+func (s *SweepReport) value(report *Report) string {
+	if s.Parameter == SweepBlockSize {
+		return FormatBytes(report.Config.BlockSize)
+	}
+	return strconv.Itoa(report.Config.IOPerDrive)
+}
+
+// cells - the throughput, IOPS and tail latency of every drive and in
+// total per value, the first row is the header.
+func (s *SweepReport) cells() [][]string {
+	param := "IOPERDRIVE"
+	if s.Parameter == SweepBlockSize {
+		param = "BLOCKSIZE"
+	}
+	cellText := [][]string{{
+		"PATH",
+		param,
+		"WRITE",
+		"WRITE IOPS",
+		"WRITE P99",
+		"READ",
+		"READ IOPS",
+		"READ P99",
+		"",
+	}}
+	if len(s.Reports) == 0 {
+		return cellText
+	}
+	for i := range s.Reports[0].Results {
+		for _, report := range s.Reports {
+			if i >= len(report.Results) {
+				continue
+			}
+			result := report.Results[i]
+			row := []string{result.Path, s.value(report), "-", "-", "-", "-", "-", "-", "✓"}
+			if result.Error != nil {
+				row[8] = result.Error.Error()
+			} else {
+				row[2], row[3], row[4] = formatRate(result.WriteThroughput), strconv.FormatUint(result.WriteIOPS, 10), p99(result.WriteLatency)
+				row[5], row[6], row[7] = formatRate(result.ReadThroughput), strconv.FormatUint(result.ReadIOPS, 10), p99(result.ReadLatency)
+			}
+			cellText = append(cellText, row)
+		}
+	}
+	for _, report := range s.Reports {
+		cellText = append(cellText, []string{
+			"total",
+			s.value(report),
+			formatRate(report.TotalWriteThroughput),
+			strconv.FormatUint(report.TotalWriteIOPS, 10),
+			"-",
+			formatRate(report.TotalReadThroughput),
+			strconv.FormatUint(report.TotalReadIOPS, 10),
+			"-",
+			"",
+		})
+	}
+	return cellText
+}
+
+// p99 - the formatted P99 latency, "-" if unknown.
+func p99(l *LatencyStats) string {
+	if l == nil {
+		return "-"
+	}
+	return formatLatency(l.P99)
+}
+
+// renderTSV - one "value, path, write, read, error, write IOPS, read
+// IOPS" line per value and drive, the value is in bytes for block sizes.
+func (s *SweepReport) renderTSV(w io.Writer) error {
+	for _, report := range s.Reports {
+		value := strconv.Itoa(report.Config.IOPerDrive)
+		if s.Parameter == SweepBlockSize {
+			value = strconv.FormatUint(report.Config.BlockSize, 10)
+		}
+		for _, result := range report.Results {
+			var errStr string
+			if result.Error != nil {
+				errStr = tsvEscaper.Replace(result.Error.Error())
+			}
+			if _, err := fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\t%d\t%d\n", value, tsvEscaper.Replace(result.Path),
+				result.WriteThroughput, result.ReadThroughput, errStr, result.WriteIOPS, result.ReadIOPS); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"time"
+)
+
+// DriveTemperature temperature of a drive polled while it was tested, in
+// degrees Celsius
+type DriveTemperature struct {
+	Start float64 `json:"start"`
+	Max   float64 `json:"max"`
+	End   float64 `json:"end"`
+	// MaxTime is when Max was first reached.
+	MaxTime time.Time `json:"maxTime"`
+	// Limit is the temperature the drive reports to throttle at, 0 if unknown.
+	Limit float64 `json:"limit,omitempty"`
+	// ThrottleEvents are the thermal management transitions NVMe drives
+	// counted during the run.
+	ThrottleEvents uint64 `json:"throttleEvents,omitempty"`
+	// Samples taken every second, to line up with throughput dips, only
+	// kept when DrivePerf.Series is set.
+	Samples []TemperatureSample `json:"samples,omitempty"`
+}
+
+// TemperatureSample temperature of a drive at a point in time
+type TemperatureSample struct {
+	Time    time.Time `json:"time"`
+	Celsius float64   `json:"celsius"`
+}
+
+// Throttled - true if the drive likely throttled its throughput because
+// of its temperature during the run.
+func (t *DriveTemperature) Throttled() bool {
+	return t != nil && (t.ThrottleEvents > 0 || (t.Limit > 0 && t.Max >= t.Limit))
+}
+
+// newDriveTemperature - summarizes the samples, nil if there are none.
+func newDriveTemperature(samples []TemperatureSample) *DriveTemperature {
+	if len(samples) == 0 {
+		return nil
+	}
+	t := &DriveTemperature{
+		Start:   samples[0].Celsius,
+		Max:     samples[0].Celsius,
+		MaxTime: samples[0].Time,
+		End:     samples[len(samples)-1].Celsius,
+		Samples: samples,
+	}
+	for _, s := range samples {
+		if s.Celsius > t.Max {
+			t.Max, t.MaxTime = s.Celsius, s.Time
+		}
+	}
+	return t
+}
+
+// temperatureCells - temperature of every drive during the run, the
+// first row is the header.
+func (r *Report) temperatureCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"START",
+		"MAX",
+		"MAX AT",
+		"END",
+		"LIMIT",
+		"",
+	}}
+	for _, result := range r.Results {
+		t := result.Temperature
+		if t == nil {
+			continue
+		}
+		limit := "-"
+		if t.Limit > 0 {
+			limit = formatCelsius(t.Limit)
+		}
+		status := "✓"
+		if t.Throttled() {
+			status = "throttled"
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			formatCelsius(t.Start),
+			formatCelsius(t.Max),
+			t.MaxTime.Local().Format("15:04:05"),
+			formatCelsius(t.End),
+			limit,
+			status,
+		})
+	}
+	return cellText
+}
+
+func formatCelsius(c float64) string {
+	return fmt.Sprintf("%.0f°C", c)
+}
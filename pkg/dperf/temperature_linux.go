@@ -0,0 +1,161 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// temperatureInterval - interval between two temperature samples.
+const temperatureInterval = time.Second
+
+// kelvinOffset - 0°C in Kelvin as reported by NVMe drives.
+const kelvinOffset = 273
+
+// errNoTemperature returned for drives that do not report their temperature.
+var errNoTemperature = errors.New("drive does not report its temperature")
+
+// temperatureSampler - polls the temperature of a drive until stopped.
+type temperatureSampler struct {
+	dev *blockDev
+	// hwmon is the directory of the hwmon sensor of the drive, empty if
+	// the temperature is read from the NVMe SMART log.
+	hwmon  string
+	before *nvmeSmartLog
+	stop   chan struct{}
+	done   chan struct{}
+
+	samples []TemperatureSample
+}
+
+// sampleTemperature - starts polling the temperature of the drive
+// backing path, returns nil if it does not report one.
+func sampleTemperature(path string) *temperatureSampler {
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return nil
+	}
+	s := &temperatureSampler{
+		dev:   dev,
+		hwmon: dev.hwmonDir(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	s.before, _ = readNVMeSmartLog(dev)
+	if _, err = s.read(); err != nil {
+		return nil
+	}
+	go s.run()
+	return s
+}
+
+func (s *temperatureSampler) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(temperatureInterval)
+	defer ticker.Stop()
+	s.sample()
+	for {
+		select {
+		case <-s.stop:
+			s.sample()
+			return
+		case <-ticker.C:
+			s.sample()
+		}
+	}
+}
+
+func (s *temperatureSampler) sample() {
+	if c, err := s.read(); err == nil {
+		s.samples = append(s.samples, TemperatureSample{Time: time.Now(), Celsius: c})
+	}
+}
+
+// read - current temperature of the drive in degrees Celsius, hwmon
+// sensors are preferred as they do not issue admin commands.
+func (s *temperatureSampler) read() (float64, error) {
+	if s.hwmon != "" {
+		return readMilliCelsius(filepath.Join(s.hwmon, "temp1_input"))
+	}
+	log, err := readNVMeSmartLog(s.dev)
+	if err != nil {
+		return 0, err
+	}
+	if log.temperature == 0 {
+		return 0, errNoTemperature
+	}
+	return float64(int(log.temperature) - kelvinOffset), nil
+}
+
+// result - stops polling and returns the temperature of the drive, nil if
+// no sample was taken.
+func (s *temperatureSampler) result() *DriveTemperature {
+	if s == nil {
+		return nil
+	}
+	close(s.stop)
+	<-s.done
+	t := newDriveTemperature(s.samples)
+	if t == nil {
+		return nil
+	}
+	if s.hwmon != "" {
+		// The highest limit below the critical temperature is where
+		// drives start throttling.
+		for _, attr := range []string{"temp1_max", "temp1_crit"} {
+			if limit, err := readMilliCelsius(filepath.Join(s.hwmon, attr)); err == nil && limit > 0 {
+				t.Limit = limit
+				break
+			}
+		}
+	}
+	if after, err := readNVMeSmartLog(s.dev); err == nil && s.before != nil {
+		t.ThrottleEvents = after.thermalTransitions - s.before.thermalTransitions
+	}
+	return t
+}
+
+// hwmonDir - directory of the hwmon temperature sensor of the whole disk,
+// exposed by the nvme driver and by drivetemp for SATA drives, empty if
+// there is none.
+func (b *blockDev) hwmonDir() string {
+	for _, pattern := range []string{
+		filepath.Join("/sys/block", b.disk, "device", "hwmon*", "temp1_input"),
+		filepath.Join("/sys/block", b.disk, "device", "hwmon", "hwmon*", "temp1_input"),
+	} {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return filepath.Dir(matches[0])
+		}
+	}
+	return ""
+}
+
+// readMilliCelsius - reads a hwmon temperature attribute in degrees Celsius.
+func readMilliCelsius(path string) (float64, error) {
+	s, err := readSysfsString(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(v) / 1000, nil
+}
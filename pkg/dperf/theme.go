@@ -0,0 +1,97 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+)
+
+// An alias of string to represent the health color code of an object
+type col string
+
+const (
+	colGrey   col = "Grey"
+	colRed    col = "Red"
+	colYellow col = "Yellow"
+	colGreen  col = "Green"
+)
+
+// Color themes
+const (
+	ThemeDefault      = "default"
+	ThemeHighContrast = "high-contrast"
+)
+
+// theme - attributes every color code is printed with.
+type theme map[col][]color.Attribute
+
+var themes = map[string]theme{
+	ThemeDefault: {
+		colGrey:   {color.FgWhite, color.Bold},
+		colRed:    {color.FgRed, color.Bold},
+		colYellow: {color.FgYellow, color.Bold},
+		colGreen:  {color.FgGreen, color.Bold},
+	},
+	// Readable on light and dark backgrounds alike: text keeps the
+	// terminal foreground, statuses get solid backgrounds.
+	ThemeHighContrast: {
+		colGrey:   {color.Bold},
+		colRed:    {color.FgHiWhite, color.BgRed, color.Bold},
+		colYellow: {color.FgBlack, color.BgYellow, color.Bold},
+		colGreen:  {color.Bold, color.Underline},
+	},
+}
+
+var activeTheme = themes[ThemeDefault]
+
+// SetColors selects the color theme, one of the Theme* constants, or
+// disables colors altogether. Colors are also disabled when NO_COLOR is
+// set, TERM is dumb or stdout is not a terminal.
+func SetColors(name string, enabled bool) error {
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown color theme %q, must be one of %s, %s", name, ThemeDefault, ThemeHighContrast)
+	}
+	activeTheme = t
+	if !enabled {
+		color.NoColor = true
+	}
+	return nil
+}
+
+// getPrintCol - map color code to color for printing
+func getPrintCol(c col) *color.Color {
+	attrs, ok := activeTheme[c]
+	if !ok {
+		return nil
+	}
+	return color.New(attrs...)
+}
+
+// getPrintColFor - the color of c for printing to w, colors are only
+// printed to stdout.
+func getPrintColFor(w io.Writer, c col) *color.Color {
+	pc := getPrintCol(c)
+	if w != os.Stdout {
+		pc.DisableColor()
+	}
+	return pc
+}
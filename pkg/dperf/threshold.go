@@ -0,0 +1,86 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBelowThreshold returned when the results miss any of the Thresholds.
+var ErrBelowThreshold = errors.New("throughput below the minimum")
+
+// Thresholds minimum throughputs in bytes/sec a run must reach, zero
+// disables a check. MinWrite and MinRead apply to every drive, a failed
+// drive misses them too.
+type Thresholds struct {
+	MinWrite      uint64
+	MinRead       uint64
+	MinTotalWrite uint64
+	MinTotalRead  uint64
+}
+
+// Enabled returns true if any of the thresholds is set.
+func (t Thresholds) Enabled() bool {
+	return t != Thresholds{}
+}
+
+// Violations lists every drive, and the totals, that missed a threshold.
+func (t Thresholds) Violations(report *Report) []string {
+	var violations []string
+	below := func(what string, v, minimum uint64) {
+		if minimum > 0 && v < minimum {
+			violations = append(violations, fmt.Sprintf("%s %s is below the minimum %s",
+				what, formatRate(v), formatRate(minimum)))
+		}
+	}
+	for _, result := range report.Results {
+		if result.Error != nil {
+			if t.MinWrite > 0 || t.MinRead > 0 {
+				violations = append(violations, fmt.Sprintf("%s failed: %v", result.Path, result.Error))
+			}
+			continue
+		}
+		below(result.Path+" write", result.WriteThroughput, t.MinWrite)
+		below(result.Path+" read", result.ReadThroughput, t.MinRead)
+	}
+	below("total write", report.TotalWriteThroughput, t.MinTotalWrite)
+	below("total read", report.TotalReadThroughput, t.MinTotalRead)
+	return violations
+}
+
+// ThresholdError lists the thresholds a run missed, it wraps
+// ErrBelowThreshold.
+type ThresholdError struct {
+	Violations []string
+}
+
+func (e *ThresholdError) Error() string {
+	return fmt.Sprintf("%v: %d checks failed", ErrBelowThreshold, len(e.Violations))
+}
+
+func (e *ThresholdError) Unwrap() error {
+	return ErrBelowThreshold
+}
+
+// check - a ThresholdError if the report missed any of the thresholds.
+func (t Thresholds) check(report *Report) error {
+	if violations := t.Violations(report); len(violations) > 0 {
+		return &ThresholdError{Violations: violations}
+	}
+	return nil
+}
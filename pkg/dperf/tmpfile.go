@@ -0,0 +1,77 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// testFiles - the test files of the I/O workers on the drive at path and
+// a func removing them. They are unnamed where the filesystem supports
+// it, and freed however the process ends, named in dir otherwise.
+func (d *DrivePerf) testFiles(path, dir string) ([]string, func()) {
+	if files, closeFiles := d.tmpFiles(path); files != nil {
+		return files, closeFiles
+	}
+	files := make([]string, d.IOPerDrive)
+	for i := range files {
+		files[i] = testFilePath(path, dir, i)
+	}
+	return files, func() { os.RemoveAll(filepath.Join(path, dir)) }
+}
+
+// tmpFiles - creates an unnamed test file for every I/O worker in the
+// drive at path with O_TMPFILE, and returns the paths they are opened by
+// and a func closing them, the filesystem then frees them. nil where
+// the filesystem does not support them, see unnamedFiles.
+func (d *DrivePerf) tmpFiles(path string) ([]string, func()) {
+	if !d.unnamedFiles() {
+		return nil, nil
+	}
+	// Referenced until closed, the finalizers would close them otherwise.
+	tmp := make([]*os.File, 0, d.IOPerDrive)
+	closeFiles := func() {
+		for _, f := range tmp {
+			f.Close()
+		}
+	}
+	files := make([]string, d.IOPerDrive)
+	for i := range files {
+		f, name, err := openTmpFile(path)
+		if err != nil {
+			closeFiles()
+			return nil, nil
+		}
+		tmp = append(tmp, f)
+		files[i] = name
+	}
+	return files, closeFiles
+}
+
+// unnamedFiles - whether the test files are tried unnamed first, several
+// files per worker derive their names from that of the first.
+func (d *DrivePerf) unnamedFiles() bool {
+	return tmpFilesSupported && d.FilesPerIO <= 1
+}
+
+// tmpFilePath - how the plan shows the unnamed test file of an I/O
+// worker on the drive at path.
+func tmpFilePath(path string, idx int) string {
+	return filepath.Join(path, "<O_TMPFILE "+strconv.Itoa(idx)+">") + " as /proc/self/fd/<fd>"
+}
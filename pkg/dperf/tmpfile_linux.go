@@ -0,0 +1,43 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// tmpFilesSupported - O_TMPFILE is a Linux flag, the filesystem may
+// still not support it.
+const tmpFilesSupported = true
+
+// openTmpFile - creates an unnamed file in dir with O_TMPFILE, and
+// returns it and the path reopening it through /proc while it is open.
+func openTmpFile(dir string) (*os.File, string, error) {
+	f, err := os.OpenFile(dir, unix.O_TMPFILE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, "", err
+	}
+	name := "/proc/self/fd/" + strconv.Itoa(int(f.Fd()))
+	if _, err = os.Stat(name); err != nil {
+		f.Close()
+		return nil, "", err
+	}
+	return f, name, nil
+}
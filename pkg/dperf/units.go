@@ -0,0 +1,66 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Units byte counts and throughputs are printed in
+const (
+	// UnitsIEC powers of 1024, e.g. MiB/s
+	UnitsIEC = "iec"
+	// UnitsSI powers of 1000 as quoted by drive vendors, e.g. MB/s
+	UnitsSI = "si"
+	// UnitsRaw plain integers, bytes and bytes/sec
+	UnitsRaw = "raw"
+)
+
+var units = UnitsIEC
+
+// SetUnits selects the units of the human readable outputs, one of the
+// Units* constants. Machine readable outputs always use raw bytes.
+func SetUnits(u string) error {
+	switch u {
+	case UnitsIEC, UnitsSI, UnitsRaw:
+		units = u
+		return nil
+	}
+	return fmt.Errorf("unknown units %q, must be one of iec, si, raw", u)
+}
+
+// FormatBytes formats a byte count in the selected units.
+func FormatBytes(v uint64) string {
+	switch units {
+	case UnitsSI:
+		return humanize.Bytes(v)
+	case UnitsRaw:
+		return strconv.FormatUint(v, 10)
+	}
+	return humanize.IBytes(v)
+}
+
+// formatRate - formats a throughput in the selected units.
+func formatRate(v uint64) string {
+	if units == UnitsRaw {
+		return strconv.FormatUint(v, 10)
+	}
+	return FormatBytes(v) + "/s"
+}
@@ -0,0 +1,249 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"strconv"
+)
+
+// verifyTrailerSize - bytes at the end of every 4KiB written with Verify
+// holding its offset, the tag of the run and its checksum.
+const verifyTrailerSize = 16
+
+// maxCorruptBlocks - corrupt blocks listed per drive, all are counted.
+const maxCorruptBlocks = 100
+
+// Reasons of corrupt blocks
+const (
+	CorruptChecksum  = "checksum"
+	CorruptMisplaced = "misplaced"
+	CorruptStale     = "stale"
+)
+
+// ErrCorruptData returned for drives whose data did not read back as it
+// was written with Verify.
+var ErrCorruptData = errors.New("data read back differs from the data written")
+
+// verifyTag - tells the data written by this process from the data left
+// at the same offsets by an earlier run.
+var verifyTag = rand.Uint32()
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Verification outcome of checking the data read back from a drive
+// against the checksums written along with it
+type Verification struct {
+	// Blocks is the number of 4KiB blocks checked.
+	Blocks  uint64 `json:"blocks"`
+	Corrupt uint64 `json:"corrupt"`
+	// CorruptBlocks are the first corrupt blocks found, at most 100.
+	CorruptBlocks []CorruptBlock `json:"corruptBlocks,omitempty"`
+
+	// tag the data is written with and expected to carry.
+	tag uint32
+}
+
+// CorruptBlock a 4KiB block read back different from what was written
+type CorruptBlock struct {
+	Worker int `json:"worker"`
+	// Offset of the block in the test file of the worker.
+	Offset uint64 `json:"offset"`
+	// Reason is checksum if the block is damaged, misplaced if it holds
+	// the data of another offset and stale if it holds data of another
+	// run, as after a lost write.
+	Reason string `json:"reason"`
+}
+
+// merge - adds the blocks checked in o.
+func (v *Verification) merge(o *Verification) {
+	if o == nil {
+		return
+	}
+	v.Blocks += o.Blocks
+	v.Corrupt += o.Corrupt
+	for _, b := range o.CorruptBlocks {
+		if len(v.CorruptBlocks) < maxCorruptBlocks {
+			v.CorruptBlocks = append(v.CorruptBlocks, b)
+		}
+	}
+}
+
+// err - ErrCorruptData with the number of corrupt blocks, nil if there
+// are none.
+func (v *Verification) err() error {
+	if v == nil || v.Corrupt == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d corrupt blocks", ErrCorruptData, v.Corrupt)
+}
+
+// alignedStart - index in b, written or read at off, of the first 4KiB
+// aligned block.
+func alignedStart(off uint64) int {
+	return int((DirectioAlignSize - off%DirectioAlignSize) % DirectioAlignSize)
+}
+
+// stamp - fills the trailer of every 4KiB block of b, written at off
+// with tag.
+func stamp(b []byte, off uint64, tag uint32) {
+	for i := alignedStart(off); i+DirectioAlignSize <= len(b); i += DirectioAlignSize {
+		blk := b[i : i+DirectioAlignSize]
+		t := blk[DirectioAlignSize-verifyTrailerSize:]
+		binary.LittleEndian.PutUint64(t, off+uint64(i))
+		binary.LittleEndian.PutUint32(t[8:], tag)
+		binary.LittleEndian.PutUint32(t[12:], crc32.Checksum(blk[:DirectioAlignSize-4], castagnoli))
+	}
+}
+
+// check - checks every 4KiB block of b, read at off by worker.
+func (v *Verification) check(b []byte, off uint64, worker int) {
+	for i := alignedStart(off); i+DirectioAlignSize <= len(b); i += DirectioAlignSize {
+		blk := b[i : i+DirectioAlignSize]
+		t := blk[DirectioAlignSize-verifyTrailerSize:]
+		v.Blocks++
+		var reason string
+		switch {
+		case binary.LittleEndian.Uint32(t[12:]) != crc32.Checksum(blk[:DirectioAlignSize-4], castagnoli):
+			reason = CorruptChecksum
+		case binary.LittleEndian.Uint64(t) != off+uint64(i):
+			reason = CorruptMisplaced
+		case binary.LittleEndian.Uint32(t[8:]) != v.tag:
+			reason = CorruptStale
+		default:
+			continue
+		}
+		v.Corrupt++
+		if len(v.CorruptBlocks) < maxCorruptBlocks {
+			v.CorruptBlocks = append(v.CorruptBlocks, CorruptBlock{Worker: worker, Offset: off + uint64(i), Reason: reason})
+		}
+	}
+}
+
+type positionedWriter interface {
+	io.Writer
+	positioned
+}
+
+type positionedReader interface {
+	io.Reader
+	positioned
+}
+
+// stamper - wraps the file written to so that the data carries its
+// checksums, when the data is verified. It wraps the timed writer, so
+// that the checksums are not part of the latency of the writes.
+func (s *ioStats) stamper(w io.Writer) io.Writer {
+	pw, ok := w.(positionedWriter)
+	if s.verify == nil || !ok {
+		return w
+	}
+	return stampWriter{pw, s.verify.tag}
+}
+
+// verifier - wraps the file read from so that the data read is checked,
+// when the data is verified. It wraps the timed reader, so that the
+// checks are not part of the latency of the reads.
+func (s *ioStats) verifier(r io.Reader) io.Reader {
+	pr, ok := r.(positionedReader)
+	if s.verify == nil || !ok {
+		return r
+	}
+	return verifyReader{positionedReader: pr, v: s.verify, worker: s.worker}
+}
+
+type stampWriter struct {
+	positionedWriter
+	tag uint32
+}
+
+func (w stampWriter) Write(b []byte) (int, error) {
+	stamp(b, w.position(), w.tag)
+	return w.positionedWriter.Write(b)
+}
+
+type verifyReader struct {
+	positionedReader
+	v      *Verification
+	worker int
+}
+
+func (r verifyReader) Read(b []byte) (int, error) {
+	off := r.position()
+	n, err := r.positionedReader.Read(b)
+	r.v.check(b[:n], off, r.worker)
+	return n, err
+}
+
+// verifyCells - blocks checked and corrupt of every verified drive, the
+// first row is the header.
+func (r *Report) verifyCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"VERIFIED",
+		"CORRUPT",
+	}}
+	for _, result := range r.Results {
+		if v := result.Verify; v != nil {
+			cellText = append(cellText, []string{
+				result.Path,
+				FormatBytes(v.Blocks * DirectioAlignSize),
+				strconv.FormatUint(v.Corrupt, 10),
+			})
+		}
+	}
+	return cellText
+}
+
+// corruptCells - the corrupt blocks listed per drive, the first row is
+// the header.
+func (r *Report) corruptCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"WORKER",
+		"OFFSET",
+		"REASON",
+	}}
+	for _, result := range r.Results {
+		v := result.Verify
+		if v == nil {
+			continue
+		}
+		for _, b := range v.CorruptBlocks {
+			cellText = append(cellText, []string{
+				result.Path,
+				strconv.Itoa(b.Worker),
+				strconv.FormatUint(b.Offset, 10),
+				b.Reason,
+			})
+		}
+		if more := v.Corrupt - uint64(len(v.CorruptBlocks)); more > 0 {
+			cellText = append(cellText, []string{
+				result.Path,
+				"",
+				fmt.Sprintf("%d more", more),
+				"",
+			})
+		}
+	}
+	return cellText
+}
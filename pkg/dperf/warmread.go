@@ -0,0 +1,114 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// WarmRead throughput, IOPS and latency of the reads of a drive through
+// the page cache once it holds the files
+type WarmRead struct {
+	Throughput uint64        `json:"throughput"`
+	IOPS       uint64        `json:"iops"`
+	Latency    *LatencyStats `json:"latency,omitempty"`
+}
+
+// runWarmRead - reads the files of the I/O workers of the drive at path
+// through the page cache, once to load them into it, then again measured.
+func (d *DrivePerf) runWarmRead(ctx context.Context, path string, files []string) (*WarmRead, error) {
+	w := *d
+	w.cached = true
+	w.RWFlags = nil
+	w.Verify = false
+	w.Latency = nil
+	w.Rate = 0
+
+	var read phaseResult
+	for _, measured := range []bool{false, true} {
+		pass := w
+		if !measured {
+			pass.Duration = 0
+		}
+		results := make([]ioResult, d.IOPerDrive)
+		errs := make([]error, d.IOPerDrive)
+		var wg sync.WaitGroup
+		wg.Add(d.IOPerDrive)
+		for i := 0; i < d.IOPerDrive; i++ {
+			go func(idx int) {
+				defer wg.Done()
+				iopath := files[idx%len(files)]
+				size := d.FileSize
+				if d.ReadOnly {
+					var err error
+					if size, err = d.readSize(iopath); err != nil {
+						errs[idx] = err
+						return
+					}
+				}
+				results[idx], errs[idx] = pass.runReadTest(ctx, iopath, alignedBlock(int(d.BlockSize)), size,
+					pass.newIOStats(path, PhaseRead, idx, size), nil)
+			}(i)
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			return nil, fmt.Errorf("warm read failed: %w", err)
+		}
+		read = phaseResults(results)
+	}
+	return &WarmRead{
+		Throughput: read.throughput,
+		IOPS:       read.iops,
+		Latency:    read.latencyStats(),
+	}, nil
+}
+
+// warmReadCells - read throughput and IOPS of every drive from the drive
+// and from the page cache, the first row is the header.
+func (r *Report) warmReadCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"COLD READ",
+		"WARM READ",
+		"COLD READ IOPS",
+		"WARM READ IOPS",
+		"CACHE SPEEDUP",
+	}}
+	for _, result := range r.Results {
+		warm := result.WarmRead
+		if warm == nil {
+			continue
+		}
+		speedup := "-"
+		if result.ReadThroughput > 0 {
+			speedup = fmt.Sprintf("%.1fx", float64(warm.Throughput)/float64(result.ReadThroughput))
+		}
+		cellText = append(cellText, []string{
+			result.Path,
+			formatRate(result.ReadThroughput),
+			formatRate(warm.Throughput),
+			strconv.FormatUint(result.ReadIOPS, 10),
+			strconv.FormatUint(warm.IOPS, 10),
+			speedup,
+		})
+	}
+	return cellText
+}
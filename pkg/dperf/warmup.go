@@ -0,0 +1,99 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// warmup - transfers data to or from the files of the drive at path,
+// one per I/O worker, for Warmup or WarmupBytes without measuring it.
+// The files are written unless ReadOnly is set, in which case they are
+// existing files which are read.
+func (d *DrivePerf) warmup(ctx context.Context, path string, files []string) error {
+	if d.Warmup <= 0 && d.WarmupBytes == 0 {
+		return nil
+	}
+	w := *d
+	w.Duration = d.Warmup
+	w.SyncEvery = 0
+	w.Latency = nil
+	w.Rate = 0
+	if d.WarmupBytes > 0 {
+		// Spread over the workers, aligned for O_DIRECT.
+		size := d.WarmupBytes / uint64(d.IOPerDrive)
+		w.FileSize = max(size-size%DirectioAlignSize, DirectioAlignSize)
+	}
+
+	errs := make([]error, d.IOPerDrive)
+	start := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(d.IOPerDrive)
+	for i := 0; i < d.IOPerDrive; i++ {
+		go func(idx int) {
+			defer wg.Done()
+			data := alignedBlock(int(d.BlockSize))
+			iopath := files[idx%len(files)]
+			if d.ReadOnly {
+				size, err := w.readSize(iopath)
+				if err == nil {
+					_, err = w.runReadTest(ctx, iopath, data, size, w.newIOStats(path, PhaseRead, idx, size), nil)
+				}
+				errs[idx] = err
+				return
+			}
+			// Unlike in the write phase the first pass stops at the deadline.
+			src := untilDeadline(w.newDataReader(idx), w.deadline(start))
+			_, errs[idx] = w.runWriteTest(ctx, iopath, data, src, w.newIOStats(path, PhaseWrite, idx, w.FileSize), nil)
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("warmup failed: %w", err)
+	}
+	return nil
+}
+
+// prefill - writes the files of the drive at path to FileSize without
+// measuring it, so that the write phase overwrites them in place.
+func (d *DrivePerf) prefill(ctx context.Context, path string, files []string) error {
+	w := *d
+	w.Duration = 0
+	w.SyncEvery = 0
+	w.Latency = nil
+	w.Rate = 0
+
+	errs := make([]error, d.IOPerDrive)
+	var wg sync.WaitGroup
+	wg.Add(d.IOPerDrive)
+	for i := 0; i < d.IOPerDrive; i++ {
+		go func(idx int) {
+			defer wg.Done()
+			data := alignedBlock(int(d.BlockSize))
+			_, errs[idx] = w.runWriteTest(ctx, files[idx], data, w.newDataReader(idx), w.newIOStats(path, PhaseWrite, idx, w.FileSize), nil)
+		}(i)
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("prefill failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Zone models of a zoned block device, as reported by Linux. Host-managed
+// devices, SMR drives and ZNS SSDs, only accept writes at the write
+// pointer of a zone, host-aware ones accept them anywhere but prefer them
+// sequential.
+const (
+	ZonedHostManaged = "host-managed"
+	ZonedHostAware   = "host-aware"
+)
+
+// ZoneStats throughput of the block operations of a drive within a zone
+// of a zoned block device tested in place of files.
+type ZoneStats struct {
+	Phase Phase `json:"phase"`
+	// Zone is the index of the zone on the device, Offset its start.
+	Zone       uint64 `json:"zone"`
+	Offset     uint64 `json:"offset"`
+	Throughput uint64 `json:"throughput"`
+}
+
+// zoneLayout - the zones of a zoned block device.
+type zoneLayout struct {
+	// model is one of the Zoned* constants.
+	model string
+	// size of a zone in bytes, capacity the bytes that can be written to
+	// it, less than size on some ZNS SSDs.
+	size, capacity uint64
+	// maxOpen is the number of zones that can be written at once, 0 if
+	// the device does not limit it.
+	maxOpen int
+}
+
+// managed - reports if the zones must be written sequentially.
+func (z *zoneLayout) managed() bool {
+	return z.model == ZonedHostManaged
+}
+
+// span - the bytes of the zones holding n bytes written from the start
+// of a zone.
+func (z *zoneLayout) span(n uint64) uint64 {
+	return (n + z.capacity - 1) / z.capacity * z.size
+}
+
+// zone - the index of the zone holding byte off of a region starting at
+// base, the region fills the capacity of every zone before the next.
+func (z *zoneLayout) zone(base, off uint64) uint64 {
+	return (base + off/z.capacity*z.size + off%z.capacity) / z.size
+}
+
+// workerSpan - the bytes of a block device between the regions of two
+// I/O workers, whole zones on a host-managed device.
+func (d *DrivePerf) workerSpan() uint64 {
+	if d.zones == nil || !d.zones.managed() {
+		return d.FileSize
+	}
+	return d.zones.span(d.FileSize)
+}
+
+// checkZones - returns an error if the host-managed device cannot be
+// written with the options of the run, every I/O worker writes its
+// zones one after the other from their start.
+func (d *DrivePerf) checkZones() error {
+	z := d.zones
+	if d.ReadOnly || !z.managed() {
+		return nil
+	}
+	if d.Offset%z.size != 0 {
+		return fmt.Errorf("the offset must be a multiple of the %s zones of a host-managed zoned device", FormatBytes(z.size))
+	}
+	if d.random() || d.Access == AccessBoth || d.ReadMix > 0 || d.Duration > 0 || d.Engine == EngineMmap {
+		return errors.New("a host-managed zoned device is written sequentially within its zones, random access, read mixes, timed phases and the mmap engine would write out of order")
+	}
+	if z.maxOpen > 0 && d.IOPerDrive > z.maxOpen {
+		return fmt.Errorf("%d I/O workers write more zones at once than the %d the device keeps open", d.IOPerDrive, z.maxOpen)
+	}
+	return nil
+}
+
+// zonedAt - applies op to the zones b spans from off.
+func (r region) zonedAt(b []byte, off int64, op func(*os.File, []byte, int64) (int, error)) (int, error) {
+	size, capacity := int64(r.zones.size), int64(r.zones.capacity)
+	var done int
+	for len(b) > 0 {
+		zone, within := off/capacity, off%capacity
+		chunk := min(int64(len(b)), capacity-within)
+		m, err := op(r.files[0], b[:chunk], r.base+zone*size+within)
+		done += m
+		if err != nil {
+			return done, err
+		}
+		b = b[m:]
+		off += int64(m)
+	}
+	return done, nil
+}
+
+// resetZones - moves the write pointers of the zones holding n bytes of
+// the region back to their start.
+func (r region) resetZones(n uint64) error {
+	return resetZones(r.files[0], uint64(r.base), r.zones.span(n))
+}
+
+// zoneOps - the bytes and time of the operations within a zone.
+type zoneOps struct {
+	bytes uint64
+	busy  time.Duration
+}
+
+// fileZones - operations of an I/O worker or a drive by zone index, the
+// I/O workers of a drive write zones of their own.
+type fileZones map[uint64]*zoneOps
+
+// record - records an operation of n bytes within zone.
+func (fz fileZones) record(zone uint64, n int, latency time.Duration) {
+	ops := fz[zone]
+	if ops == nil {
+		ops = &zoneOps{}
+		fz[zone] = ops
+	}
+	ops.bytes += uint64(n)
+	ops.busy += latency
+}
+
+// merge - adds the zones of a worker to those of its drive.
+func (fz fileZones) merge(o fileZones) {
+	for zone, ops := range o {
+		fz[zone] = ops
+	}
+}
+
+// stats - the zones of z operated on in phase, by index.
+func (fz fileZones) stats(phase Phase, z *zoneLayout) []ZoneStats {
+	var stats []ZoneStats
+	for _, zone := range slices.Sorted(maps.Keys(fz)) {
+		zs := ZoneStats{Phase: phase, Zone: zone, Offset: zone * z.size}
+		if ops := fz[zone]; ops.busy > 0 {
+			zs.Throughput = uint64(float64(ops.bytes) / ops.busy.Seconds())
+		}
+		stats = append(stats, zs)
+	}
+	return stats
+}
+
+// zoneCells - throughput by zone of every phase of every zoned block
+// device, the first row is the header.
+func (r *Report) zoneCells() [][]string {
+	cellText := [][]string{{
+		"PATH",
+		"ZONED",
+		"PHASE",
+		"ZONE",
+		"OFFSET",
+		"THROUGHPUT",
+	}}
+	for _, result := range r.Results {
+		if result.Error != nil {
+			continue
+		}
+		for _, zs := range result.Zones {
+			cellText = append(cellText, []string{
+				result.Path,
+				result.Zoned,
+				string(zs.Phase),
+				strconv.FormatUint(zs.Zone, 10),
+				FormatBytes(zs.Offset),
+				formatRate(zs.Throughput),
+			})
+		}
+	}
+	return cellText
+}
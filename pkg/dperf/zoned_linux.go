@@ -0,0 +1,168 @@
+// This file is part of MinIO dperf
+// Copyright (c) 2021 MinIO, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dperf
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// _IOWR(0x12, 130, struct blk_zone_report)
+	blkReportZone = 0xC0101282
+	// _IOW(0x12, 131, struct blk_zone_range)
+	blkResetZone = 0x40101283
+
+	// blkZoneRepCapacity flags the reports filling in the zone capacity.
+	blkZoneRepCapacity = 1
+
+	blkZoneTypeConventional = 1
+	blkZoneCondEmpty        = 1
+	blkZoneCondReadOnly     = 0xD
+	blkZoneCondOffline      = 0xF
+
+	// zoneReportBatch - the zones reported by one ioctl.
+	zoneReportBatch = 64
+)
+
+// blkZone - struct blk_zone from linux/blkzoned.h, in 512 byte sectors.
+type blkZone struct {
+	start    uint64
+	len      uint64
+	wp       uint64
+	typ      uint8
+	cond     uint8
+	nonSeq   uint8
+	reset    uint8
+	_        [4]uint8
+	capacity uint64
+	_        [24]uint8
+}
+
+// blkZoneReport - struct blk_zone_report from linux/blkzoned.h followed
+// by room for zoneReportBatch zones.
+type blkZoneReport struct {
+	sector  uint64
+	nrZones uint32
+	flags   uint32
+	zones   [zoneReportBatch]blkZone
+}
+
+// blkZoneRange - struct blk_zone_range from linux/blkzoned.h.
+type blkZoneRange struct {
+	sector    uint64
+	nrSectors uint64
+}
+
+// zonedModel - the zone model of the drive backing path, one of the
+// Zoned* constants, empty if it is not zoned.
+func zonedModel(path string) string {
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return ""
+	}
+	switch model, _ := dev.queueAttr("zoned"); model {
+	case ZonedHostManaged, ZonedHostAware:
+		return model
+	}
+	return ""
+}
+
+// deviceZones - the zones of the block device at path, their capacity
+// that of the zone at offset, nil if the device is not zoned.
+func deviceZones(path string, offset uint64) (*zoneLayout, error) {
+	model := zonedModel(path)
+	if model == "" {
+		return nil, nil
+	}
+	dev, err := pathBlockDev(path)
+	if err != nil {
+		return nil, err
+	}
+	sectors, err := dev.queueAttrUint("chunk_sectors")
+	if err != nil {
+		return nil, err
+	}
+	z := &zoneLayout{model: model, size: sectors << 9, capacity: sectors << 9}
+	for _, attr := range []string{"max_open_zones", "max_active_zones"} {
+		if n, err := dev.queueAttrUint(attr); err == nil && n > 0 && (z.maxOpen == 0 || int(n) < z.maxOpen) {
+			z.maxOpen = int(n)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	report, err := reportZones(f, offset)
+	if err != nil {
+		return nil, err
+	}
+	if report.nrZones > 0 && report.flags&blkZoneRepCapacity != 0 && report.zones[0].capacity > 0 {
+		z.capacity = report.zones[0].capacity << 9
+	}
+	return z, nil
+}
+
+// reportZones - the zones of the device open as f from the one holding
+// offset on, zoneReportBatch at most.
+func reportZones(f *os.File, offset uint64) (*blkZoneReport, error) {
+	report := &blkZoneReport{sector: offset >> 9, nrZones: zoneReportBatch}
+	if _, _, e := unix.Syscall(unix.SYS_IOCTL, f.Fd(), blkReportZone, uintptr(unsafe.Pointer(report))); e != 0 {
+		return nil, fmt.Errorf("report zones: %w", e)
+	}
+	return report, nil
+}
+
+// resetZones - resets the write pointers of the sequential zones of the
+// device open as f from offset for length bytes, those already empty are
+// left alone, read-only and offline zones cannot be written.
+func resetZones(f *os.File, offset, length uint64) error {
+	end := (offset + length) >> 9
+	for sector := offset >> 9; sector < end; {
+		report, err := reportZones(f, sector<<9)
+		if err != nil {
+			return err
+		}
+		if report.nrZones == 0 {
+			return nil
+		}
+		for _, zone := range report.zones[:report.nrZones] {
+			if zone.start >= end {
+				return nil
+			}
+			sector = zone.start + zone.len
+			switch {
+			case zone.cond == blkZoneCondReadOnly:
+				return fmt.Errorf("the zone at %s is read-only", FormatBytes(zone.start<<9))
+			case zone.cond == blkZoneCondOffline:
+				return fmt.Errorf("the zone at %s is offline", FormatBytes(zone.start<<9))
+			case zone.typ == blkZoneTypeConventional || zone.cond == blkZoneCondEmpty:
+				continue
+			}
+			rng := blkZoneRange{sector: zone.start, nrSectors: zone.len}
+			if _, _, e := unix.Syscall(unix.SYS_IOCTL, f.Fd(), blkResetZone, uintptr(unsafe.Pointer(&rng))); e != 0 {
+				return fmt.Errorf("reset zone at %s: %w", FormatBytes(zone.start<<9), e)
+			}
+		}
+	}
+	return nil
+}